@@ -6,7 +6,6 @@ import (
 
 	"github.com/getoptimum/mump2p-cli/internal/auth"
 	"github.com/getoptimum/mump2p-cli/internal/formatter"
-	"github.com/getoptimum/mump2p-cli/internal/session"
 	"github.com/spf13/cobra"
 )
 
@@ -66,7 +65,7 @@ var logoutCmd = &cobra.Command{
 		if err := storage.RemoveToken(); err != nil {
 			return err
 		}
-		session.InvalidateSession()
+		sessionStore().InvalidateAll()
 
 		fmt.Println("✅ Successfully logged out")
 		return nil
@@ -0,0 +1,313 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/getoptimum/mump2p-cli/internal/auth"
+	"github.com/getoptimum/mump2p-cli/internal/formatter"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	exportOut        string
+	exportEncrypt    bool
+	importIn         string
+	bundlePassphrase string
+)
+
+// TokenInspection represents the decoded contents of a JWT
+type TokenInspection struct {
+	Header      map[string]interface{} `json:"header" yaml:"header"`
+	Subject     string                 `json:"subject,omitempty" yaml:"subject,omitempty"`
+	ClientID    string                 `json:"client_id,omitempty" yaml:"client_id,omitempty"`
+	IssuedAt    string                 `json:"issued_at,omitempty" yaml:"issued_at,omitempty"`
+	ExpiresAt   string                 `json:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+	IsExpired   bool                   `json:"is_expired" yaml:"is_expired"`
+	IsActive    bool                   `json:"is_active" yaml:"is_active"`
+	LimitsSetAt string                 `json:"limits_set_at,omitempty" yaml:"limits_set_at,omitempty"`
+	RateLimits  *RateLimitInfo         `json:"rate_limits" yaml:"rate_limits"`
+	ExtraClaims map[string]interface{} `json:"extra_claims,omitempty" yaml:"extra_claims,omitempty"`
+}
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Manage stored credentials",
+}
+
+var authExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the stored token as a portable bundle",
+	Long: `Export the stored authentication token as a bundle that can be imported on
+another machine. Use --encrypt to seal the bundle with a passphrase.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		storage := auth.NewStorageWithPath(GetAuthPath())
+		token, err := storage.LoadToken()
+		if err != nil {
+			return fmt.Errorf("not authenticated: %v", err)
+		}
+
+		passphrase := ""
+		if exportEncrypt {
+			passphrase = resolvePassphrase()
+			if passphrase == "" && stdinIsTerminal() {
+				if passphrase, err = promptPassphrase(true); err != nil {
+					return err
+				}
+			}
+			if passphrase == "" {
+				return fmt.Errorf("--encrypt requires a passphrase: enter it at the prompt, or set MUMP2P_BUNDLE_PASSPHRASE")
+			}
+		}
+
+		data, err := auth.ExportBundle(token, passphrase)
+		if err != nil {
+			return fmt.Errorf("failed to export token: %v", err)
+		}
+
+		if exportOut == "" || exportOut == "-" {
+			fmt.Println(string(data))
+			return nil
+		}
+		if err := os.WriteFile(exportOut, append(data, '\n'), 0600); err != nil {
+			return fmt.Errorf("failed to write bundle: %v", err)
+		}
+		fmt.Printf("✅ Token exported to %s\n", exportOut)
+		return nil
+	},
+}
+
+var authImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import a token bundle or raw JWT",
+	Long: `Import a bundle produced by 'mump2p auth export', or a raw JWT access token.
+Reads from --in, or from stdin when --in is omitted or set to "-".`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var (
+			data []byte
+			err  error
+		)
+		if importIn == "" || importIn == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(importIn)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read input: %v", err)
+		}
+
+		passphrase := resolvePassphrase()
+		token, err := auth.ImportBundle(data, passphrase)
+		if errors.Is(err, auth.ErrPassphraseRequired) && stdinIsTerminal() {
+			if passphrase, err = promptPassphrase(false); err != nil {
+				return err
+			}
+			token, err = auth.ImportBundle(data, passphrase)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to import token: %v", err)
+		}
+
+		storage := auth.NewStorageWithPath(GetAuthPath())
+		if err := storage.SaveToken(token); err != nil {
+			return err
+		}
+		sessionStore().InvalidateAll()
+
+		fmt.Println("✅ Token imported")
+		fmt.Printf("Token expires at: %s\n", token.ExpiresAt.Format(time.RFC822))
+		if token.RefreshToken == "" {
+			fmt.Println("No refresh token included; run 'mump2p login' once it expires.")
+		}
+		return nil
+	},
+}
+
+var authInspectCmd = &cobra.Command{
+	Use:   "inspect [jwt|-]",
+	Short: "Decode and display all claims of a JWT",
+	Long: `Decode a JWT without verifying its signature and display every claim.
+Defaults to the stored token; pass a token as argument or "-" to read it from stdin.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var raw string
+		switch {
+		case len(args) == 1 && args[0] == "-":
+			data, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return fmt.Errorf("failed to read input: %v", err)
+			}
+			raw = strings.TrimSpace(string(data))
+		case len(args) == 1:
+			raw = strings.TrimSpace(args[0])
+		default:
+			storage := auth.NewStorageWithPath(GetAuthPath())
+			token, err := storage.LoadToken()
+			if err != nil {
+				return fmt.Errorf("not authenticated: %v", err)
+			}
+			raw = token.Token
+		}
+
+		parser := auth.NewTokenParser()
+		claims, err := parser.ParseToken(raw)
+		if err != nil {
+			return fmt.Errorf("error parsing token: %v", err)
+		}
+		header, rawClaims, err := parser.ParseRawClaims(raw)
+		if err != nil {
+			return fmt.Errorf("error parsing token: %v", err)
+		}
+
+		response := TokenInspection{
+			Header:    header,
+			Subject:   claims.Subject,
+			ClientID:  claims.ClientID,
+			IsExpired: !claims.ExpiresAt.IsZero() && time.Now().After(claims.ExpiresAt),
+			IsActive:  claims.IsActive,
+			RateLimits: &RateLimitInfo{
+				PublishPerHour:   claims.MaxPublishPerHour,
+				PublishPerSec:    claims.MaxPublishPerSec,
+				MaxMessageSizeMB: float64(claims.MaxMessageSize) / (1 << 20),
+				DailyQuotaMB:     float64(claims.DailyQuota) / (1 << 20),
+			},
+		}
+		if !claims.IssuedAt.IsZero() {
+			response.IssuedAt = claims.IssuedAt.Format(time.RFC3339)
+		}
+		if !claims.ExpiresAt.IsZero() {
+			response.ExpiresAt = claims.ExpiresAt.Format(time.RFC3339)
+		}
+		if claims.LimitsSetAt != 0 {
+			response.LimitsSetAt = time.Unix(claims.LimitsSetAt, 0).Format(time.RFC3339)
+		}
+
+		known := make(map[string]bool, len(auth.KnownClaims))
+		for _, k := range auth.KnownClaims {
+			known[k] = true
+		}
+		for k, v := range rawClaims {
+			if known[k] {
+				continue
+			}
+			if response.ExtraClaims == nil {
+				response.ExtraClaims = make(map[string]interface{})
+			}
+			response.ExtraClaims[k] = v
+		}
+
+		f := formatter.New(GetOutputFormat())
+		if !f.IsTable() {
+			output, err := f.Format(response)
+			if err != nil {
+				return fmt.Errorf("failed to format output: %v", err)
+			}
+			fmt.Println(output)
+			return nil
+		}
+
+		fmt.Println("Token Header:")
+		fmt.Println("-------------")
+		for _, k := range sortedKeys(header) {
+			fmt.Printf("%-12s %v\n", k+":", header[k])
+		}
+
+		fmt.Println("\nClaims:")
+		fmt.Println("-------")
+		fmt.Printf("Subject:     %s\n", response.Subject)
+		fmt.Printf("Client ID:   %s\n", response.ClientID)
+		if response.IssuedAt != "" {
+			fmt.Printf("Issued At:   %s\n", response.IssuedAt)
+		}
+		if response.ExpiresAt != "" {
+			fmt.Printf("Expires At:  %s (expired: %t)\n", response.ExpiresAt, response.IsExpired)
+		}
+		fmt.Printf("Is Active:   %t\n", response.IsActive)
+		if response.LimitsSetAt != "" {
+			fmt.Printf("Limits Set:  %s\n", response.LimitsSetAt)
+		}
+
+		fmt.Println("\nRate Limits:")
+		fmt.Println("------------")
+		fmt.Printf("Publish Rate:      %d per hour\n", claims.MaxPublishPerHour)
+		fmt.Printf("Publish Rate:      %d per second\n", claims.MaxPublishPerSec)
+		fmt.Printf("Max Message Size:  %.2f MB\n", response.RateLimits.MaxMessageSizeMB)
+		fmt.Printf("Daily Quota:       %.2f MB\n", response.RateLimits.DailyQuotaMB)
+
+		if len(response.ExtraClaims) > 0 {
+			fmt.Println("\nOther Claims:")
+			fmt.Println("-------------")
+			for _, k := range sortedKeys(response.ExtraClaims) {
+				fmt.Printf("%s: %v\n", k, response.ExtraClaims[k])
+			}
+		}
+		return nil
+	},
+}
+
+// resolvePassphrase returns the bundle passphrase from the flag or environment
+func resolvePassphrase() string {
+	if bundlePassphrase != "" {
+		return bundlePassphrase
+	}
+	return os.Getenv("MUMP2P_BUNDLE_PASSPHRASE")
+}
+
+func stdinIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// promptPassphrase reads the bundle passphrase from the terminal without
+// echoing it, asking twice when confirm is set.
+func promptPassphrase(confirm bool) (string, error) {
+	read := func(prompt string) (string, error) {
+		fmt.Fprint(os.Stderr, prompt)
+		b, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("failed to read passphrase: %v", err)
+		}
+		return string(b), nil
+	}
+	passphrase, err := read("Bundle passphrase: ")
+	if err != nil || !confirm {
+		return passphrase, err
+	}
+	again, err := read("Repeat passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if again != passphrase {
+		return "", fmt.Errorf("passphrases do not match")
+	}
+	return passphrase, nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func init() {
+	authCmd.PersistentFlags().StringVar(&bundlePassphrase, "passphrase", "", "Passphrase for encrypted bundles; insecure, as it is visible in ps and shell history (prefer the prompt or env: MUMP2P_BUNDLE_PASSPHRASE)")
+
+	authExportCmd.Flags().StringVar(&exportOut, "out", "", "Write the bundle to this file instead of stdout")
+	authExportCmd.Flags().BoolVar(&exportEncrypt, "encrypt", false, "Encrypt the bundle with a passphrase")
+
+	authImportCmd.Flags().StringVar(&importIn, "in", "", "Read the bundle from this file instead of stdin")
+
+	authCmd.AddCommand(authExportCmd)
+	authCmd.AddCommand(authImportCmd)
+	authCmd.AddCommand(authInspectCmd)
+	rootCmd.AddCommand(authCmd)
+}
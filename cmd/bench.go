@@ -0,0 +1,313 @@
+package cmd
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/getoptimum/mump2p-cli/internal/auth"
+	"github.com/getoptimum/mump2p-cli/internal/bench"
+	"github.com/getoptimum/mump2p-cli/internal/formatter"
+	"github.com/getoptimum/mump2p-cli/internal/ratelimit"
+	pb "github.com/getoptimum/mump2p-cli/proto"
+	"github.com/spf13/cobra"
+)
+
+var (
+	benchTopic        string
+	benchCount        int
+	benchSize         int
+	benchIntervalMs   int
+	benchPubNode      int
+	benchSubNode      int
+	benchWarmup       time.Duration
+	benchWait         time.Duration
+	benchExport       string
+	benchServiceURL   string
+	benchExposeAmount uint32
+)
+
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Measure end-to-end publish/subscribe latency",
+	Long: `Subscribe and publish in one process over the chosen session nodes.
+Each payload carries a sequence number and send timestamp, so delivery ratio,
+duplicates, reordering and latency percentiles are measured on one clock.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if benchCount <= 0 {
+			return withExitCode(ExitUsage, fmt.Errorf("--count must be positive"))
+		}
+		if benchIntervalMs <= 0 {
+			return withExitCode(ExitUsage, fmt.Errorf("--interval-ms must be positive"))
+		}
+		if benchPubNode < 1 || benchSubNode < 1 {
+			return withExitCode(ExitUsage, fmt.Errorf("--pub-node and --sub-node are 1-based node indexes"))
+		}
+
+		f := formatter.New(GetOutputFormat())
+
+		var claims *auth.TokenClaims
+		var clientIDToUse string
+		var accessToken string
+		if !IsAuthDisabled() {
+			tokenStr, c, err := loadTokenAndClaims(GetAuthPath())
+			if err != nil {
+				return err
+			}
+			accessToken = tokenStr
+			claims = c
+			clientIDToUse = c.ClientID
+			limitNodeMessages(c)
+		} else {
+			clientIDToUse = GetClientID()
+			if clientIDToUse == "" {
+				return fmt.Errorf("--client-id is required when using --disable-auth")
+			}
+		}
+
+		runID := bench.NewRunID()
+		topic := benchTopic
+		if topic == "" {
+			topic = "bench/" + hex.EncodeToString(runID[:4])
+		}
+
+		interval := time.Duration(benchIntervalMs) * time.Millisecond
+		var limiter *ratelimit.RateLimiter
+		if claims != nil {
+			if int64(benchSize) > claims.MaxMessageSize {
+				return fmt.Errorf("--size %d exceeds your max message size of %d bytes", benchSize, claims.MaxMessageSize)
+			}
+			if claims.MaxPublishPerSec > 0 {
+				if min := time.Second / time.Duration(claims.MaxPublishPerSec); interval < min {
+					fmt.Fprintf(os.Stderr, "Raising interval to %s to stay within %d publish/sec\n", humanDuration(min), claims.MaxPublishPerSec)
+					interval = min
+				}
+			}
+			l, err := ratelimit.NewRateLimiterWithDir(claims, GetAuthDir())
+			if err != nil {
+				return fmt.Errorf("rate limiter setup failed: %v", err)
+			}
+			limiter = l
+		}
+
+		expose := benchExposeAmount
+		if need := uint32(max(benchPubNode, benchSubNode)); expose < need {
+			expose = need
+		}
+
+		sessReq, err := newSessionRequest(benchServiceURL, clientIDToUse, accessToken, []string{topic}, []string{"publish", "subscribe"}, expose, "", "")
+		if err != nil {
+			return err
+		}
+		sess, _, err := sessionStore().GetOrCreate(sessReq)
+		if err != nil {
+			return fmt.Errorf("session creation failed: %w", err)
+		}
+		if benchPubNode > len(sess.Nodes) || benchSubNode > len(sess.Nodes) {
+			return fmt.Errorf("session has %d node(s); --pub-node/--sub-node out of range", len(sess.Nodes))
+		}
+		pubNode := sess.Nodes[benchPubNode-1]
+		subNode := sess.Nodes[benchSubNode-1]
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+		go func() {
+			select {
+			case <-sigChan:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		sc, err := dialNode(subNode)
+		if err != nil {
+			return fmt.Errorf("subscribe node %s unreachable: %v", subNode.Address, err)
+		}
+		defer sc.Close()
+		msgChan, err := sc.Subscribe(ctx, subNode.Ticket, topic, 1000, func(err error) {
+			fmt.Fprintf(statusOut(), "Subscribe stream on %s ended: %v\n", subNode.Address, err)
+		})
+		if err != nil {
+			return fmt.Errorf("subscribe on %s failed: %v", subNode.Address, err)
+		}
+
+		rec := bench.NewRecorder(runID)
+		go func() {
+			for resp := range msgChan {
+				if resp.GetCommand() != pb.ResponseType_Message {
+					continue
+				}
+				now := time.Now()
+				decoded, _, _ := decodeMessage(resp.Data)
+				rec.Observe(decoded, now)
+			}
+		}()
+
+		pc, err := dialNode(pubNode)
+		if err != nil {
+			return fmt.Errorf("publish node %s unreachable: %v", pubNode.Address, err)
+		}
+		defer pc.Close()
+
+		fmt.Fprintf(os.Stderr, "Benchmarking '%s': %d x %d bytes every %s, publish via %s, subscribe via %s\n",
+			topic, benchCount, max(benchSize, bench.HeaderSize), humanDuration(interval), pubNode.Address, subNode.Address)
+
+		select {
+		case <-time.After(benchWarmup):
+		case <-ctx.Done():
+		}
+
+		started := time.Now()
+		sent, pubErrors := 0, 0
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		// a limit stops publishing; the report still covers what was sent
+		var limitErr error
+	publishLoop:
+		for seq := 0; seq < benchCount; seq++ {
+			if seq > 0 {
+				select {
+				case <-ticker.C:
+				case <-ctx.Done():
+					break publishLoop
+				}
+			}
+			payload := bench.Encode(runID, uint64(seq), time.Now(), benchSize)
+			if limiter != nil {
+				if err := limiter.CheckPublishAllowed(int64(len(payload))); err != nil {
+					fmt.Fprintf(statusOut(), "Stopping after %d message(s): %v\n", seq, err)
+					limitErr = err
+					break
+				}
+			}
+			pctx, pcancel := context.WithTimeout(ctx, 10*time.Second)
+			_, err := pc.Publish(pctx, pubNode.Ticket, topic, payload)
+			pcancel()
+			if err != nil {
+				pubErrors++
+				if IsDebugMode() {
+					fmt.Fprintf(statusOut(), "  publish %d failed: %v\n", seq, err)
+				}
+				continue
+			}
+			sent++
+			if limiter != nil {
+				_ = limiter.RecordPublish(int64(len(payload)))
+			}
+			if IsDebugMode() {
+				fmt.Fprintf(statusOut(), "  sent %d/%d (received %d)\n", seq+1, benchCount, rec.Received())
+			}
+		}
+
+		deadline := time.Now().Add(benchWait)
+		for rec.Received() < sent && time.Now().Before(deadline) && ctx.Err() == nil {
+			time.Sleep(20 * time.Millisecond)
+		}
+
+		rep := rec.Report(sent, pubErrors)
+		rep.Topic = topic
+		rep.PublishNode = pubNode.Address
+		rep.SubscribeNode = subNode.Address
+		rep.PayloadSize = max(benchSize, bench.HeaderSize)
+		rep.StartedAt = started.UTC()
+		rep.DurationMs = float64(time.Since(started)) / float64(time.Millisecond)
+
+		if benchExport != "" {
+			if err := exportBenchReport(rep, benchExport); err != nil {
+				return err
+			}
+		}
+
+		if !f.IsTable() {
+			summary := *rep
+			summary.Samples = nil
+			output, err := f.Format(summary)
+			if err != nil {
+				return fmt.Errorf("failed to format output: %v", err)
+			}
+			fmt.Println(output)
+			return withExitCode(ExitRateLimited, limitErr)
+		}
+
+		printBenchReport(rep)
+		if benchExport != "" {
+			fmt.Printf("\nResults written to %s\n", benchExport)
+		}
+		return withExitCode(ExitRateLimited, limitErr)
+	},
+}
+
+func exportBenchReport(rep *bench.Report, path string) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %v", err)
+	}
+	defer out.Close()
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		err = rep.WriteCSV(out)
+	} else {
+		err = rep.WriteJSON(out)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write export file: %v", err)
+	}
+	return nil
+}
+
+func printBenchReport(rep *bench.Report) {
+	fmt.Println("\nBenchmark Results:")
+	fmt.Println("------------------")
+	fmt.Printf("Sent:          %d (%d publish error(s))\n", rep.Sent, rep.PublishErrors)
+	fmt.Printf("Received:      %d (%.1f%% delivered, %d lost)\n", rep.Received, rep.DeliveryRatio*100, rep.Lost)
+	fmt.Printf("Duplicates:    %d\n", rep.Duplicates)
+	fmt.Printf("Out of order:  %d\n", rep.OutOfOrder)
+	fmt.Printf("Duration:      %s\n", humanDuration(time.Duration(rep.DurationMs*float64(time.Millisecond))))
+
+	if rep.Received == 0 {
+		return
+	}
+	l := rep.Latency
+	fmt.Println("\nLatency:")
+	fmt.Println("--------")
+	fmt.Printf("min %.2fms  p50 %.2fms  p90 %.2fms  p99 %.2fms  max %.2fms  (mean %.2fms)\n",
+		l.Min, l.P50, l.P90, l.P99, l.Max, l.Mean)
+
+	fmt.Println("\nHistogram:")
+	maxCount := 0
+	for _, b := range rep.Histogram {
+		maxCount = max(maxCount, b.Count)
+	}
+	prev := 0.0
+	for _, b := range rep.Histogram {
+		if b.Count > 0 {
+			bar := strings.Repeat("█", max(1, b.Count*40/maxCount))
+			fmt.Printf("  %9s  %-40s %d\n", b.Label(prev), bar, b.Count)
+		}
+		if b.UpperMs > 0 {
+			prev = b.UpperMs
+		}
+	}
+}
+
+func init() {
+	benchCmd.Flags().StringVar(&benchTopic, "topic", "", "Topic to benchmark on (default: a random bench/<id> topic)")
+	benchCmd.Flags().IntVar(&benchCount, "count", 100, "Number of messages to publish")
+	benchCmd.Flags().IntVar(&benchSize, "size", 1024, "Payload size in bytes (minimum 30)")
+	benchCmd.Flags().IntVar(&benchIntervalMs, "interval-ms", 100, "Interval between publishes in milliseconds")
+	benchCmd.Flags().IntVar(&benchPubNode, "pub-node", 1, "Session node to publish through (1-based)")
+	benchCmd.Flags().IntVar(&benchSubNode, "sub-node", 1, "Session node to subscribe through (1-based)")
+	benchCmd.Flags().DurationVar(&benchWarmup, "warmup", 500*time.Millisecond, "Delay between subscribing and the first publish")
+	benchCmd.Flags().DurationVar(&benchWait, "wait", 5*time.Second, "How long to wait for outstanding messages after the last publish")
+	benchCmd.Flags().StringVar(&benchExport, "export", "", "Write per-message results to a .json or .csv file")
+	benchCmd.Flags().StringVar(&benchServiceURL, "service-url", "", "Override the default proxy URL (comma-separated URLs fail over in turn)")
+	benchCmd.Flags().Uint32Var(&benchExposeAmount, "expose-amount", 1, "Number of nodes to request from proxy")
+	rootCmd.AddCommand(benchCmd)
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/getoptimum/mump2p-cli/internal/ratelimit"
+	"github.com/getoptimum/mump2p-cli/internal/session"
+)
+
+// Exit codes returned by the CLI. They are part of its interface for
+// scripts; keep the README table in sync.
+const (
+	ExitOK          = 0
+	ExitError       = 1 // any failure without a more specific code
+	ExitUsage       = 2 // invalid flags or input
+	ExitAuth        = 3 // not logged in, expired token or inactive account
+	ExitRateLimited = 4 // a local rate limit or quota was hit
+	ExitNodesFailed = 5 // every node failed
+)
+
+// exitError attaches an exit code to an error.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+
+func (e *exitError) Unwrap() error { return e.err }
+
+// withExitCode makes the CLI exit with code if err ends the command.
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: code, err: err}
+}
+
+// exitCode returns the exit code for the error that ended a command.
+func exitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var ee *exitError
+	if errors.As(err, &ee) {
+		return ee.code
+	}
+	if ratelimit.IsRateLimitError(err) {
+		return ExitRateLimited
+	}
+	if errors.Is(err, ratelimit.ErrInactive) || session.IsAuthError(err) {
+		return ExitAuth
+	}
+	return ExitError
+}
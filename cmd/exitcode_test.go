@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/getoptimum/mump2p-cli/internal/auth"
+	"github.com/getoptimum/mump2p-cli/internal/ratelimit"
+	"github.com/getoptimum/mump2p-cli/internal/session"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestExitCode tests the exit code chosen for each kind of error.
+func TestExitCode(t *testing.T) {
+	claims := &auth.TokenClaims{
+		Subject:           "exitcode-test",
+		IsActive:          true,
+		MaxPublishPerHour: 10,
+		MaxPublishPerSec:  10,
+		MaxMessageSize:    10,
+		DailyQuota:        100,
+	}
+	limiter, err := ratelimit.NewRateLimiterWithDir(claims, t.TempDir())
+	require.NoError(t, err)
+	tooLarge := limiter.CheckPublishAllowed(11)
+	require.Error(t, tooLarge)
+
+	inactive, err := ratelimit.NewRateLimiterWithDir(&auth.TokenClaims{Subject: "inactive"}, t.TempDir())
+	require.NoError(t, err)
+	inactiveErr := inactive.CheckPublishAllowed(1)
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, ExitOK},
+		{"plain", errors.New("boom"), ExitError},
+		{"explicit code", withExitCode(ExitUsage, errors.New("bad flag")), ExitUsage},
+		{"wrapped explicit code", fmt.Errorf("publish: %w", withExitCode(ExitNodesFailed, errors.New("down"))), ExitNodesFailed},
+		{"rate limit", tooLarge, ExitRateLimited},
+		{"wrapped rate limit", fmt.Errorf("stopped: %w", tooLarge), ExitRateLimited},
+		{"inactive token", inactiveErr, ExitAuth},
+		{"proxy 401", fmt.Errorf("session creation failed: %w", &session.StatusError{Code: 401}), ExitAuth},
+		{"proxy 403 after failover", fmt.Errorf("session creation failed: %w",
+			errors.Join(errors.New("a: down"), &session.StatusError{Code: 403})), ExitAuth},
+		{"proxy 500", fmt.Errorf("session creation failed: %w", &session.StatusError{Code: 500}), ExitError},
+		{"grpc unauthenticated", fmt.Errorf("session creation failed: %w", status.Error(codes.Unauthenticated, "no token")), ExitAuth},
+		{"grpc unavailable", status.Error(codes.Unavailable, "down"), ExitError},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, exitCode(tc.err))
+		})
+	}
+}
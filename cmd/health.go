@@ -5,10 +5,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"time"
 
-	"github.com/getoptimum/mump2p-cli/internal/config"
 	"github.com/getoptimum/mump2p-cli/internal/formatter"
+	"github.com/getoptimum/mump2p-cli/internal/session"
 	"github.com/spf13/cobra"
 )
 
@@ -31,21 +30,14 @@ var healthCmd = &cobra.Command{
 	Short: "Check the health status of the proxy server",
 	Long:  `Check the health status and system metrics of the proxy server.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		// use custom service URL if provided, otherwise use the default
-		baseURL := config.LoadConfig().ServiceUrl
-		if healthServiceURL != "" {
-			baseURL = healthServiceURL
-		}
-
-		url := baseURL + "/api/v1/health"
-		req, err := http.NewRequest("GET", url, nil)
+		// the --service-url proxies, else the profile's, tried in turn
+		endpoints, err := proxyEndpoints(healthServiceURL)
 		if err != nil {
-			return fmt.Errorf("failed to create request: %v", err)
+			return err
 		}
-
-		// Use HTTP client with timeout to prevent hanging during fuzzing
-		client := &http.Client{Timeout: 10 * time.Second}
-		resp, err := client.Do(req)
+		resp, err := session.Do(endpoints, func(base string) (*http.Request, error) {
+			return http.NewRequest("GET", base+"/api/v1/health", nil)
+		})
 		if err != nil {
 			return fmt.Errorf("health check failed: %v", err)
 		}
@@ -95,6 +87,6 @@ var healthCmd = &cobra.Command{
 }
 
 func init() {
-	healthCmd.Flags().StringVar(&healthServiceURL, "service-url", "", "Override the default service URL")
+	healthCmd.Flags().StringVar(&healthServiceURL, "service-url", "", "Override the default service URL (comma-separated URLs fail over in turn)")
 	rootCmd.AddCommand(healthCmd)
 }
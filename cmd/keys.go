@@ -0,0 +1,311 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/getoptimum/mump2p-cli/internal/formatter"
+	"github.com/getoptimum/mump2p-cli/internal/keyring"
+	"github.com/spf13/cobra"
+)
+
+var (
+	keysGenerateName  string
+	keysGenerateType  string
+	keysGenerateTopic string
+	keysImportName    string
+	keysImportFile    string
+	keysImportTopic   string
+	keysExportPrivate bool
+)
+
+// KeySummary is one keyring entry as shown by `keys list`. Key material is
+// left out.
+type KeySummary struct {
+	Name       string `json:"name" yaml:"name"`
+	Type       string `json:"type" yaml:"type"`
+	ID         string `json:"id" yaml:"id"`
+	Topic      string `json:"topic,omitempty" yaml:"topic,omitempty"`
+	HasPrivate bool   `json:"has_private" yaml:"has_private"`
+	Created    string `json:"created" yaml:"created"`
+}
+
+func loadKeyring() (*keyring.Keyring, error) {
+	return keyring.Load(filepath.Join(GetAuthDir(), keyring.FileName))
+}
+
+// loadSealer returns the function that encrypts payloads for publish
+// --encrypt and a description of the encryption, or nil when encryption is
+// off. Recipients take precedence; otherwise keyName or the topic's
+// symmetric key is used.
+func loadSealer(encrypt bool, topic, keyName string, recipients []string) (func([]byte) ([]byte, error), string, error) {
+	if !encrypt {
+		if keyName != "" || len(recipients) > 0 {
+			return nil, "", withExitCode(ExitUsage, errors.New("--key and --recipients require --encrypt"))
+		}
+		return nil, "", nil
+	}
+	if keyName != "" && len(recipients) > 0 {
+		return nil, "", withExitCode(ExitUsage, errors.New("use either --key or --recipients, not both"))
+	}
+	kr, err := loadKeyring()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(recipients) > 0 {
+		keys := make([]keyring.Key, len(recipients))
+		for i, name := range recipients {
+			if keys[i], err = kr.Get(strings.TrimSpace(name)); err != nil {
+				return nil, "", withExitCode(ExitUsage, err)
+			}
+		}
+		return func(b []byte) ([]byte, error) {
+			return keyring.SealFor(keys, topic, b)
+		}, fmt.Sprintf("x25519, %d recipient(s)", len(keys)), nil
+	}
+
+	var k keyring.Key
+	if keyName != "" {
+		k, err = kr.Get(keyName)
+	} else {
+		k, err = kr.ForTopic(topic)
+	}
+	if err != nil {
+		return nil, "", withExitCode(ExitUsage, fmt.Errorf("%v (create one with: mump2p keys generate --name <name> --topic %s)", err, topic))
+	}
+	if k.Type != keyring.Symmetric {
+		return nil, "", withExitCode(ExitUsage, fmt.Errorf("key %s is an %s key, not a symmetric key", k.Name, k.Type))
+	}
+	return func(b []byte) ([]byte, error) {
+		return keyring.SealSymmetric(k, topic, b)
+	}, "symmetric key " + k.Name, nil
+}
+
+// loadSigner returns the ed25519 key for publish --sign, or nil when name
+// is empty.
+func loadSigner(name string) (*keyring.Key, error) {
+	if name == "" {
+		return nil, nil
+	}
+	kr, err := loadKeyring()
+	if err != nil {
+		return nil, err
+	}
+	k, err := kr.Get(name)
+	if err != nil {
+		return nil, withExitCode(ExitUsage, err)
+	}
+	if k.Type != keyring.Ed25519 || !k.HasPrivate() {
+		return nil, withExitCode(ExitUsage, fmt.Errorf("key %s is not an ed25519 key with a private part (create one with: mump2p keys generate --name <name> --type ed25519)", k.Name))
+	}
+	return &k, nil
+}
+
+// loadTrustStore returns the keyring whose ed25519 keys subscribe --verify
+// trusts: path, or the local keyring when path is empty.
+func loadTrustStore(path string) (*keyring.Keyring, error) {
+	if path == "" {
+		path = filepath.Join(GetAuthDir(), keyring.FileName)
+	}
+	kr, err := keyring.Load(path)
+	if err != nil {
+		return nil, withExitCode(ExitUsage, err)
+	}
+	for _, k := range kr.Keys {
+		if k.Type == keyring.Ed25519 {
+			return kr, nil
+		}
+	}
+	return nil, withExitCode(ExitUsage, fmt.Errorf("trust store %s has no ed25519 keys; import publishers with: mump2p keys import --name <name> ed25519-public:...", path))
+}
+
+var keysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "Manage keys for end-to-end payload encryption",
+	Long: `Keys are stored in keyring.json next to the auth file, readable only by
+the current user.
+
+A symmetric key is a secret shared by everyone on a topic; bind it with
+--topic and publish --encrypt picks it up automatically. An x25519 key pair
+lets others encrypt to you: share the output of 'keys export' and they
+import it and publish with --recipients. An ed25519 key pair signs what you
+publish with --sign; subscribers import its public half and use --verify.`,
+}
+
+var keysGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate a new key",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		kr, err := loadKeyring()
+		if err != nil {
+			return err
+		}
+		k, err := keyring.Generate(keysGenerateName, keyring.Type(keysGenerateType), keysGenerateTopic)
+		if err != nil {
+			return withExitCode(ExitUsage, err)
+		}
+		if err := kr.Add(k); err != nil {
+			return withExitCode(ExitUsage, err)
+		}
+		if err := kr.Save(); err != nil {
+			return fmt.Errorf("failed to save keyring: %v", err)
+		}
+		fmt.Printf("Generated %s key %s (id %s)\n", k.Type, k.Name, k.ID())
+		if k.Type != keyring.Symmetric {
+			fmt.Printf("Share the public key with: mump2p keys export %s\n", k.Name)
+		}
+		return nil
+	},
+}
+
+var keysImportCmd = &cobra.Command{
+	Use:   "import [key]",
+	Short: "Import a key written by 'keys export'",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var value string
+		switch {
+		case len(args) == 1 && keysImportFile == "":
+			value = args[0]
+		case len(args) == 0 && keysImportFile != "":
+			data, err := os.ReadFile(keysImportFile)
+			if err != nil {
+				return withExitCode(ExitUsage, fmt.Errorf("failed to read key file: %v", err))
+			}
+			value = string(data)
+		default:
+			return withExitCode(ExitUsage, errors.New("pass the key as an argument or with --file"))
+		}
+
+		k, err := keyring.Parse(keysImportName, value)
+		if err != nil {
+			return withExitCode(ExitUsage, err)
+		}
+		k.Topic = keysImportTopic
+
+		kr, err := loadKeyring()
+		if err != nil {
+			return err
+		}
+		if err := kr.Add(k); err != nil {
+			return withExitCode(ExitUsage, err)
+		}
+		if err := kr.Save(); err != nil {
+			return fmt.Errorf("failed to save keyring: %v", err)
+		}
+		fmt.Printf("Imported %s key %s (id %s)\n", k.Type, k.Name, k.ID())
+		return nil
+	},
+}
+
+var keysExportCmd = &cobra.Command{
+	Use:   "export <name>",
+	Short: "Print a key so it can be imported elsewhere",
+	Long: `Prints the public half of an x25519 or ed25519 key, or with --private the
+whole key. Symmetric keys are always printed in full; share them only over a
+secure channel.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		kr, err := loadKeyring()
+		if err != nil {
+			return err
+		}
+		k, err := kr.Get(args[0])
+		if err != nil {
+			return err
+		}
+		out, err := k.Export(keysExportPrivate)
+		if err != nil {
+			return err
+		}
+		fmt.Println(out)
+		return nil
+	},
+}
+
+var keysListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List keys in the keyring",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		kr, err := loadKeyring()
+		if err != nil {
+			return err
+		}
+		summaries := make([]KeySummary, len(kr.Keys))
+		for i, k := range kr.Keys {
+			summaries[i] = KeySummary{
+				Name:       k.Name,
+				Type:       string(k.Type),
+				ID:         k.ID(),
+				Topic:      k.Topic,
+				HasPrivate: k.HasPrivate(),
+				Created:    k.Created.Format("2006-01-02T15:04:05Z07:00"),
+			}
+		}
+
+		f := formatter.New(GetOutputFormat())
+		if !f.IsTable() {
+			output, err := f.Format(summaries)
+			if err != nil {
+				return fmt.Errorf("failed to format output: %v", err)
+			}
+			fmt.Println(output)
+			return nil
+		}
+
+		if len(summaries) == 0 {
+			fmt.Println("No keys. Create one with: mump2p keys generate --name <name>")
+			return nil
+		}
+		fmt.Printf("%-16s  %-9s  %-16s  %-7s  %s\n", "NAME", "TYPE", "ID", "PRIVATE", "TOPIC")
+		for _, s := range summaries {
+			private := "no"
+			if s.HasPrivate {
+				private = "yes"
+			}
+			fmt.Printf("%-16s  %-9s  %-16s  %-7s  %s\n", s.Name, s.Type, s.ID, private, s.Topic)
+		}
+		return nil
+	},
+}
+
+var keysRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Delete a key from the keyring",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		kr, err := loadKeyring()
+		if err != nil {
+			return err
+		}
+		if err := kr.Remove(args[0]); err != nil {
+			return err
+		}
+		if err := kr.Save(); err != nil {
+			return fmt.Errorf("failed to save keyring: %v", err)
+		}
+		fmt.Printf("Removed key %s\n", args[0])
+		return nil
+	},
+}
+
+func init() {
+	keysGenerateCmd.Flags().StringVar(&keysGenerateName, "name", "", "Name of the key (required)")
+	keysGenerateCmd.Flags().StringVar(&keysGenerateType, "type", string(keyring.Symmetric), "Key type: symmetric, x25519 or ed25519")
+	keysGenerateCmd.Flags().StringVar(&keysGenerateTopic, "topic", "", "Use this symmetric key for the topic by default")
+	keysGenerateCmd.MarkFlagRequired("name") //nolint:errcheck
+
+	keysImportCmd.Flags().StringVar(&keysImportName, "name", "", "Name of the key (required)")
+	keysImportCmd.Flags().StringVar(&keysImportFile, "file", "", "Read the key from a file")
+	keysImportCmd.Flags().StringVar(&keysImportTopic, "topic", "", "Use this symmetric key for the topic by default")
+	keysImportCmd.MarkFlagRequired("name") //nolint:errcheck
+
+	keysExportCmd.Flags().BoolVar(&keysExportPrivate, "private", false, "Include the private key")
+
+	keysCmd.AddCommand(keysGenerateCmd, keysImportCmd, keysExportCmd, keysListCmd, keysRemoveCmd)
+	rootCmd.AddCommand(keysCmd)
+}
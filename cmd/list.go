@@ -7,8 +7,8 @@ import (
 	"net/http"
 
 	"github.com/getoptimum/mump2p-cli/internal/auth"
-	"github.com/getoptimum/mump2p-cli/internal/config"
 	"github.com/getoptimum/mump2p-cli/internal/formatter"
+	"github.com/getoptimum/mump2p-cli/internal/session"
 	"github.com/spf13/cobra"
 )
 
@@ -36,25 +36,26 @@ This command shows your active topics and their count.`,
 				return fmt.Errorf("--client-id is required when using --disable-auth")
 			}
 
-			// Determine service URL
-			serviceURL := config.LoadConfig().ServiceUrl
-			if listServiceURL != "" {
-				serviceURL = listServiceURL
-				fmt.Printf("Using custom service URL: %s\n", serviceURL)
-			}
-
-			// Create HTTP GET request to /api/v1/topics with client_id query parameter
-			endpoint := fmt.Sprintf("%s/api/v1/topics?client_id=%s", serviceURL, clientIDToUse)
-			req, err := http.NewRequest("GET", endpoint, nil)
+			// Determine service URLs
+			endpoints, err := proxyEndpoints(listServiceURL)
 			if err != nil {
-				return fmt.Errorf("failed to create HTTP request: %v", err)
+				return err
+			}
+			if listServiceURL != "" {
+				fmt.Printf("Using custom service URL: %s\n", listServiceURL)
 			}
 
-			// Set headers (no auth needed for disabled auth)
-			req.Header.Set("Content-Type", "application/json")
-
-			// Execute the request
-			resp, err := http.DefaultClient.Do(req)
+			// GET /api/v1/topics with client_id query parameter, trying each
+			// proxy in turn
+			resp, err := session.Do(endpoints, func(base string) (*http.Request, error) {
+				req, err := http.NewRequest("GET", fmt.Sprintf("%s/api/v1/topics?client_id=%s", base, clientIDToUse), nil)
+				if err != nil {
+					return nil, fmt.Errorf("failed to create HTTP request: %v", err)
+				}
+				// Set headers (no auth needed for disabled auth)
+				req.Header.Set("Content-Type", "application/json")
+				return req, nil
+			})
 			if err != nil {
 				return fmt.Errorf("HTTP GET request failed: %v", err)
 			}
@@ -126,27 +127,28 @@ This command shows your active topics and their count.`,
 			return fmt.Errorf("your account is inactive, please contact support")
 		}
 
-		// Determine service URL
-		serviceURL := config.LoadConfig().ServiceUrl
-		if listServiceURL != "" {
-			serviceURL = listServiceURL
-			fmt.Printf("Using custom service URL: %s\n", serviceURL)
-		}
-
-		// Create HTTP GET request to /api/v1/topics with client_id query parameter
-		// This provides fallback for servers that don't extract client_id from JWT claims
-		endpoint := fmt.Sprintf("%s/api/v1/topics?client_id=%s", serviceURL, claims.ClientID)
-		req, err := http.NewRequest("GET", endpoint, nil)
+		// Determine service URLs
+		endpoints, err := proxyEndpoints(listServiceURL)
 		if err != nil {
-			return fmt.Errorf("failed to create HTTP request: %v", err)
+			return err
+		}
+		if listServiceURL != "" {
+			fmt.Printf("Using custom service URL: %s\n", listServiceURL)
 		}
 
-		// Set authorization header
-		req.Header.Set("Authorization", "Bearer "+token.Token)
-		req.Header.Set("Content-Type", "application/json")
-
-		// Execute the request
-		resp, err := http.DefaultClient.Do(req)
+		// GET /api/v1/topics with client_id query parameter, trying each
+		// proxy in turn. The query parameter provides fallback for servers
+		// that don't extract client_id from JWT claims
+		resp, err := session.Do(endpoints, func(base string) (*http.Request, error) {
+			req, err := http.NewRequest("GET", fmt.Sprintf("%s/api/v1/topics?client_id=%s", base, claims.ClientID), nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create HTTP request: %v", err)
+			}
+			// Set authorization header
+			req.Header.Set("Authorization", "Bearer "+token.Token)
+			req.Header.Set("Content-Type", "application/json")
+			return req, nil
+		})
 		if err != nil {
 			return fmt.Errorf("HTTP GET request failed: %v", err)
 		}
@@ -200,6 +202,6 @@ This command shows your active topics and their count.`,
 }
 
 func init() {
-	listTopicsCmd.Flags().StringVar(&listServiceURL, "service-url", "", "Override the default service URL")
+	listTopicsCmd.Flags().StringVar(&listServiceURL, "service-url", "", "Override the default service URL (comma-separated URLs fail over in turn)")
 	rootCmd.AddCommand(listTopicsCmd)
 }
@@ -0,0 +1,282 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/getoptimum/mump2p-cli/internal/formatter"
+	"github.com/getoptimum/mump2p-cli/internal/node"
+	"github.com/getoptimum/mump2p-cli/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var (
+	nodesTopic        string
+	nodesServiceURL   string
+	nodesExposeAmount uint32
+	nodesStrategy     string
+	nodesTimeout      time.Duration
+)
+
+// NodeInfo is a session node as shown by `nodes list`.
+type NodeInfo struct {
+	ID        string  `json:"id" yaml:"id"`
+	Address   string  `json:"address" yaml:"address"`
+	Region    string  `json:"region" yaml:"region"`
+	Transport string  `json:"transport" yaml:"transport"`
+	Score     float32 `json:"score" yaml:"score"`
+}
+
+// NodeHealth is the Health RPC result for one node.
+type NodeHealth struct {
+	Address    string  `json:"address" yaml:"address"`
+	Region     string  `json:"region" yaml:"region"`
+	Healthy    bool    `json:"healthy" yaml:"healthy"`
+	NodeMode   string  `json:"node_mode,omitempty" yaml:"node_mode,omitempty"`
+	MemoryUsed float32 `json:"memory_used" yaml:"memory_used"`
+	CPUUsed    float32 `json:"cpu_used" yaml:"cpu_used"`
+	DiskUsed   float32 `json:"disk_used" yaml:"disk_used"`
+	P2PAddress string  `json:"p2p_address,omitempty" yaml:"p2p_address,omitempty"`
+	Country    string  `json:"country,omitempty" yaml:"country,omitempty"`
+	CountryISO string  `json:"country_iso,omitempty" yaml:"country_iso,omitempty"`
+	LatencyMs  int64   `json:"latency_ms" yaml:"latency_ms"`
+	Error      string  `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// NodeTopics is the ListTopics RPC result for one node.
+type NodeTopics struct {
+	Address string   `json:"address" yaml:"address"`
+	Region  string   `json:"region" yaml:"region"`
+	Topics  []string `json:"topics" yaml:"topics"`
+	Error   string   `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+var nodesCmd = &cobra.Command{
+	Use:   "nodes",
+	Short: "Inspect the nodes of your session",
+	Long: `Inspect the nodes the proxy assigns to your session. health and topics
+query every node directly over gRPC instead of going through the proxy.`,
+}
+
+var nodesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the nodes of the current session",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sess, err := nodesSession()
+		if err != nil {
+			return err
+		}
+
+		infos := make([]NodeInfo, len(sess.Nodes))
+		for i, n := range sess.Nodes {
+			infos[i] = NodeInfo{ID: n.ID, Address: n.Address, Region: nodeRegion(n), Transport: n.Transport, Score: n.Score}
+		}
+
+		f := formatter.New(GetOutputFormat())
+		if !f.IsTable() {
+			output, err := f.Format(infos)
+			if err != nil {
+				return fmt.Errorf("failed to format output: %v", err)
+			}
+			fmt.Println(output)
+			return nil
+		}
+
+		fmt.Printf("Session %s (%d node(s)):\n", sess.SessionID, len(infos))
+		for i, n := range infos {
+			fmt.Printf("  %d. %-24s %-14s %-10s score %.2f\n", i+1, n.Address, n.Region, n.Transport, n.Score)
+		}
+		return nil
+	},
+}
+
+var nodesHealthCmd = &cobra.Command{
+	Use:   "health",
+	Short: "Query the Health RPC of every session node",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sess, err := nodesSession()
+		if err != nil {
+			return err
+		}
+
+		results := make([]NodeHealth, len(sess.Nodes))
+		forEachNode(sess.Nodes, func(i int, n session.Node, c *node.Client, ctx context.Context) {
+			r := NodeHealth{Address: n.Address, Region: nodeRegion(n)}
+			start := time.Now()
+			h, err := c.Health(ctx)
+			r.LatencyMs = time.Since(start).Milliseconds()
+			if err != nil {
+				r.Error = err.Error()
+			} else {
+				r.Healthy = h.GetStatus()
+				r.NodeMode = h.GetNodeMode()
+				r.MemoryUsed = h.GetMemoryUsed()
+				r.CPUUsed = h.GetCpuUsed()
+				r.DiskUsed = h.GetDiskUsed()
+				r.P2PAddress = h.GetP2PAddress()
+				r.Country = h.GetCountry()
+				r.CountryISO = h.GetCountryIso()
+			}
+			results[i] = r
+		}, func(i int, n session.Node, err error) {
+			results[i] = NodeHealth{Address: n.Address, Region: nodeRegion(n), Error: err.Error()}
+		})
+
+		f := formatter.New(GetOutputFormat())
+		if !f.IsTable() {
+			output, err := f.Format(results)
+			if err != nil {
+				return fmt.Errorf("failed to format output: %v", err)
+			}
+			fmt.Println(output)
+			return nil
+		}
+
+		for _, r := range results {
+			fmt.Printf("%s (%s)\n", r.Address, r.Region)
+			if r.Error != "" {
+				fmt.Printf("  Error:   %s\n", r.Error)
+				continue
+			}
+			status := "healthy"
+			if !r.Healthy {
+				status = "unhealthy"
+			}
+			fmt.Printf("  Status:  %s (%s) in %dms\n", status, r.NodeMode, r.LatencyMs)
+			fmt.Printf("  Memory:  %.1f%%  CPU: %.1f%%  Disk: %.1f%%\n", r.MemoryUsed, r.CPUUsed, r.DiskUsed)
+			if r.P2PAddress != "" {
+				fmt.Printf("  P2P:     %s\n", r.P2PAddress)
+			}
+			if r.Country != "" {
+				fmt.Printf("  Country: %s (%s)\n", r.Country, r.CountryISO)
+			}
+		}
+		return nil
+	},
+}
+
+var nodesTopicsCmd = &cobra.Command{
+	Use:   "topics",
+	Short: "Query the topics every session node has subscribers for",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sess, err := nodesSession()
+		if err != nil {
+			return err
+		}
+
+		results := make([]NodeTopics, len(sess.Nodes))
+		forEachNode(sess.Nodes, func(i int, n session.Node, c *node.Client, ctx context.Context) {
+			r := NodeTopics{Address: n.Address, Region: nodeRegion(n), Topics: []string{}}
+			topics, err := c.ListTopics(ctx)
+			if err != nil {
+				r.Error = err.Error()
+			} else {
+				sort.Strings(topics)
+				r.Topics = append(r.Topics, topics...)
+			}
+			results[i] = r
+		}, func(i int, n session.Node, err error) {
+			results[i] = NodeTopics{Address: n.Address, Region: nodeRegion(n), Topics: []string{}, Error: err.Error()}
+		})
+
+		f := formatter.New(GetOutputFormat())
+		if !f.IsTable() {
+			output, err := f.Format(results)
+			if err != nil {
+				return fmt.Errorf("failed to format output: %v", err)
+			}
+			fmt.Println(output)
+			return nil
+		}
+
+		for _, r := range results {
+			switch {
+			case r.Error != "":
+				fmt.Printf("%s (%s): %s\n", r.Address, r.Region, r.Error)
+			case len(r.Topics) == 0:
+				fmt.Printf("%s (%s): no topics\n", r.Address, r.Region)
+			default:
+				fmt.Printf("%s (%s): %d topic(s)\n", r.Address, r.Region, len(r.Topics))
+				for _, t := range r.Topics {
+					fmt.Printf("  - %s\n", t)
+				}
+			}
+		}
+		return nil
+	},
+}
+
+// nodesSession returns the session whose nodes the nodes commands inspect.
+func nodesSession() (*session.Session, error) {
+	var clientIDToUse, accessToken string
+	if !IsAuthDisabled() {
+		tokenStr, claims, err := loadTokenAndClaims(GetAuthPath())
+		if err != nil {
+			return nil, err
+		}
+		accessToken = tokenStr
+		clientIDToUse = claims.ClientID
+		limitNodeMessages(claims)
+	} else {
+		clientIDToUse = GetClientID()
+		if clientIDToUse == "" {
+			return nil, fmt.Errorf("--client-id is required when using --disable-auth")
+		}
+	}
+
+	var topics []string
+	if nodesTopic != "" {
+		topics = []string{nodesTopic}
+	}
+	sessReq, err := newSessionRequest(nodesServiceURL, clientIDToUse, accessToken, topics, []string{"subscribe"}, nodesExposeAmount, "", "")
+	if err != nil {
+		return nil, err
+	}
+	sess, _, err := sessionStore().GetOrCreate(sessReq)
+	if err != nil {
+		return nil, fmt.Errorf("session creation failed: %w", err)
+	}
+	if err := orderSessionNodes(sess, nodesStrategy); err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
+
+// forEachNode connects to every node concurrently and calls query with a
+// ready client, or failed when the node cannot be reached.
+func forEachNode(nodes []session.Node, query func(i int, n session.Node, c *node.Client, ctx context.Context), failed func(i int, n session.Node, err error)) {
+	var wg sync.WaitGroup
+	for i, n := range nodes {
+		wg.Add(1)
+		go func(i int, n session.Node) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), nodesTimeout)
+			defer cancel()
+			c, err := dialNode(n)
+			if err != nil {
+				failed(i, n, err)
+				return
+			}
+			defer c.Close()
+			if err := c.WaitReady(ctx); err != nil {
+				failed(i, n, err)
+				return
+			}
+			query(i, n, c, ctx)
+		}(i, n)
+	}
+	wg.Wait()
+}
+
+func init() {
+	nodesCmd.PersistentFlags().StringVar(&nodesTopic, "topic", "", "Topic to request the session for (default: reuse any cached session)")
+	nodesCmd.PersistentFlags().StringVar(&nodesServiceURL, "service-url", "", "Override the default proxy URL (comma-separated URLs fail over in turn)")
+	nodesCmd.PersistentFlags().Uint32Var(&nodesExposeAmount, "expose-amount", 3, "Number of nodes to request from proxy")
+	nodesCmd.PersistentFlags().StringVar(&nodesStrategy, "node-strategy", "proxy", "Node order: proxy, rtt or region")
+	nodesCmd.PersistentFlags().DurationVar(&nodesTimeout, "timeout", 5*time.Second, "Per-node timeout for health and topics")
+	nodesCmd.AddCommand(nodesListCmd, nodesHealthCmd, nodesTopicsCmd)
+	rootCmd.AddCommand(nodesCmd)
+}
@@ -11,10 +11,12 @@ import (
 	"time"
 
 	"github.com/getoptimum/mump2p-cli/internal/auth"
-	"github.com/getoptimum/mump2p-cli/internal/config"
+	"github.com/getoptimum/mump2p-cli/internal/chunk"
+	"github.com/getoptimum/mump2p-cli/internal/formatter"
+	"github.com/getoptimum/mump2p-cli/internal/keyring"
 	"github.com/getoptimum/mump2p-cli/internal/node"
+	"github.com/getoptimum/mump2p-cli/internal/payload"
 	"github.com/getoptimum/mump2p-cli/internal/ratelimit"
-	"github.com/getoptimum/mump2p-cli/internal/session"
 	pb "github.com/getoptimum/mump2p-cli/proto"
 	"github.com/spf13/cobra"
 )
@@ -25,6 +27,22 @@ var (
 	file            string
 	serviceURL      string
 	pubExposeAmount uint32
+	pubHedge        time.Duration
+	pubFanout       int
+	pubNodeStrategy string
+	pubRegion       string
+	pubProtocol     string
+	pubMessageHex   string
+	pubMessageB64   string
+	pubProtoDesc    string
+	pubProtoType    string
+	pubCompress     string
+	pubEncrypt      bool
+	pubKey          string
+	pubRecipients   []string
+	pubSign         string
+	pubChunked      bool
+	pubChunkSize    int
 )
 
 func addDebugPrefix(data []byte, addr string) []byte {
@@ -37,11 +55,42 @@ func printDebugInfo(data []byte, addr string, topic string) {
 	currentTime := time.Now().UnixNano()
 	sum := sha256.Sum256(data)
 	hash := hex.EncodeToString(sum[:])
-	fmt.Printf("Publish:\tsender_info:%s, [send_time, size]:[%d, %d]\ttopic:%s\tmsg_hash:%s\tprotocol:gRPC-direct\n",
+	fmt.Fprintf(statusOut(), "Publish:\tsender_info:%s, [send_time, size]:[%d, %d]\ttopic:%s\tmsg_hash:%s\tprotocol:gRPC-direct\n",
 		addr, currentTime, len(data), topic, hash[:8])
 }
 
-func shortMsgID(resp *pb.Response) string {
+// PublishResult describes a successful publish.
+type PublishResult struct {
+	Topic         string         `json:"topic" yaml:"topic"`
+	Node          string         `json:"node" yaml:"node"`
+	Region        string         `json:"region" yaml:"region"`
+	MessageID     string         `json:"message_id,omitempty" yaml:"message_id,omitempty"`
+	SHA256        string         `json:"sha256" yaml:"sha256"`
+	Size          int            `json:"size" yaml:"size"`
+	WireSize      int            `json:"wire_size" yaml:"wire_size"`
+	Codec         string         `json:"codec,omitempty" yaml:"codec,omitempty"`
+	Encryption    string         `json:"encryption,omitempty" yaml:"encryption,omitempty"`
+	Signer        string         `json:"signer,omitempty" yaml:"signer,omitempty"`
+	SessionID     string         `json:"session_id" yaml:"session_id"`
+	SessionReused bool           `json:"session_reused" yaml:"session_reused"`
+	Latency       PublishLatency `json:"latency" yaml:"latency"`
+	Attempts      []node.Attempt `json:"attempts,omitempty" yaml:"attempts,omitempty"`
+}
+
+// PublishLatency breaks down where the time of a publish went. SessionMs
+// is near zero when a cached session was reused.
+type PublishLatency struct {
+	SessionMs float64 `json:"session_ms" yaml:"session_ms"`
+	PublishMs float64 `json:"publish_ms" yaml:"publish_ms"`
+	TotalMs   float64 `json:"total_ms" yaml:"total_ms"`
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// traceMessageID returns the message ID from a node's publish trace.
+func traceMessageID(resp *pb.Response) string {
 	if resp == nil || len(resp.Data) == 0 {
 		return ""
 	}
@@ -50,15 +99,9 @@ func shortMsgID(resp *pb.Response) string {
 		return ""
 	}
 	if mid, ok := trace["messageID"].(string); ok && mid != "" {
-		if len(mid) > 8 {
-			return mid[:8]
-		}
 		return mid
 	}
 	if mid, ok := trace["message_id"].(string); ok && mid != "" {
-		if len(mid) > 8 {
-			return mid[:8]
-		}
 		return mid
 	}
 	return ""
@@ -68,186 +111,391 @@ var publishCmd = &cobra.Command{
 	Use:   "publish",
 	Short: "Publish a message to the Optimum Network",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if pubMessage == "" && file == "" {
-			return errors.New("either --message or --file must be provided")
+		inputs := 0
+		for _, in := range []string{pubMessage, pubMessageHex, pubMessageB64, file} {
+			if in != "" {
+				inputs++
+			}
+		}
+		if inputs == 0 {
+			return withExitCode(ExitUsage, errors.New("one of --message, --message-hex, --message-base64 or --file must be provided"))
+		}
+		if inputs > 1 {
+			return withExitCode(ExitUsage, errors.New("only one of --message, --message-hex, --message-base64 or --file should be used at a time"))
+		}
+		if pubFanout < 1 {
+			return withExitCode(ExitUsage, errors.New("--fanout must be at least 1"))
 		}
-		if pubMessage != "" && file != "" {
-			return errors.New("only one of --message or --file should be used at a time")
+		if pubChunked {
+			switch {
+			case file == "":
+				return withExitCode(ExitUsage, errors.New("--chunked needs --file"))
+			case pubProtoDesc != "" || pubProtoType != "":
+				return withExitCode(ExitUsage, errors.New("--chunked cannot be combined with --proto-descriptor"))
+			case pubHedge > 0 || pubFanout > 1:
+				return withExitCode(ExitUsage, errors.New("--chunked cannot be combined with --hedge or --fanout"))
+			case pubChunkSize <= 0:
+				return withExitCode(ExitUsage, errors.New("--chunk-size must be positive"))
+			}
+		}
+		f := formatter.New(GetOutputFormat())
+
+		claims, clientIDToUse, accessToken, err := loadPublishCredentials()
+		if err != nil {
+			return err
 		}
 
-		var claims *auth.TokenClaims
-		var clientIDToUse string
-		var accessToken string
+		var data []byte
 
-		if !IsAuthDisabled() {
-			authClient := auth.NewClient()
-			storage := auth.NewStorageWithPath(GetAuthPath())
-			token, err := authClient.GetValidToken(storage)
+		switch {
+		case pubChunked:
+			// read chunk by chunk in publishChunked
+		case file != "":
+			content, err := os.ReadFile(file)
 			if err != nil {
-				return fmt.Errorf("authentication required: %v", err)
+				return withExitCode(ExitUsage, fmt.Errorf("failed to read file: %v", err))
 			}
-			accessToken = token.Token
-			parser := auth.NewTokenParser()
-			claims, err = parser.ParseToken(token.Token)
+			data = content
+		case pubMessageHex != "":
+			decoded, err := payload.Decode(pubMessageHex, payload.Hex)
 			if err != nil {
-				return fmt.Errorf("error parsing token: %v", err)
+				return withExitCode(ExitUsage, err)
 			}
-			if !claims.IsActive {
-				return fmt.Errorf("your account is inactive, please contact support")
+			data = decoded
+		case pubMessageB64 != "":
+			decoded, err := payload.Decode(pubMessageB64, payload.Base64)
+			if err != nil {
+				return withExitCode(ExitUsage, err)
 			}
-			clientIDToUse = claims.ClientID
-		} else {
-			clientIDToUse = GetClientID()
-			if clientIDToUse == "" {
-				return fmt.Errorf("--client-id is required when using --disable-auth")
+			data = decoded
+		default:
+			data = []byte(pubMessage)
+		}
+
+		codec, err := loadProtoCodec(pubProtoDesc, pubProtoType)
+		if err != nil {
+			return err
+		}
+		if codec != nil {
+			if data, err = codec.FromJSON(data); err != nil {
+				return withExitCode(ExitUsage, err)
 			}
 		}
 
-		var data []byte
+		toWire, wi, err := loadWireEncoder()
+		if err != nil {
+			return err
+		}
+		if pubChunked {
+			result := ChunkedPublishResult{Codec: wi.Codec, Encryption: wi.Encryption, Signer: wi.Signer}
+			return publishChunked(f, claims, clientIDToUse, accessToken, toWire, result)
+		}
 
-		if file != "" {
-			content, err := os.ReadFile(file)
-			if err != nil {
-				return fmt.Errorf("failed to read file: %v", err)
-			}
-			data = content
-		} else {
-			data = []byte(pubMessage)
+		wire, err := toWire(data)
+		if err != nil {
+			return err
 		}
 
-		messageSize := int64(len(data))
+		// limits and quotas count the bytes on the wire
+		messageSize := int64(len(wire))
 
 		if !IsAuthDisabled() {
 			limiter, err := ratelimit.NewRateLimiterWithDir(claims, GetAuthDir())
 			if err != nil {
 				return fmt.Errorf("rate limiter setup failed: %v", err)
 			}
-			if err := limiter.CheckPublishAllowed(messageSize); err != nil {
+			// every fanout copy reaches the mesh; hedged copies are
+			// counted once they start
+			if err := limiter.CheckPublishesAllowed(pubFanout, messageSize); err != nil {
 				return err
 			}
 		}
 
-		proxyURL := config.LoadConfig().ServiceUrl
-		if serviceURL != "" {
-			proxyURL = serviceURL
+		// hedging needs more than one node to choose from
+		expose := max(pubExposeAmount, uint32(pubFanout))
+		if pubHedge > 0 {
+			expose = max(expose, 2)
+		}
+
+		sessReq, err := newSessionRequest(serviceURL, clientIDToUse, accessToken, []string{pubTopic}, []string{"publish"}, expose, pubRegion, pubProtocol)
+		if err != nil {
+			return err
 		}
 
 		sessionStart := time.Now()
-		sess, reused, err := session.GetOrCreateSession(
-			proxyURL,
-			clientIDToUse,
-			accessToken,
-			[]string{pubTopic},
-			[]string{"publish"},
-			pubExposeAmount,
-		)
+		sess, reused, err := sessionStore().GetOrCreate(sessReq)
 		if err != nil {
-			return fmt.Errorf("session creation failed: %v", err)
+			return fmt.Errorf("session creation failed: %w", err)
 		}
 		sessionDur := time.Since(sessionStart)
 
 		if IsDebugMode() {
 			if reused {
-				fmt.Printf("Reusing session %s | %d node(s) available\n", sess.SessionID, len(sess.Nodes))
+				fmt.Fprintf(statusOut(), "Reusing session %s | %d node(s) available\n", sess.SessionID, len(sess.Nodes))
 			} else {
-				fmt.Printf("New session %s from %s (%s) | %d node(s) available\n",
-					sess.SessionID, proxyURL, humanDuration(sessionDur), len(sess.Nodes))
+				fmt.Fprintf(statusOut(), "New session %s from %s (%s) | %d node(s) available\n",
+					sess.SessionID, sess.Proxy, humanDuration(sessionDur), len(sess.Nodes))
 			}
 		}
 
-		var published bool
-		for i, n := range sess.Nodes {
-			if IsDebugMode() {
-				fmt.Printf("  Trying node %d/%d: %s (%s, score: %.2f)...\n",
-					i+1, len(sess.Nodes), n.Address, n.Region, n.Score)
-			}
+		if err := orderSessionNodes(sess, pubNodeStrategy); err != nil {
+			return err
+		}
 
+		targets := make([]node.PublishTarget, len(sess.Nodes))
+		for i, n := range sess.Nodes {
 			nodeAddr := extractIPFromURL(n.Address)
 			if nodeAddr == "" {
 				nodeAddr = n.Address
 			}
-
-			publishData := data
-			if IsDebugMode() {
-				publishData = addDebugPrefix(data, nodeAddr)
-			}
-
-			connectStart := time.Now()
-			nc, connErr := node.NewClient(n.Address)
-			if connErr != nil {
-				fmt.Printf("  Node %s unreachable: %v\n", n.Address, connErr)
-				continue
-			}
-
-			ctx, ctxCancel := context.WithTimeout(context.Background(), 10*time.Second)
-			resp, pubErr := nc.Publish(ctx, n.Ticket, pubTopic, publishData)
-			rpcDur := time.Since(connectStart)
-			ctxCancel()
-			nc.Close()
-
-			if pubErr != nil {
-				fmt.Printf("  Node %s failed: %v\n", n.Address, pubErr)
-				continue
+			dialOpts, err := nodeDialOptions(n)
+			if err != nil {
+				return err
 			}
-
+			targets[i] = node.PublishTarget{Address: n.Address, Ticket: n.Ticket, Data: wire, Dial: dialOpts}
 			if IsDebugMode() {
-				printDebugInfo(publishData, nodeAddr, pubTopic)
-			}
-
-			region := n.Region
-			if region == "" {
-				region = "unknown"
+				if targets[i].Data, err = toWire(addDebugPrefix(data, nodeAddr)); err != nil {
+					return err
+				}
 			}
+		}
 
-			msgID := shortMsgID(resp)
-			suffix := ""
-			if msgID != "" {
-				suffix = fmt.Sprintf(" [msg: %s]", msgID)
+		opts := node.HedgeOptions{
+			Delay:   pubHedge,
+			Fanout:  pubFanout,
+			Timeout: 10 * time.Second,
+		}
+		if IsDebugMode() {
+			opts.OnStart = func(i int, t node.PublishTarget) {
+				n := sess.Nodes[i]
+				fmt.Fprintf(statusOut(), "  Trying node %d/%d: %s (%s, score: %.2f)...\n",
+					i+1, len(sess.Nodes), n.Address, n.Region, n.Score)
 			}
+		}
 
-			if IsDebugMode() && !reused {
-				fmt.Printf("Session: %s | Published: %s | Total: %s\n",
-					humanDuration(sessionDur), humanDuration(rpcDur),
-					humanDuration(sessionDur+rpcDur))
+		res, pubErr := node.HedgedPublish(context.Background(), pubTopic, targets, opts)
+		if res == nil {
+			return withExitCode(ExitNodesFailed, pubErr)
+		}
+		for _, a := range res.Attempts {
+			if a.Status == node.AttemptFailed {
+				fmt.Fprintf(statusOut(), "  Node %s failed: %v\n", a.Address, a.Error)
 			}
+		}
+		if pubErr != nil {
+			return withExitCode(ExitNodesFailed, pubErr)
+		}
 
-			fmt.Printf("Published to %s (%s) in %s%s\n",
-				n.Address, region, humanDuration(rpcDur), suffix)
+		n := sess.Nodes[res.Winner]
+		resp := res.Response
+		rpcDur := res.Attempts[res.Winner].Latency
 
-			if IsDebugMode() && resp != nil && msgID != "" {
-				var trace map[string]interface{}
-				if json.Unmarshal(resp.Data, &trace) == nil {
-					if mid, ok := trace["messageID"].(string); ok && mid != "" {
-						fmt.Printf("  message-id: %s\n", mid)
-					} else if mid, ok := trace["message_id"].(string); ok && mid != "" {
-						fmt.Printf("  message-id: %s\n", mid)
-					}
-				}
+		if IsDebugMode() {
+			nodeAddr := extractIPFromURL(n.Address)
+			if nodeAddr == "" {
+				nodeAddr = n.Address
 			}
+			printDebugInfo(targets[res.Winner].Data, nodeAddr, pubTopic)
+		}
 
-			published = true
-			break
+		sum := sha256.Sum256(data)
+		result := PublishResult{
+			Topic:         pubTopic,
+			Node:          n.Address,
+			Region:        nodeRegion(n),
+			MessageID:     traceMessageID(resp),
+			SHA256:        hex.EncodeToString(sum[:]),
+			Size:          len(data),
+			WireSize:      len(wire),
+			SessionID:     sess.SessionID,
+			SessionReused: reused,
+			Latency: PublishLatency{
+				SessionMs: millis(sessionDur),
+				PublishMs: millis(rpcDur),
+				TotalMs:   millis(sessionDur + rpcDur),
+			},
+		}
+		result.Codec, result.Encryption, result.Signer = wi.Codec, wi.Encryption, wi.Signer
+		if pubHedge > 0 || pubFanout > 1 {
+			result.Attempts = res.Attempts
 		}
 
-		if !published {
-			return fmt.Errorf("all %d node(s) failed to publish", len(sess.Nodes))
+		if !f.IsTable() {
+			output, err := f.Format(result)
+			if err != nil {
+				return fmt.Errorf("failed to format output: %v", err)
+			}
+			fmt.Println(output)
+		} else {
+			printPublishResult(result, sessionDur, rpcDur)
 		}
 
 		if !IsAuthDisabled() {
 			if limiter, err := ratelimit.NewRateLimiterWithDir(claims, GetAuthDir()); err == nil {
-				_ = limiter.RecordPublish(messageSize)
+				_ = limiter.RecordPublishes(startedAttempts(res), messageSize)
 			}
 		}
 		return nil
 	},
 }
 
+// startedAttempts counts the attempts of a hedged publish that were sent,
+// each of which is a real publish.
+func startedAttempts(res *node.HedgeResult) int {
+	n := 0
+	for _, a := range res.Attempts {
+		if a.Status != node.AttemptSkipped {
+			n++
+		}
+	}
+	return n
+}
+
+// loadPublishCredentials returns the token claims, client ID and access
+// token to publish with. claims is nil when auth is disabled.
+func loadPublishCredentials() (*auth.TokenClaims, string, string, error) {
+	if IsAuthDisabled() {
+		clientID := GetClientID()
+		if clientID == "" {
+			return nil, "", "", withExitCode(ExitUsage, fmt.Errorf("--client-id is required when using --disable-auth"))
+		}
+		return nil, clientID, "", nil
+	}
+	token, claims, err := loadTokenAndClaims(GetAuthPath())
+	if err != nil {
+		return nil, "", "", err
+	}
+	limitNodeMessages(claims)
+	return claims, claims.ClientID, token, nil
+}
+
+// wireInfo names the codec, encryption and signer a wire encoder applies;
+// each is empty when unused.
+type wireInfo struct {
+	Codec      string
+	Encryption string
+	Signer     string
+}
+
+// loadWireEncoder returns the function that turns a payload into what is
+// sent, following --compress, --encrypt and --sign. It compresses, then
+// encrypts, since ciphertext does not compress, and signs last so
+// subscribers can check the sender before decrypting.
+func loadWireEncoder() (func([]byte) ([]byte, error), wireInfo, error) {
+	var info wireInfo
+	var compressor payload.Codec
+	if pubCompress != "" && pubCompress != "none" {
+		var err error
+		if compressor, err = payload.LookupCodec(pubCompress); err != nil {
+			return nil, info, withExitCode(ExitUsage, err)
+		}
+		info.Codec = compressor.Name()
+	}
+	seal, encryption, err := loadSealer(pubEncrypt, pubTopic, pubKey, pubRecipients)
+	if err != nil {
+		return nil, info, err
+	}
+	info.Encryption = encryption
+	signer, err := loadSigner(pubSign)
+	if err != nil {
+		return nil, info, err
+	}
+	if signer != nil {
+		info.Signer = signer.Name
+	}
+	return func(b []byte) ([]byte, error) {
+		var err error
+		if compressor != nil {
+			if b, err = payload.Wrap(compressor, b); err != nil {
+				return nil, err
+			}
+		}
+		if seal != nil {
+			if b, err = seal(b); err != nil {
+				return nil, fmt.Errorf("encryption failed: %v", err)
+			}
+		}
+		if signer != nil {
+			if b, err = keyring.Sign(*signer, pubTopic, b); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	}, info, nil
+}
+
+// printPublishResult prints r for table output.
+func printPublishResult(r PublishResult, sessionDur, publishDur time.Duration) {
+	if IsDebugMode() && !r.SessionReused {
+		fmt.Printf("Session: %s | Published: %s | Total: %s\n",
+			humanDuration(sessionDur), humanDuration(publishDur),
+			humanDuration(sessionDur+publishDur))
+	}
+
+	suffix := ""
+	if r.MessageID != "" {
+		short := r.MessageID
+		if len(short) > 8 {
+			short = short[:8]
+		}
+		suffix = fmt.Sprintf(" [msg: %s]", short)
+	}
+	if r.Codec != "" {
+		suffix += fmt.Sprintf(" [%s: %d → %d bytes]", r.Codec, r.Size, r.WireSize)
+	}
+	if r.Encryption != "" {
+		suffix += fmt.Sprintf(" [encrypted: %s]", r.Encryption)
+	}
+	if r.Signer != "" {
+		suffix += fmt.Sprintf(" [signed: %s]", r.Signer)
+	}
+	fmt.Printf("Published to %s (%s) in %s%s\n",
+		r.Node, r.Region, humanDuration(publishDur), suffix)
+
+	if len(r.Attempts) > 0 {
+		printHedgeAttempts(r.Attempts)
+	}
+	if IsDebugMode() && r.MessageID != "" {
+		fmt.Printf("  message-id: %s\n", r.MessageID)
+	}
+}
+
+// printHedgeAttempts lists every node a hedged publish was sent to.
+func printHedgeAttempts(attempts []node.Attempt) {
+	for i, a := range attempts {
+		if a.Status == node.AttemptSkipped {
+			continue
+		}
+		line := fmt.Sprintf("  #%d %s: %s after %s", i+1, a.Address, a.Status, humanDuration(a.Latency))
+		if a.Error != "" {
+			line += " (" + a.Error + ")"
+		}
+		fmt.Println(line)
+	}
+}
+
 func init() {
-	publishCmd.Flags().StringVar(&pubTopic, "topic", "", "Topic to publish to")
+	publishCmd.PersistentFlags().StringVar(&pubTopic, "topic", "", "Topic to publish to")
 	publishCmd.Flags().StringVar(&pubMessage, "message", "", "Message string to publish")
+	publishCmd.Flags().StringVar(&pubMessageHex, "message-hex", "", "Binary message to publish, hex encoded")
+	publishCmd.Flags().StringVar(&pubMessageB64, "message-base64", "", "Binary message to publish, base64 encoded")
 	publishCmd.Flags().StringVar(&file, "file", "", "Path of the file to publish")
-	publishCmd.Flags().StringVar(&serviceURL, "service-url", "", "Override the default proxy URL")
-	publishCmd.Flags().Uint32Var(&pubExposeAmount, "expose-amount", 1, "Number of nodes to request from proxy")
-	publishCmd.MarkFlagRequired("topic") //nolint:errcheck
+	publishCmd.Flags().BoolVar(&pubChunked, "chunked", false, "Split --file into chunks so it can exceed the max message size; subscribers rebuild it with --reassemble")
+	publishCmd.Flags().IntVar(&pubChunkSize, "chunk-size", chunk.DefaultSize, "Bytes of the file per chunk with --chunked (capped below the max message size)")
+	publishCmd.PersistentFlags().StringVar(&pubCompress, "compress", "none", "Compress the message before sending: none, gzip or zstd; subscribers decompress automatically")
+	publishCmd.PersistentFlags().BoolVar(&pubEncrypt, "encrypt", false, "Encrypt the message end to end with the topic's key from the keyring (see 'mump2p keys')")
+	publishCmd.PersistentFlags().StringVar(&pubKey, "key", "", "With --encrypt, use this symmetric key instead of the topic's")
+	publishCmd.PersistentFlags().StringSliceVar(&pubRecipients, "recipients", nil, "With --encrypt, encrypt to these x25519 keys (comma-separated key names) instead")
+	publishCmd.PersistentFlags().StringVar(&pubSign, "sign", "", "Sign the message with this ed25519 key from the keyring so subscribers can verify the sender")
+	publishCmd.Flags().StringVar(&pubProtoDesc, "proto-descriptor", "", "FileDescriptorSet (protoc --include_imports --descriptor_set_out) for encoding a JSON message as protobuf")
+	publishCmd.Flags().StringVar(&pubProtoType, "proto-type", "", "Full name of the message type in --proto-descriptor, e.g. pkg.Msg")
+	publishCmd.PersistentFlags().StringVar(&serviceURL, "service-url", "", "Override the default proxy URL (comma-separated URLs fail over in turn)")
+	publishCmd.PersistentFlags().Uint32Var(&pubExposeAmount, "expose-amount", 1, "Number of nodes to request from proxy")
+	publishCmd.Flags().DurationVar(&pubHedge, "hedge", 0, "Also publish to the next node if no ack arrives within this delay (e.g. 50ms)")
+	publishCmd.Flags().IntVar(&pubFanout, "fanout", 1, "Publish to this many nodes at once; the first ack wins")
+	publishCmd.PersistentFlags().StringVar(&pubNodeStrategy, "node-strategy", "proxy", "Node selection: proxy (proxy order), rtt (measured latency and score) or region (nearest region first)")
+	publishCmd.PersistentFlags().StringVar(&pubRegion, "region", "", "Ask the proxy for nodes in this region")
+	publishCmd.PersistentFlags().StringVar(&pubProtocol, "protocol", "", "Ask the proxy for nodes running this protocol: mump2p or gossipsub")
+	publishCmd.MarkPersistentFlagRequired("topic") //nolint:errcheck
 	rootCmd.AddCommand(publishCmd)
 }
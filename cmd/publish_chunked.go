@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/getoptimum/mump2p-cli/internal/auth"
+	"github.com/getoptimum/mump2p-cli/internal/chunk"
+	"github.com/getoptimum/mump2p-cli/internal/formatter"
+)
+
+// ChunkedPublishResult describes a file published with --chunked.
+type ChunkedPublishResult struct {
+	Topic      string  `json:"topic" yaml:"topic"`
+	File       string  `json:"file" yaml:"file"`
+	TransferID string  `json:"transfer_id" yaml:"transfer_id"`
+	SHA256     string  `json:"sha256" yaml:"sha256"`
+	Size       int64   `json:"size" yaml:"size"`
+	Chunks     int     `json:"chunks" yaml:"chunks"`
+	ChunkSize  int     `json:"chunk_size" yaml:"chunk_size"`
+	WireSize   int64   `json:"wire_size" yaml:"wire_size"`
+	Codec      string  `json:"codec,omitempty" yaml:"codec,omitempty"`
+	Encryption string  `json:"encryption,omitempty" yaml:"encryption,omitempty"`
+	Signer     string  `json:"signer,omitempty" yaml:"signer,omitempty"`
+	Node       string  `json:"node" yaml:"node"`
+	Region     string  `json:"region" yaml:"region"`
+	SessionID  string  `json:"session_id" yaml:"session_id"`
+	DurationMs float64 `json:"duration_ms" yaml:"duration_ms"`
+}
+
+// chunkOverhead measures how much larger than its data a chunk of size bytes
+// is on the wire: the chunk header plus the compression, encryption and
+// signature envelopes. Random data stands in for the file, since it is what
+// compression grows the most.
+func chunkOverhead(toWire func([]byte) ([]byte, error), size int) (int, error) {
+	probe := make([]byte, chunk.MaxHeaderSize+size)
+	if _, err := rand.Read(probe[chunk.MaxHeaderSize:]); err != nil {
+		return 0, err
+	}
+	wire, err := toWire(probe)
+	if err != nil {
+		return 0, err
+	}
+	return len(wire) - size, nil
+}
+
+// publishChunked publishes --file as a manifest followed by its chunks,
+// each passed through toWire. result carries the codec, encryption and
+// signer; the rest is filled in here.
+func publishChunked(f *formatter.Formatter, claims *auth.TokenClaims, clientID, accessToken string, toWire func([]byte) ([]byte, error), result ChunkedPublishResult) error {
+	chunkSize := pubChunkSize
+	overhead := 0
+	if claims != nil {
+		chunkSize = min(chunkSize, int(claims.MaxMessageSize))
+		var err error
+		if overhead, err = chunkOverhead(toWire, chunkSize); err != nil {
+			return err
+		}
+		if limit := int(claims.MaxMessageSize) - overhead; chunkSize > limit {
+			if limit <= 0 {
+				return fmt.Errorf("max message size of %d bytes is too small for chunking", claims.MaxMessageSize)
+			}
+			chunkSize = limit
+		}
+	}
+
+	manifest, err := chunk.NewManifest(file, chunkSize)
+	if err != nil {
+		return withExitCode(ExitUsage, fmt.Errorf("failed to read file: %v", err))
+	}
+	in, err := os.Open(file)
+	if err != nil {
+		return withExitCode(ExitUsage, fmt.Errorf("failed to read file: %v", err))
+	}
+	defer in.Close()
+
+	stream, err := openPublishStream(claims, clientID, accessToken)
+	if err != nil {
+		return err
+	}
+	defer stream.close()
+	if stream.limiter != nil {
+		st := stream.limiter.GetUsageStats()
+		// the manifest is one more message
+		if msgs := manifest.Total + 1; st.PublishCount+msgs > st.PublishLimitPerHour {
+			return withExitCode(ExitRateLimited, fmt.Errorf("%d byte file needs %d messages, more than the remaining publish limit (%d of %d/hour used), resets in %s",
+				manifest.Size, msgs, st.PublishCount, st.PublishLimitPerHour, st.TimeUntilReset))
+		}
+		if wire := manifest.Size + int64(manifest.Total)*int64(overhead); st.BytesPublished+wire > st.DailyQuota {
+			return withExitCode(ExitRateLimited, fmt.Errorf("%d byte file exceeds the remaining daily quota (%d of %d bytes used), resets in %s",
+				manifest.Size, st.BytesPublished, st.DailyQuota, st.TimeUntilReset))
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if f.IsTable() {
+		fmt.Fprintf(statusOut(), "Publishing %s (%s) in %d chunk(s) of up to %s [transfer: %s]\n",
+			manifest.Name, humanBytes(uint64(manifest.Size)), manifest.Total, humanBytes(uint64(chunkSize)), manifest.ID[:8])
+	}
+
+	start := time.Now()
+	var wireSize int64
+	publish := func(msg []byte, what string) error {
+		wire, err := toWire(msg)
+		if err != nil {
+			return err
+		}
+		if err := stream.publish(ctx, wire); err != nil {
+			return fmt.Errorf("failed to publish %s: %w", what, err)
+		}
+		wireSize += int64(len(wire))
+		return nil
+	}
+
+	if err := publish(manifest.Encode(), "manifest"); err != nil {
+		return err
+	}
+	for seq := range manifest.Total {
+		msg, err := manifest.Chunk(in, seq)
+		if err != nil {
+			return fmt.Errorf("failed to read chunk %d: %v", seq, err)
+		}
+		if err := publish(msg, fmt.Sprintf("chunk %d/%d", seq+1, manifest.Total)); err != nil {
+			return err
+		}
+		if IsDebugMode() {
+			fmt.Fprintf(statusOut(), "  sent chunk %d/%d via %s\n", seq+1, manifest.Total, stream.node().Address)
+		}
+	}
+	elapsed := time.Since(start)
+
+	n := stream.node()
+	result.Topic = pubTopic
+	result.File = file
+	result.TransferID = manifest.ID
+	result.SHA256 = manifest.SHA256
+	result.Size = manifest.Size
+	result.Chunks = manifest.Total
+	result.ChunkSize = manifest.ChunkSize
+	result.WireSize = wireSize
+	result.Node = n.Address
+	result.Region = nodeRegion(n)
+	result.SessionID = stream.sess.SessionID
+	result.DurationMs = millis(elapsed)
+
+	if !f.IsTable() {
+		output, err := f.Format(result)
+		if err != nil {
+			return fmt.Errorf("failed to format output: %v", err)
+		}
+		fmt.Println(output)
+		return nil
+	}
+	suffix := ""
+	if result.Codec != "" {
+		suffix += fmt.Sprintf(" [%s: %d → %d bytes]", result.Codec, result.Size, result.WireSize)
+	}
+	if result.Encryption != "" {
+		suffix += fmt.Sprintf(" [encrypted: %s]", result.Encryption)
+	}
+	if result.Signer != "" {
+		suffix += fmt.Sprintf(" [signed: %s]", result.Signer)
+	}
+	fmt.Printf("Published %s in %d chunk(s) to %s (%s) in %s%s\n",
+		manifest.Name, manifest.Total, n.Address, result.Region, humanDuration(elapsed), suffix)
+	return nil
+}
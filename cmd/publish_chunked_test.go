@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/getoptimum/mump2p-cli/internal/chunk"
+	"github.com/getoptimum/mump2p-cli/internal/payload"
+	"github.com/stretchr/testify/require"
+)
+
+// TestChunkOverhead tests that a chunk sized by the measured overhead fits
+// the max message size after compression grows it.
+func TestChunkOverhead(t *testing.T) {
+	gzip, err := payload.LookupCodec("gzip")
+	require.NoError(t, err)
+	toWire := func(b []byte) ([]byte, error) { return payload.Wrap(gzip, b) }
+
+	const maxMessage = 64 << 10
+	overhead, err := chunkOverhead(toWire, maxMessage)
+	require.NoError(t, err)
+	require.Greater(t, overhead, chunk.MaxHeaderSize, "compression adds to the header")
+
+	data := make([]byte, maxMessage-overhead)
+	_, err = rand.Read(data)
+	require.NoError(t, err)
+	wire, err := toWire(append(make([]byte, chunk.MaxHeaderSize), data...))
+	require.NoError(t, err)
+	require.LessOrEqual(t, len(wire), maxMessage)
+}
@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/getoptimum/mump2p-cli/internal/auth"
+	"github.com/getoptimum/mump2p-cli/internal/node"
+	"github.com/getoptimum/mump2p-cli/internal/ratelimit"
+	"github.com/getoptimum/mump2p-cli/internal/session"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// publishStream publishes a series of messages to --topic over one session.
+// It keeps one publish stream to the current node open, moves to the next node
+// when one fails and paces sends to the rate limits instead of failing on
+// the per-second one. Long-running callers outlive node tickets, so the
+// session is renewed once it is past its refresh time, or when a node
+// rejects the ticket.
+type publishStream struct {
+	topic        string
+	req          session.Request
+	sess         *session.Session
+	refreshAfter time.Time              // zero when the proxy gave none
+	limiter      *ratelimit.RateLimiter // nil when auth is disabled
+	maxSize      int64                  // 0 when auth is disabled
+	current      int
+	client       *node.Client
+	ps           *node.PublishStream // on client, for the current node's ticket
+}
+
+// openPublishStream creates the session for --topic from the publish flags.
+func openPublishStream(claims *auth.TokenClaims, clientID, accessToken string) (*publishStream, error) {
+	s := &publishStream{topic: pubTopic}
+	if claims != nil {
+		var err error
+		if s.limiter, err = ratelimit.NewRateLimiterWithDir(claims, GetAuthDir()); err != nil {
+			return nil, fmt.Errorf("rate limiter setup failed: %v", err)
+		}
+		s.maxSize = claims.MaxMessageSize
+	}
+
+	var err error
+	if s.req, err = newSessionRequest(serviceURL, clientID, accessToken, []string{pubTopic}, []string{"publish"}, pubExposeAmount, pubRegion, pubProtocol); err != nil {
+		return nil, err
+	}
+	if err := s.renew(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// renew gets a session from the cache or the proxy and starts over at its
+// first node.
+func (s *publishStream) renew() error {
+	sess, _, err := sessionStore().GetOrCreate(s.req)
+	if err != nil {
+		return fmt.Errorf("session creation failed: %w", err)
+	}
+	if err := orderSessionNodes(sess, pubNodeStrategy); err != nil {
+		return err
+	}
+	if len(sess.Nodes) == 0 {
+		return withExitCode(ExitNodesFailed, fmt.Errorf("session has no nodes"))
+	}
+	s.close()
+	s.sess, s.current = sess, 0
+	s.refreshAfter, _ = time.Parse(time.RFC3339, sess.RefreshAfter)
+	return nil
+}
+
+// refresh drops the current session from the cache, so it is not handed
+// out again, and replaces it with a new one.
+func (s *publishStream) refresh(why string) error {
+	old := s.sess.SessionID
+	if _, err := sessionStore().Remove(func(c *session.CachedSession) bool { return c.Session.SessionID == old }); err != nil {
+		return fmt.Errorf("failed to drop session %s: %v", old, err)
+	}
+	if err := s.renew(); err != nil {
+		return err
+	}
+	fmt.Fprintf(statusOut(), "Session renewed (%s): %s\n", why, s.sess.SessionID)
+	return nil
+}
+
+// fits reports whether a message of size bytes is within the max message
+// size, so callers can reject it before it reaches the limiter.
+func (s *publishStream) fits(size int64) error {
+	if s.maxSize > 0 && size > s.maxSize {
+		return fmt.Errorf("%d bytes exceeds the max message size of %d bytes", size, s.maxSize)
+	}
+	return nil
+}
+
+// publish sends wire, waiting for the per-second limit if needed. Errors
+// carry an exit code: the other limits end in ExitRateLimited and running
+// out of nodes in ExitNodesFailed.
+func (s *publishStream) publish(ctx context.Context, wire []byte) error {
+	size := int64(len(wire))
+	if s.limiter != nil {
+		if err := s.limiter.WaitPublishAllowed(ctx, size); err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			return withExitCode(ExitRateLimited, err)
+		}
+	}
+	if !s.refreshAfter.IsZero() && time.Now().After(s.refreshAfter) {
+		if err := s.refresh("past its refresh time"); err != nil {
+			return err
+		}
+	}
+	if err := s.send(ctx, wire); err != nil {
+		return err
+	}
+	if s.limiter != nil {
+		_ = s.limiter.RecordPublish(size)
+	}
+	return nil
+}
+
+func (s *publishStream) send(ctx context.Context, data []byte) error {
+	var lastErr error
+	refreshed := false
+	for tries := 0; tries < len(s.sess.Nodes); tries++ {
+		n := s.sess.Nodes[s.current]
+		if s.client == nil {
+			c, err := dialNode(n)
+			if err != nil {
+				lastErr = err
+				s.next()
+				continue
+			}
+			s.client = c
+		}
+		err := s.sendCurrent(ctx, n, data)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		lastErr = err
+		fmt.Fprintf(statusOut(), "  Node %s failed: %v\n", n.Address, err)
+		if !refreshed && ticketRejected(err) {
+			// the ticket has expired or was revoked; the other nodes'
+			// tickets come from the same session
+			if err := s.refresh("ticket rejected"); err != nil {
+				return err
+			}
+			refreshed, tries = true, -1
+			continue
+		}
+		s.next()
+	}
+	return withExitCode(ExitNodesFailed, fmt.Errorf("all %d node(s) failed: %v", len(s.sess.Nodes), lastErr))
+}
+
+// sendCurrent publishes data to node n over its publish stream, opening the
+// stream first if needed. A stream that worked before may have been dropped
+// while idle, such as by a node restart, so it is reopened once before the
+// node counts as failed.
+func (s *publishStream) sendCurrent(ctx context.Context, n session.Node, data []byte) error {
+	reused := s.ps != nil
+	for {
+		if s.ps == nil {
+			ps, err := s.client.OpenPublishStream(n.Ticket, s.topic)
+			if err != nil {
+				return err
+			}
+			s.ps = ps
+		}
+		pctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		_, err := s.ps.Publish(pctx, data)
+		cancel()
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		s.ps.Close()
+		s.ps = nil
+		if !reused {
+			return err
+		}
+		reused = false
+	}
+}
+
+// ticketRejected reports whether a node refused the session ticket.
+func ticketRejected(err error) bool {
+	switch status.Code(err) {
+	case codes.PermissionDenied, codes.Unauthenticated:
+		return true
+	}
+	return false
+}
+
+// node is the node the last message went to.
+func (s *publishStream) node() session.Node {
+	return s.sess.Nodes[s.current]
+}
+
+func (s *publishStream) next() {
+	s.close()
+	s.current = (s.current + 1) % len(s.sess.Nodes)
+}
+
+func (s *publishStream) close() {
+	if s.ps != nil {
+		s.ps.Close()
+		s.ps = nil
+	}
+	if s.client != nil {
+		s.client.Close()
+		s.client = nil
+	}
+}
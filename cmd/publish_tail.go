@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/getoptimum/mump2p-cli/internal/formatter"
+	"github.com/getoptimum/mump2p-cli/internal/tail"
+	"github.com/spf13/cobra"
+)
+
+var (
+	tailPath       string
+	tailBatch      int
+	tailBatchWait  time.Duration
+	tailMatch      string
+	tailFromStart  bool
+	tailOffsetFile string
+	tailInterval   time.Duration
+)
+
+// TailResult summarizes a publish tail run when it stops.
+type TailResult struct {
+	Topic    string `json:"topic" yaml:"topic"`
+	Path     string `json:"path" yaml:"path"`
+	Messages int    `json:"messages" yaml:"messages"`
+	Lines    int    `json:"lines" yaml:"lines"`
+	Filtered int    `json:"filtered,omitempty" yaml:"filtered,omitempty"`
+	Dropped  int    `json:"dropped,omitempty" yaml:"dropped,omitempty"`
+	Offset   int64  `json:"offset" yaml:"offset"`
+}
+
+var publishTailCmd = &cobra.Command{
+	Use:   "tail",
+	Short: "Publish lines appended to a file, such as a log",
+	Long: `Follow a file like tail -F and publish each new line, or each --batch of
+lines, over one node connection. Rotation and truncation are followed, and
+the read offset is saved after every publish so a restart resumes where
+the last run stopped.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if tailBatch < 1 {
+			return withExitCode(ExitUsage, errors.New("--batch must be at least 1"))
+		}
+		if tailInterval <= 0 {
+			return withExitCode(ExitUsage, errors.New("--interval must be positive"))
+		}
+		var match *regexp.Regexp
+		if tailMatch != "" {
+			var err error
+			if match, err = regexp.Compile(tailMatch); err != nil {
+				return withExitCode(ExitUsage, fmt.Errorf("invalid --match: %v", err))
+			}
+		}
+		path, err := filepath.Abs(tailPath)
+		if err != nil {
+			return withExitCode(ExitUsage, err)
+		}
+		claims, clientID, accessToken, err := loadPublishCredentials()
+		if err != nil {
+			return err
+		}
+		toWire, _, err := loadWireEncoder()
+		if err != nil {
+			return err
+		}
+
+		offsetFile := tailOffsetFile
+		if offsetFile == "" {
+			sum := sha256.Sum256([]byte(path))
+			offsetFile = filepath.Join(GetAuthDir(), "tail", hex.EncodeToString(sum[:8])+".json")
+		}
+		saved, err := tail.LoadPosition(offsetFile, path)
+		if err != nil {
+			return withExitCode(ExitUsage, fmt.Errorf("invalid offset file %s: %v", offsetFile, err))
+		}
+		follower, err := tail.Open(path, saved, tailFromStart)
+		if err != nil {
+			return withExitCode(ExitUsage, fmt.Errorf("failed to open %s: %v", tailPath, err))
+		}
+		defer follower.Close()
+
+		stream, err := openPublishStream(claims, clientID, accessToken)
+		if err != nil {
+			return err
+		}
+		defer stream.close()
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		from := "the end"
+		switch pos := follower.Position(); {
+		case saved != nil && pos == *saved:
+			from = fmt.Sprintf("saved offset %d", pos.Offset)
+		case pos.Offset == 0:
+			from = "the start"
+		}
+		fmt.Fprintf(statusOut(), "Tailing %s to '%s' from %s\n", tailPath, pubTopic, from)
+
+		result := TailResult{Topic: pubTopic, Path: path}
+		var (
+			batch      []string
+			batchStart time.Time
+			pos        = follower.Position()
+			dirty      bool
+		)
+		save := func() error {
+			if err := tail.SavePosition(offsetFile, path, pos); err != nil {
+				return fmt.Errorf("failed to save offset: %v", err)
+			}
+			dirty = false
+			return nil
+		}
+		// flush publishes the batch and saves the offset past it. Messages
+		// too large to send are dropped with a warning, since they would
+		// never fit.
+		flush := func(ctx context.Context) error {
+			if len(batch) == 0 {
+				return nil
+			}
+			wire, err := toWire([]byte(strings.Join(batch, "\n")))
+			if err != nil {
+				return err
+			}
+			if err := stream.fits(int64(len(wire))); err != nil {
+				fmt.Fprintf(statusOut(), "Dropping %d line(s): %v\n", len(batch), err)
+				result.Dropped += len(batch)
+			} else {
+				if err := stream.publish(ctx, wire); err != nil {
+					return err
+				}
+				result.Messages++
+				result.Lines += len(batch)
+				if IsDebugMode() {
+					fmt.Fprintf(statusOut(), "  sent %d line(s), %d bytes via %s\n", len(batch), len(wire), stream.node().Address)
+				}
+			}
+			batch = nil
+			return save()
+		}
+
+		ticker := time.NewTicker(tailInterval)
+		defer ticker.Stop()
+	loop:
+		for {
+			lines, readErr := follower.Read()
+			for _, l := range lines {
+				pos, dirty = l.Pos, true
+				if l.Text == "" || (match != nil && !match.MatchString(l.Text)) {
+					if l.Text != "" {
+						result.Filtered++
+					}
+					continue
+				}
+				if len(batch) == 0 {
+					batchStart = time.Now()
+				}
+				batch = append(batch, l.Text)
+				if len(batch) >= tailBatch {
+					if err = flush(ctx); err != nil {
+						break loop
+					}
+				}
+			}
+			if readErr != nil {
+				err = fmt.Errorf("failed to read %s: %v", tailPath, readErr)
+				break
+			}
+			if len(batch) > 0 && time.Since(batchStart) >= tailBatchWait {
+				if err = flush(ctx); err != nil {
+					break
+				}
+			}
+			// skipped lines move the offset too
+			if dirty && len(batch) == 0 {
+				if err = save(); err != nil {
+					break
+				}
+			}
+			select {
+			case <-ctx.Done():
+				break loop
+			case <-ticker.C:
+			}
+		}
+
+		if ctx.Err() != nil {
+			// send what was batched before stopping
+			fctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			err = flush(fctx)
+			cancel()
+		}
+		result.Offset = pos.Offset
+		if f := formatter.New(GetOutputFormat()); !f.IsTable() {
+			if output, ferr := f.Format(result); ferr == nil {
+				fmt.Println(output)
+			}
+		} else {
+			summary := fmt.Sprintf("Published %d message(s) with %d line(s)", result.Messages, result.Lines)
+			if result.Filtered > 0 {
+				summary += fmt.Sprintf(", %d filtered out", result.Filtered)
+			}
+			if result.Dropped > 0 {
+				summary += fmt.Sprintf(", %d dropped", result.Dropped)
+			}
+			fmt.Println(summary)
+		}
+		return err
+	},
+}
+
+func init() {
+	publishTailCmd.Flags().StringVar(&tailPath, "path", "", "File to follow")
+	publishTailCmd.Flags().IntVar(&tailBatch, "batch", 1, "Lines per message")
+	publishTailCmd.Flags().DurationVar(&tailBatchWait, "batch-wait", time.Second, "Send a partial batch once its first line is this old")
+	publishTailCmd.Flags().StringVar(&tailMatch, "match", "", "Only publish lines matching this regular expression")
+	publishTailCmd.Flags().BoolVar(&tailFromStart, "from-start", false, "Without a saved offset, start at the beginning of the file instead of the end")
+	publishTailCmd.Flags().StringVar(&tailOffsetFile, "offset-file", "", "Where to save the read offset (default: under the auth directory, per path)")
+	publishTailCmd.Flags().DurationVar(&tailInterval, "interval", 250*time.Millisecond, "How often to check the file for new lines")
+	publishTailCmd.MarkFlagRequired("path") //nolint:errcheck
+	publishCmd.AddCommand(publishTailCmd)
+}
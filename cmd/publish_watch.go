@@ -0,0 +1,240 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/getoptimum/mump2p-cli/internal/watchdir"
+	"github.com/spf13/cobra"
+)
+
+var (
+	watchDir      string
+	watchInterval time.Duration
+	watchSettle   time.Duration
+	watchState    string
+)
+
+// Watch event types.
+const (
+	WatchPublished        = "published"
+	WatchFailed           = "failed"
+	WatchAlreadyPublished = "already-published"
+)
+
+// WatchEvent reports a file handled by publish watch. With --output json
+// one is written per line.
+type WatchEvent struct {
+	Type     string    `json:"type" yaml:"type"`
+	Time     time.Time `json:"time" yaml:"time"`
+	File     string    `json:"file" yaml:"file"`
+	Path     string    `json:"path" yaml:"path"`
+	Size     int64     `json:"size" yaml:"size"`
+	WireSize int64     `json:"wire_size,omitempty" yaml:"wire_size,omitempty"`
+	SHA256   string    `json:"sha256,omitempty" yaml:"sha256,omitempty"`
+	Node     string    `json:"node,omitempty" yaml:"node,omitempty"`
+	Error    string    `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+var publishWatchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Publish each file dropped into a directory",
+	Long: `Watch a directory and publish each file in it as one message over a single
+session. A file is picked up once a writer closes it (inotify, Linux) or
+its size has not changed for --settle. Published files are moved to done/,
+and files that cannot be published to failed/ with the reason in
+NAME.error. A state file in the directory makes restarts safe: a file that
+was published but not yet moved is moved without being sent again.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if watchInterval <= 0 {
+			return withExitCode(ExitUsage, errors.New("--interval must be positive"))
+		}
+		if watchSettle < 0 {
+			return withExitCode(ExitUsage, errors.New("--settle cannot be negative"))
+		}
+		claims, clientID, accessToken, err := loadPublishCredentials()
+		if err != nil {
+			return err
+		}
+		toWire, _, err := loadWireEncoder()
+		if err != nil {
+			return err
+		}
+
+		w, err := watchdir.New(watchDir, watchSettle)
+		if err != nil {
+			return withExitCode(ExitUsage, fmt.Errorf("invalid --dir: %v", err))
+		}
+		defer w.Close()
+		statePath := watchState
+		if statePath == "" {
+			statePath = filepath.Join(watchDir, watchdir.StateFileName)
+		}
+		state, err := watchdir.LoadState(statePath)
+		if err != nil {
+			return withExitCode(ExitUsage, err)
+		}
+		if err := state.Prune(watchDir); err != nil {
+			return fmt.Errorf("failed to save state: %v", err)
+		}
+
+		stream, err := openPublishStream(claims, clientID, accessToken)
+		if err != nil {
+			return err
+		}
+		defer stream.close()
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		records := newRecordWriter(os.Stdout)
+		mode := fmt.Sprintf("polling every %s", watchInterval)
+		if w.Notifying() {
+			mode = "inotify, " + mode
+		}
+		fmt.Fprintf(statusOut(), "Watching %s for files to publish to '%s' (%s)\n", watchDir, pubTopic, mode)
+
+		var published, failed int
+		report := func(ev WatchEvent) {
+			ev.Time = time.Now()
+			if records != nil {
+				records.write(ev)
+				return
+			}
+			switch ev.Type {
+			case WatchFailed:
+				fmt.Fprintf(statusOut(), "Failed to publish %s: %s → %s\n", ev.File, ev.Error, ev.Path)
+			case WatchAlreadyPublished:
+				fmt.Printf("Moved %s, published before a restart → %s\n", ev.File, ev.Path)
+			default:
+				fmt.Printf("Published %s (%s) to %s → %s\n", ev.File, humanBytes(uint64(ev.Size)), ev.Node, ev.Path)
+			}
+		}
+		// fail moves a file that cannot be published aside; only an error
+		// moving it ends the watch
+		fail := func(ev WatchEvent, cause error) error {
+			dest, err := watchdir.MoveFailed(watchDir, ev.File, cause)
+			if err != nil {
+				return fmt.Errorf("failed to move %s to %s: %v", ev.File, watchdir.FailedDir, err)
+			}
+			failed++
+			ev.Type, ev.Path, ev.Error = WatchFailed, dest, cause.Error()
+			report(ev)
+			return nil
+		}
+		// done moves a published file and forgets it
+		done := func(ev WatchEvent) error {
+			dest, err := watchdir.MoveDone(watchDir, ev.File)
+			if err != nil {
+				return fmt.Errorf("failed to move %s to %s: %v", ev.File, watchdir.DoneDir, err)
+			}
+			if err := state.Forget(ev.File); err != nil {
+				return fmt.Errorf("failed to save state: %v", err)
+			}
+			ev.Path = dest
+			report(ev)
+			return nil
+		}
+
+		// handle publishes one file. Errors from the stream end the watch
+		// and leave the file where it is for the next run.
+		handle := func(name string) error {
+			defer w.Forget(name)
+			ev := WatchEvent{File: name}
+			path := filepath.Join(watchDir, name)
+			info, err := os.Stat(path)
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			if err != nil {
+				return fail(ev, err)
+			}
+			// the wire size is never below the file size without
+			// --compress, and a file that only fits compressed is left to
+			// --chunked rather than read into memory first
+			if err := stream.fits(info.Size()); err != nil {
+				ev.Size = info.Size()
+				return fail(ev, fmt.Errorf("%v; send it with publish --chunked", err))
+			}
+			data, err := os.ReadFile(path)
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			if err != nil {
+				return fail(ev, err)
+			}
+			sum := sha256.Sum256(data)
+			ev.Size, ev.SHA256 = int64(len(data)), hex.EncodeToString(sum[:])
+			if state.Published(name, ev.SHA256) {
+				ev.Type = WatchAlreadyPublished
+				return done(ev)
+			}
+			if len(data) == 0 {
+				return fail(ev, errors.New("file is empty"))
+			}
+			wire, err := toWire(data)
+			if err != nil {
+				return fail(ev, err)
+			}
+			if err := stream.fits(int64(len(wire))); err != nil {
+				return fail(ev, fmt.Errorf("%v; send it with publish --chunked", err))
+			}
+			if err := stream.publish(ctx, wire); err != nil {
+				return fmt.Errorf("failed to publish %s: %w", name, err)
+			}
+			if err := state.MarkPublished(name, ev.Size, ev.SHA256, time.Now()); err != nil {
+				return fmt.Errorf("failed to save state: %v", err)
+			}
+			published++
+			ev.Type, ev.WireSize, ev.Node = WatchPublished, int64(len(wire)), stream.node().Address
+			return done(ev)
+		}
+
+		ticker := time.NewTicker(watchInterval)
+		defer ticker.Stop()
+	loop:
+		for {
+			ready, scanErr := w.Ready(time.Now())
+			if scanErr != nil {
+				err = fmt.Errorf("failed to read %s: %v", watchDir, scanErr)
+				break
+			}
+			for _, name := range ready {
+				if ctx.Err() != nil {
+					break
+				}
+				if err = handle(name); err != nil {
+					break loop
+				}
+			}
+			select {
+			case <-ctx.Done():
+				break loop
+			case <-ticker.C:
+			case <-w.Wake():
+			}
+		}
+		if ctx.Err() != nil {
+			err = nil
+		}
+		fmt.Fprintf(statusOut(), "Published %d file(s), %d failed\n", published, failed)
+		return err
+	},
+}
+
+func init() {
+	publishWatchCmd.Flags().StringVar(&watchDir, "dir", "", "Directory to watch for files to publish")
+	publishWatchCmd.Flags().DurationVar(&watchInterval, "interval", 2*time.Second, "How often to scan the directory")
+	publishWatchCmd.Flags().DurationVar(&watchSettle, "settle", 2*time.Second, "Publish a file once its size has not changed for this long")
+	publishWatchCmd.Flags().StringVar(&watchState, "state", "", "State file (default: DIR/"+watchdir.StateFileName+")")
+	publishWatchCmd.MarkFlagRequired("dir") //nolint:errcheck
+	publishCmd.AddCommand(publishWatchCmd)
+}
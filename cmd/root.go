@@ -2,10 +2,14 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"time"
 
+	"github.com/getoptimum/mump2p-cli/internal/config"
+	"github.com/getoptimum/mump2p-cli/internal/formatter"
+	"github.com/getoptimum/mump2p-cli/internal/httpclient"
 	"github.com/spf13/cobra"
 )
 
@@ -15,6 +19,22 @@ var (
 	disableAuth  bool
 	clientID     string
 	outputFormat string
+	profileName  string
+
+	sessionTransport string
+	sessionAddress   string
+	sessionPlaintext bool
+
+	nodeTLS           bool
+	nodeTLSCA         string
+	nodeTLSCert       string
+	nodeTLSKey        string
+	nodeTLSServerName string
+
+	httpCA      string
+	httpTimeout time.Duration
+
+	loadedProfile *config.Profile
 )
 
 var rootCmd = &cobra.Command{
@@ -22,15 +42,22 @@ var rootCmd = &cobra.Command{
 	Short: "Direct P2P publish/subscribe on the Optimum Network",
 	Long: `mump2p connects you directly to the Optimum P2P network.
 Publish and subscribe with direct node connections for real-time, low-latency messaging.`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		httpclient.ConfigureLazily(httpOptions)
+	},
 }
 
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
 
 func init() {
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return withExitCode(ExitUsage, err)
+	})
+
 	// Add global flag for custom authentication path
 	rootCmd.PersistentFlags().StringVar(&authPath, "auth-path", os.Getenv("MUMP2P_AUTH_PATH"), "Custom path for authentication file (default: ~/.mump2p/auth.yml, env: MUMP2P_AUTH_PATH)")
 
@@ -43,6 +70,20 @@ func init() {
 	// Add global output format flag
 	rootCmd.PersistentFlags().StringVar(&outputFormat, "output", "table", "Output format (table, json, yaml)")
 
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", os.Getenv("MUMP2P_PROFILE"), "Profile from config.yml next to the auth file (env: MUMP2P_PROFILE)")
+	rootCmd.PersistentFlags().StringVar(&sessionTransport, "session-transport", "", "How sessions are requested: http or grpc (default: profile, then http)")
+	rootCmd.PersistentFlags().StringVar(&sessionAddress, "session-address", "", "host:port of the gRPC SessionService (for --session-transport grpc)")
+	rootCmd.PersistentFlags().BoolVar(&sessionPlaintext, "session-plaintext", false, "Connect to a remote gRPC SessionService without TLS (only without an access token, as with --disable-auth)")
+
+	rootCmd.PersistentFlags().BoolVar(&nodeTLS, "tls", false, "Use TLS to every node, not only nodes whose transport asks for it")
+	rootCmd.PersistentFlags().StringVar(&nodeTLSCA, "tls-ca", "", "PEM CA bundle to verify nodes with (default: system roots)")
+	rootCmd.PersistentFlags().StringVar(&nodeTLSCert, "tls-cert", "", "Client certificate for mTLS to nodes")
+	rootCmd.PersistentFlags().StringVar(&nodeTLSKey, "tls-key", "", "Client key for mTLS to nodes")
+	rootCmd.PersistentFlags().StringVar(&nodeTLSServerName, "tls-server-name", "", "Override the server name verified in node certificates")
+
+	rootCmd.PersistentFlags().StringVar(&httpCA, "http-ca", "", "PEM CA bundle to trust for HTTPS, in addition to the system roots")
+	rootCmd.PersistentFlags().DurationVar(&httpTimeout, "http-timeout", 0, "Timeout for HTTP requests to the proxy, Auth0 and GitHub (default 30s)")
+
 	// disable completion option
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
 }
@@ -76,11 +117,55 @@ func GetClientID() string {
 	return clientID
 }
 
+// GetProfile returns the selected profile from config.yml, loading it on
+// first use.
+func GetProfile() (*config.Profile, error) {
+	if loadedProfile != nil {
+		return loadedProfile, nil
+	}
+	p, err := config.LoadProfile(GetAuthDir(), profileName)
+	if err != nil {
+		return nil, err
+	}
+	loadedProfile = p
+	return p, nil
+}
+
+// httpOptions returns the --http flags, falling back to the profile, for
+// the shared HTTP client. It runs on the first HTTP request, so commands
+// that make none never read them. A profile that cannot be loaded is
+// skipped with a warning, so update and login still work with a broken
+// config.yml.
+func httpOptions() (httpclient.Options, error) {
+	profile, err := GetProfile()
+	if err != nil {
+		fmt.Fprintf(statusOut(), "Warning: ignoring config.yml for HTTP settings: %v\n", err)
+		profile = &config.Profile{}
+	}
+	timeout := httpTimeout
+	if timeout == 0 {
+		timeout = profile.HTTP.Timeout
+	}
+	return httpclient.Options{
+		CAFile:  firstNonEmpty(httpCA, profile.HTTP.CAFile),
+		Timeout: timeout,
+	}, nil
+}
+
 // GetOutputFormat returns the output format
 func GetOutputFormat() string {
 	return outputFormat
 }
 
+// statusOut is where progress notes go: stdout for table output, stderr
+// when stdout carries json or yaml.
+func statusOut() io.Writer {
+	if formatter.New(outputFormat).IsTable() {
+		return os.Stdout
+	}
+	return os.Stderr
+}
+
 func humanDuration(d time.Duration) string {
 	if d < time.Second {
 		return fmt.Sprintf("%dms", d.Milliseconds())
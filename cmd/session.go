@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/getoptimum/mump2p-cli/internal/formatter"
+	"github.com/getoptimum/mump2p-cli/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var sessionInvalidateAll bool
+
+// SessionSummary is one cached session as shown by `session list`. Tickets
+// are left out.
+type SessionSummary struct {
+	Key          string   `json:"key" yaml:"key"`
+	SessionID    string   `json:"session_id" yaml:"session_id"`
+	Status       string   `json:"status" yaml:"status"`
+	Proxy        string   `json:"proxy" yaml:"proxy"`
+	Transport    string   `json:"transport" yaml:"transport"`
+	ClientID     string   `json:"client_id" yaml:"client_id"`
+	Capabilities []string `json:"capabilities" yaml:"capabilities"`
+	Topics       []string `json:"topics" yaml:"topics"`
+	Nodes        int      `json:"nodes" yaml:"nodes"`
+	ExpiresAt    string   `json:"expires_at" yaml:"expires_at"`
+	LastUsed     string   `json:"last_used" yaml:"last_used"`
+}
+
+func summarizeSession(c *session.CachedSession) SessionSummary {
+	transport := c.Transport
+	if transport == "" {
+		transport = string(session.TransportHTTP)
+	}
+	return SessionSummary{
+		Key:          c.Key,
+		SessionID:    c.Session.SessionID,
+		Status:       c.Status(),
+		Proxy:        firstNonEmpty(c.IssuedBy, c.ProxyURL),
+		Transport:    transport,
+		ClientID:     c.ClientID,
+		Capabilities: c.Capabilities,
+		Topics:       c.Topics,
+		Nodes:        len(c.Session.Nodes),
+		ExpiresAt:    c.Session.ExpiresAt,
+		LastUsed:     c.LastUsed.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+var sessionCmd = &cobra.Command{
+	Use:   "session",
+	Short: "Manage cached sessions",
+	Long: `Sessions are cached in sessions.json next to the auth file, one per
+proxy, client, capability set, topics and hints, so alternating between
+publish and subscribe reuses both sessions.`,
+}
+
+var sessionListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List cached sessions, most recently used first",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cached, err := sessionStore().List()
+		if err != nil {
+			return fmt.Errorf("failed to read session cache: %v", err)
+		}
+		summaries := make([]SessionSummary, len(cached))
+		for i, c := range cached {
+			summaries[i] = summarizeSession(c)
+		}
+
+		f := formatter.New(GetOutputFormat())
+		if !f.IsTable() {
+			output, err := f.Format(summaries)
+			if err != nil {
+				return fmt.Errorf("failed to format output: %v", err)
+			}
+			fmt.Println(output)
+			return nil
+		}
+
+		if len(summaries) == 0 {
+			fmt.Println("No cached sessions.")
+			return nil
+		}
+		fmt.Printf("%-12s  %-8s  %-20s  %-5s  %s\n", "KEY", "STATUS", "CAPABILITIES", "NODES", "TOPICS")
+		for _, s := range summaries {
+			fmt.Printf("%-12s  %-8s  %-20s  %-5d  %s\n",
+				s.Key, s.Status, strings.Join(s.Capabilities, ","), s.Nodes, strings.Join(s.Topics, ","))
+		}
+		return nil
+	},
+}
+
+var sessionShowCmd = &cobra.Command{
+	Use:   "show <key>",
+	Short: "Show a cached session and its nodes",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := sessionStore().Find(args[0])
+		if err != nil {
+			return err
+		}
+
+		f := formatter.New(GetOutputFormat())
+		if !f.IsTable() {
+			output, err := f.Format(c)
+			if err != nil {
+				return fmt.Errorf("failed to format output: %v", err)
+			}
+			fmt.Println(output)
+			return nil
+		}
+
+		s := summarizeSession(c)
+		fmt.Printf("Key:           %s\n", s.Key)
+		fmt.Printf("Session ID:    %s\n", s.SessionID)
+		fmt.Printf("Status:        %s\n", s.Status)
+		fmt.Printf("Proxy:         %s (%s)\n", s.Proxy, s.Transport)
+		if strings.Contains(c.ProxyURL, ",") {
+			fmt.Printf("Failover:      %s\n", c.ProxyURL)
+		}
+		fmt.Printf("Client ID:     %s\n", s.ClientID)
+		fmt.Printf("Capabilities:  %s\n", strings.Join(s.Capabilities, ", "))
+		fmt.Printf("Topics:        %s\n", strings.Join(s.Topics, ", "))
+		if c.Region != "" || c.Protocol != "" {
+			fmt.Printf("Hints:         region=%s protocol=%s\n", c.Region, c.Protocol)
+		}
+		fmt.Printf("Refresh after: %s\n", c.Session.RefreshAfter)
+		fmt.Printf("Expires at:    %s\n", s.ExpiresAt)
+		fmt.Printf("Last used:     %s\n", s.LastUsed)
+		fmt.Println("Nodes:")
+		for i, n := range c.Session.Nodes {
+			fmt.Printf("  %d. %s (%s) score %.2f\n", i+1, n.Address, nodeRegion(n), n.Score)
+		}
+		return nil
+	},
+}
+
+var sessionInvalidateCmd = &cobra.Command{
+	Use:   "invalidate [key...]",
+	Short: "Remove cached sessions so the next command requests new ones",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 && !sessionInvalidateAll {
+			return errors.New("pass one or more session keys, or --all")
+		}
+
+		store := sessionStore()
+		keys := make(map[string]bool)
+		for _, a := range args {
+			c, err := store.Find(a)
+			if err != nil {
+				return err
+			}
+			keys[c.Key] = true
+		}
+
+		removed, err := store.Remove(func(c *session.CachedSession) bool {
+			return sessionInvalidateAll || keys[c.Key]
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update session cache: %v", err)
+		}
+		fmt.Printf("Removed %d session(s)\n", removed)
+		return nil
+	},
+}
+
+var sessionPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove expired sessions and sessions past their refresh time",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		removed, err := sessionStore().Prune()
+		if err != nil {
+			return fmt.Errorf("failed to update session cache: %v", err)
+		}
+		fmt.Printf("Pruned %d session(s)\n", removed)
+		return nil
+	},
+}
+
+func init() {
+	sessionInvalidateCmd.Flags().BoolVar(&sessionInvalidateAll, "all", false, "Remove every cached session")
+	sessionCmd.AddCommand(sessionListCmd, sessionShowCmd, sessionInvalidateCmd, sessionPruneCmd)
+	rootCmd.AddCommand(sessionCmd)
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -15,12 +16,13 @@ import (
 	"sync/atomic"
 	"syscall"
 	"time"
-	"unicode/utf8"
 
-	"github.com/getoptimum/mump2p-cli/internal/auth"
-	"github.com/getoptimum/mump2p-cli/internal/config"
+	"github.com/getoptimum/mump2p-cli/internal/chunk"
 	"github.com/getoptimum/mump2p-cli/internal/entities"
+	"github.com/getoptimum/mump2p-cli/internal/httpclient"
+	"github.com/getoptimum/mump2p-cli/internal/keyring"
 	"github.com/getoptimum/mump2p-cli/internal/node"
+	"github.com/getoptimum/mump2p-cli/internal/payload"
 	"github.com/getoptimum/mump2p-cli/internal/session"
 	"github.com/getoptimum/mump2p-cli/internal/webhook"
 	pb "github.com/getoptimum/mump2p-cli/proto"
@@ -36,6 +38,31 @@ var (
 	webhookTimeoutSecs int
 	subServiceURL      string
 	subExposeAmount    uint32
+	subRedundancy      int
+	subNodeStrategy    string
+	subRegion          string
+	subProtocol        string
+	subOverflow        string
+	subBufferSize      int
+	subSpillDir        string
+	subSpillMaxMB      int64
+	subPayloadEncoding string
+	webhookBinary      string
+	subProtoDescriptor string
+	subProtoType       string
+	subDecrypt         bool
+	subVerify          bool
+	subVerifyPolicy    string
+	subTrustStore      string
+	subReassemble      string
+	subReassembleWait  time.Duration
+)
+
+// Policies for messages that fail subscribe --verify.
+const (
+	verifyReject   = "reject"
+	verifyWarn     = "warn"
+	verifyAnnotate = "annotate"
 )
 
 func printDebugReceiveInfo(message []byte, receiverAddr string, topic string, messageNum int32, protocol string) {
@@ -59,25 +86,6 @@ func decodeMessage(rawMsg []byte) (decoded []byte, topic string, p2pMsg *entitie
 	return msg.Message, msg.Topic, msg
 }
 
-func isReadable(b []byte) bool {
-	for _, c := range b {
-		if c < 0x20 && c != '\n' && c != '\r' && c != '\t' {
-			return false
-		}
-	}
-	return len(b) > 0 && utf8.Valid(b)
-}
-
-func formatMessage(data []byte) string {
-	if isReadable(data) {
-		return string(data)
-	}
-	if len(data) > 256 {
-		return fmt.Sprintf("[binary %d bytes] %x...", len(data), data[:128])
-	}
-	return fmt.Sprintf("[binary %d bytes] %x", len(data), data)
-}
-
 var subscribeCmd = &cobra.Command{
 	Use:   "subscribe",
 	Short: "Subscribe to a topic and stream messages from the P2P network",
@@ -86,26 +94,17 @@ var subscribeCmd = &cobra.Command{
 		var accessToken string
 
 		if !IsAuthDisabled() {
-			authClient := auth.NewClient()
-			storage := auth.NewStorageWithPath(GetAuthPath())
-			token, err := authClient.GetValidToken(storage)
-			if err != nil {
-				return fmt.Errorf("authentication required: %v", err)
-			}
-			accessToken = token.Token
-			parser := auth.NewTokenParser()
-			claims, err := parser.ParseToken(token.Token)
+			token, claims, err := loadTokenAndClaims(GetAuthPath())
 			if err != nil {
-				return fmt.Errorf("error parsing token: %v", err)
-			}
-			if !claims.IsActive {
-				return fmt.Errorf("your account is inactive, please contact support")
+				return err
 			}
+			accessToken = token
 			clientIDToUse = claims.ClientID
+			limitSubscribedMessages(claims)
 		} else {
 			clientIDToUse = GetClientID()
 			if clientIDToUse == "" {
-				return fmt.Errorf("--client-id is required when using --disable-auth")
+				return withExitCode(ExitUsage, fmt.Errorf("--client-id is required when using --disable-auth"))
 			}
 		}
 
@@ -123,7 +122,7 @@ var subscribeCmd = &cobra.Command{
 				return fmt.Errorf("failed to open persistence file: %v", err)
 			}
 			defer persistFile.Close()
-			fmt.Printf("Persisting data to: %s\n", persistPath)
+			fmt.Fprintf(statusOut(), "Persisting data to: %s\n", persistPath)
 		}
 
 		var webhookFormatter *webhook.TemplateFormatter
@@ -137,91 +136,149 @@ var subscribeCmd = &cobra.Command{
 			}
 			webhookFormatter = formatter
 			if webhookSchema == "" {
-				fmt.Printf("Forwarding messages to webhook (raw format): %s\n", webhookURL)
+				fmt.Fprintf(statusOut(), "Forwarding messages to webhook (raw format): %s\n", webhookURL)
 			} else {
-				fmt.Printf("Forwarding messages to webhook (custom schema): %s\n", webhookURL)
+				fmt.Fprintf(statusOut(), "Forwarding messages to webhook (custom schema): %s\n", webhookURL)
 			}
 		}
 
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-		proxyURL := config.LoadConfig().ServiceUrl
-		if subServiceURL != "" {
-			proxyURL = subServiceURL
+		// With --output json or yaml, stdout carries only records and
+		// progress notes move to stderr.
+		records := newRecordWriter(os.Stdout)
+
+		if subRedundancy < 1 {
+			return withExitCode(ExitUsage, fmt.Errorf("--redundancy must be at least 1"))
+		}
+		overflow, err := node.ParseOverflow(subOverflow)
+		if err != nil {
+			return withExitCode(ExitUsage, err)
+		}
+		encoding, err := payload.ParseEncoding(subPayloadEncoding)
+		if err != nil {
+			return withExitCode(ExitUsage, err)
+		}
+		if webhookBinary != webhook.BinaryRaw && webhookBinary != webhook.BinaryBase64 {
+			return withExitCode(ExitUsage, fmt.Errorf("--webhook-binary must be raw or base64"))
+		}
+		protoCodec, err := loadProtoCodec(subProtoDescriptor, subProtoType)
+		if err != nil {
+			return err
+		}
+		var keys *keyring.Keyring
+		if subDecrypt {
+			if keys, err = loadKeyring(); err != nil {
+				return err
+			}
+			if len(keys.Keys) == 0 {
+				return withExitCode(ExitUsage, errors.New("--decrypt needs keys in the keyring (see 'mump2p keys')"))
+			}
+		}
+		var trust *keyring.Keyring
+		if subVerify {
+			switch subVerifyPolicy {
+			case verifyReject, verifyWarn, verifyAnnotate:
+			default:
+				return withExitCode(ExitUsage, fmt.Errorf("--verify-policy must be reject, warn or annotate"))
+			}
+			if trust, err = loadTrustStore(subTrustStore); err != nil {
+				return err
+			}
+		}
+		var assembler *chunk.Assembler
+		if subReassemble != "" {
+			if assembler, err = chunk.NewAssembler(subReassemble, subReassembleWait); err != nil {
+				return withExitCode(ExitUsage, fmt.Errorf("invalid --reassemble directory: %v", err))
+			}
+		}
+
+		sessReq, err := newSessionRequest(subServiceURL, clientIDToUse, accessToken, []string{subTopic}, []string{"subscribe"}, max(subExposeAmount, uint32(subRedundancy)), subRegion, subProtocol)
+		if err != nil {
+			return err
 		}
 
 		sessionStart := time.Now()
-		sess, reused, err := session.GetOrCreateSession(
-			proxyURL,
-			clientIDToUse,
-			accessToken,
-			[]string{subTopic},
-			[]string{"subscribe"},
-			subExposeAmount,
-		)
+		sess, reused, err := sessionStore().GetOrCreate(sessReq)
 		if err != nil {
-			return fmt.Errorf("session creation failed: %v", err)
+			return fmt.Errorf("session creation failed: %w", err)
 		}
 		sessionDur := time.Since(sessionStart)
 
 		if IsDebugMode() {
 			if reused {
-				fmt.Printf("Reusing session %s | %d node(s) available\n", sess.SessionID, len(sess.Nodes))
+				fmt.Fprintf(statusOut(), "Reusing session %s | %d node(s) available\n", sess.SessionID, len(sess.Nodes))
 			} else {
-				fmt.Printf("New session %s from %s (%s) | %d node(s) available\n",
-					sess.SessionID, proxyURL, humanDuration(sessionDur), len(sess.Nodes))
+				fmt.Fprintf(statusOut(), "New session %s from %s (%s) | %d node(s) available\n",
+					sess.SessionID, sess.Proxy, humanDuration(sessionDur), len(sess.Nodes))
 			}
 		}
 
-		var (
-			nodeClient    *node.Client
-			connectedNode session.Node
-			msgChan       <-chan *pb.Response
-		)
+		if err := orderSessionNodes(sess, subNodeStrategy); err != nil {
+			return err
+		}
+
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
+		var (
+			connected []session.Node
+			streams   []<-chan *pb.Response
+		)
 		connectStart := time.Now()
 		for i, n := range sess.Nodes {
+			if len(connected) == subRedundancy {
+				break
+			}
 			if IsDebugMode() {
-				fmt.Printf("  Trying node %d/%d: %s (%s, score: %.2f)...\n",
+				fmt.Fprintf(statusOut(), "  Trying node %d/%d: %s (%s, score: %.2f)...\n",
 					i+1, len(sess.Nodes), n.Address, n.Region, n.Score)
 			}
 
-			nc, connErr := node.NewClient(n.Address)
+			dialOpts, err := nodeDialOptions(n)
+			if err != nil {
+				return err
+			}
+			nc, connErr := node.NewClient(n.Address, dialOpts...)
 			if connErr != nil {
-				fmt.Printf("  Node %s unreachable, falling back...\n", n.Address)
+				fmt.Fprintf(statusOut(), "  Node %s unreachable, falling back...\n", n.Address)
+				records.write(SubscribeFailover{Type: EventFailover, Time: time.Now(), Node: subscribeNode(n), Error: connErr.Error()})
 				continue
 			}
 
-			ch, subErr := nc.Subscribe(ctx, n.Ticket, subTopic, 100)
+			ch, subErr := nc.Subscribe(ctx, n.Ticket, subTopic, 100, func(err error) {
+				fmt.Fprintf(statusOut(), "  Stream from %s ended: %v\n", n.Address, err)
+				records.write(SubscribeStreamError{Type: EventStreamError, Time: time.Now(), Node: subscribeNode(n), Error: err.Error()})
+			})
 			if subErr != nil {
-				fmt.Printf("  Node %s subscribe failed, falling back...\n", n.Address)
+				fmt.Fprintf(statusOut(), "  Node %s subscribe failed, falling back...\n", n.Address)
+				records.write(SubscribeFailover{Type: EventFailover, Time: time.Now(), Node: subscribeNode(n), Error: subErr.Error()})
 				nc.Close()
 				continue
 			}
+			defer nc.Close()
 
-			nodeClient = nc
-			connectedNode = n
-			msgChan = ch
-			break
+			connected = append(connected, n)
+			streams = append(streams, ch)
 		}
 
-		if nodeClient == nil {
-			return fmt.Errorf("all %d node(s) failed to connect", len(sess.Nodes))
+		if len(connected) == 0 {
+			return withExitCode(ExitNodesFailed, fmt.Errorf("all %d node(s) failed to connect", len(sess.Nodes)))
 		}
-		defer nodeClient.Close()
 		connectDur := time.Since(connectStart)
 
-		region := connectedNode.Region
-		if region == "" {
-			region = "unknown"
+		receiverAddrs := make([]string, len(connected))
+		for i, n := range connected {
+			receiverAddrs[i] = extractIPFromURL(n.Address)
+			if receiverAddrs[i] == "" {
+				receiverAddrs[i] = n.Address
+			}
 		}
 
 		var backupNodes []session.Node
-		for _, n := range sess.Nodes {
-			if n.Address != connectedNode.Address {
+		for _, n := range sess.Nodes[len(connected):] {
+			if !containsNode(connected, n) {
 				backupNodes = append(backupNodes, n)
 			}
 		}
@@ -233,24 +290,58 @@ var subscribeCmd = &cobra.Command{
 			backupSuffix = fmt.Sprintf(" — %d backup nodes ready", len(backupNodes))
 		}
 
-		fmt.Printf("Subscribed to '%s' on %s (%s) in %s%s\n",
-			subTopic, connectedNode.Address, region, humanDuration(connectDur), backupSuffix)
-
-		for _, bn := range backupNodes {
-			r := bn.Region
-			if r == "" {
-				r = "unknown"
+		if len(connected) == 1 {
+			fmt.Fprintf(statusOut(), "Subscribed to '%s' on %s (%s) in %s%s\n",
+				subTopic, connected[0].Address, nodeRegion(connected[0]), humanDuration(connectDur), backupSuffix)
+		} else {
+			fmt.Fprintf(statusOut(), "Subscribed to '%s' on %d nodes in %s%s\n",
+				subTopic, len(connected), humanDuration(connectDur), backupSuffix)
+			for _, n := range connected {
+				fmt.Fprintf(statusOut(), "  stream: %s (%s)\n", n.Address, nodeRegion(n))
 			}
-			fmt.Printf("  backup: %s (%s)\n", bn.Address, r)
+		}
+		if len(connected) < subRedundancy {
+			fmt.Fprintf(statusOut(), "  only %d of %d redundant streams connected\n", len(connected), subRedundancy)
 		}
 
-		receiverAddr := extractIPFromURL(connectedNode.Address)
-		if receiverAddr == "" {
-			receiverAddr = connectedNode.Address
+		for _, bn := range backupNodes {
+			fmt.Fprintf(statusOut(), "  backup: %s (%s)\n", bn.Address, nodeRegion(bn))
+		}
+		records.write(SubscribeConnected{
+			Type:      EventConnected,
+			Time:      time.Now(),
+			Topic:     subTopic,
+			SessionID: sess.SessionID,
+			Nodes:     subscribeNodes(connected),
+			Backups:   subscribeNodes(backupNodes),
+		})
+
+		// A single stream is passed through as is; redundant streams are
+		// deduplicated so each message is handled once.
+		dedupSize := 0
+		if len(streams) > 1 {
+			dedupSize = node.DefaultDedupSize
+		}
+		merger := node.NewMerger(len(streams), dedupSize)
+		// The queue keeps the streams flowing while stdout, persistence or the
+		// webhook fall behind, applying the --overflow policy when it fills.
+		queue := node.NewQueue(node.QueueOptions{
+			Overflow: overflow,
+			Size:     subBufferSize,
+			SpillDir: subSpillDir,
+			SpillMax: subSpillMaxMB << 20,
+		})
+		msgChan, err := queue.Run(ctx, merger.Run(ctx, streams, 100))
+		if err != nil {
+			return err
+		}
+		if IsDebugMode() {
+			go reportBackpressure(ctx, queue)
 		}
 
 		type webhookMsg struct {
 			data []byte
+			sig  webhook.Signature
 		}
 
 		var wq chan webhookMsg
@@ -258,48 +349,104 @@ var subscribeCmd = &cobra.Command{
 			wq = make(chan webhookMsg, webhookQueueSize)
 			go func() {
 				for msg := range wq {
-					go func(payload []byte) {
+					go func(msg webhookMsg) {
 						wctx, wcancel := context.WithTimeout(context.Background(), time.Duration(webhookTimeoutSecs)*time.Second)
 						defer wcancel()
 
-						formattedPayload, fmtErr := webhookFormatter.FormatMessage(payload, subTopic, clientIDToUse, "grpc-msg")
+						var (
+							formattedPayload []byte
+							contentType      string
+							fmtErr           error
+						)
+						if payload.IsText(msg.data) {
+							formattedPayload, fmtErr = webhookFormatter.FormatMessage(msg.data, subTopic, clientIDToUse, "grpc-msg", msg.sig)
+							if webhookSchema != "" {
+								contentType = "application/json"
+							}
+						} else {
+							formattedPayload, contentType, fmtErr = webhookFormatter.FormatBinary(msg.data, webhookBinary, subTopic, clientIDToUse, "grpc-msg", msg.sig)
+						}
 						if fmtErr != nil {
-							fmt.Printf("Failed to format webhook payload: %v\n", fmtErr)
+							fmt.Fprintf(statusOut(), "Failed to format webhook payload: %v\n", fmtErr)
 							return
 						}
 
 						req, reqErr := http.NewRequestWithContext(wctx, "POST", webhookURL, bytes.NewBuffer(formattedPayload))
 						if reqErr != nil {
-							fmt.Printf("Failed to create webhook request: %v\n", reqErr)
+							fmt.Fprintf(statusOut(), "Failed to create webhook request: %v\n", reqErr)
 							return
 						}
-						if webhookSchema != "" {
-							req.Header.Set("Content-Type", "application/json")
+						if contentType != "" {
+							req.Header.Set("Content-Type", contentType)
 						}
-						resp, doErr := http.DefaultClient.Do(req)
+						resp, doErr := httpclient.Client(-1).Do(req)
 						if doErr != nil {
-							fmt.Printf("Webhook request error: %v\n", doErr)
+							fmt.Fprintf(statusOut(), "Webhook request error: %v\n", doErr)
 							return
 						}
 						defer resp.Body.Close()
 						if resp.StatusCode >= 400 {
-							fmt.Printf("Webhook responded with status code: %d\n", resp.StatusCode)
+							fmt.Fprintf(statusOut(), "Webhook responded with status code: %d\n", resp.StatusCode)
 						}
-					}(msg.data)
+					}(msg)
 				}
 			}()
 		}
 
 		doneChan := make(chan struct{})
-		var messageCount int32
+		var messageCount, undecryptable, rejected, files, failedFiles int32
+
+		reportFile := func(r chunk.Result) {
+			if r.Err != nil {
+				atomic.AddInt32(&failedFiles, 1)
+			} else {
+				atomic.AddInt32(&files, 1)
+			}
+			if records != nil {
+				records.write(newSubscribeFile(r))
+				return
+			}
+			if r.Err != nil {
+				fmt.Fprintf(statusOut(), "Transfer of %s failed: %v\n", r.Name(), r.Err)
+				return
+			}
+			fmt.Printf("[%s] received %s (%s in %d chunks) → %s\n",
+				subTopic, r.Name(), humanBytes(uint64(r.Manifest.Size)), r.Manifest.Total, r.Path)
+		}
+		if assembler != nil {
+			go func() {
+				ticker := time.NewTicker(time.Second)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case now := <-ticker.C:
+						for _, r := range assembler.Expire(now) {
+							reportFile(r)
+						}
+					}
+				}
+			}()
+		}
 		subscribeStart := time.Now()
 
 		go func() {
 			defer close(doneChan)
-			for resp := range msgChan {
-				if !IsDebugMode() {
-					switch resp.GetCommand() {
-					case pb.ResponseType_MessageTraceMumP2P, pb.ResponseType_MessageTraceGossipSub:
+			for arrival := range msgChan {
+				resp := arrival.Response
+				via := connected[arrival.Stream]
+				isTrace := false
+				switch resp.GetCommand() {
+				case pb.ResponseType_MessageTraceMumP2P, pb.ResponseType_MessageTraceGossipSub:
+					isTrace = true
+					if !IsDebugMode() {
+						continue
+					}
+					if records != nil {
+						if rec, ok := newSubscribeTrace(arrival, via); ok {
+							records.write(rec)
+						}
 						continue
 					}
 				}
@@ -310,14 +457,94 @@ var subscribeCmd = &cobra.Command{
 					continue
 				}
 
-				if IsDebugMode() {
+				// The signature covers the payload as published, so it is
+				// checked before anything is decoded. Without --verify the
+				// envelope is still removed so the payload can be decoded,
+				// and the message is marked unverified. Traces are not
+				// signed.
+				var verification *keyring.Verification
+				if !isTrace && (trust != nil || keyring.IsSigned(decodedMsg)) {
+					inner, v := trust.Verify(decodedMsg, subTopic)
+					if trust != nil && !v.OK() {
+						switch subVerifyPolicy {
+						case verifyReject:
+							atomic.AddInt32(&rejected, 1)
+							fmt.Fprintf(statusOut(), "Rejected message: %s\n", v)
+							continue
+						case verifyWarn:
+							fmt.Fprintf(statusOut(), "Warning: message not verified: %s\n", v)
+						}
+					}
+					decodedMsg, verification = inner, &v
+				}
+
+				// Messages that cannot be decrypted are reported and skipped;
+				// unencrypted messages pass through.
+				encrypted := false
+				if keys != nil && keyring.IsSealed(decodedMsg) {
+					plain, err := keys.Open(decodedMsg, subTopic)
+					if err != nil {
+						atomic.AddInt32(&undecryptable, 1)
+						fmt.Fprintf(statusOut(), "Skipping message that could not be decrypted: %v\n", err)
+						continue
+					}
+					decodedMsg, encrypted = plain, true
+				}
+
+				// Compressed payloads are detected by their header.
+				var codec string
+				if unwrapped, c, err := payload.Unwrap(decodedMsg); err != nil {
+					fmt.Fprintf(statusOut(), "Failed to decode payload: %v\n", err)
+				} else if c != nil {
+					decodedMsg, codec = unwrapped, c.Name()
+				}
+
+				// Chunks of a file are written to the --reassemble directory
+				// instead of being delivered as messages.
+				if assembler != nil && chunk.Is(decodedMsg) {
+					m, err := chunk.Parse(decodedMsg)
+					if err != nil {
+						fmt.Fprintf(statusOut(), "Ignoring chunk: %v\n", err)
+						continue
+					}
+					if IsDebugMode() && m.Manifest != nil {
+						fmt.Fprintf(statusOut(), "Receiving %s (%s in %d chunks) [transfer: %s]\n",
+							m.Manifest.Name, humanBytes(uint64(m.Manifest.Size)), m.Manifest.Total, m.Manifest.ID[:8])
+					}
+					if r := assembler.Add(m, time.Now()); r != nil {
+						reportFile(*r)
+					}
+					continue
+				}
+
+				// Decoded messages are JSON text from here on; payloads that
+				// do not decode are passed through as received.
+				if protoCodec != nil && len(decodedMsg) > 0 {
+					if js, err := protoCodec.ToJSON(decodedMsg); err != nil {
+						fmt.Fprintf(statusOut(), "Failed to decode payload: %v\n", err)
+					} else {
+						decodedMsg = js
+					}
+				}
+
+				if records != nil {
+					atomic.AddInt32(&messageCount, 1)
+					rec := newSubscribeMessage(arrival, subTopic, decodedMsg, p2pMsg, via, encoding)
+					rec.Codec = codec
+					rec.Encrypted = encrypted
+					rec.Signature = verification
+					records.write(rec)
+				} else if IsDebugMode() {
 					n := atomic.AddInt32(&messageCount, 1)
-					printDebugReceiveInfo(decodedMsg, receiverAddr, subTopic, n, "gRPC-direct")
+					printDebugReceiveInfo(decodedMsg, receiverAddrs[arrival.Stream], subTopic, n, "gRPC-direct")
 					if p2pMsg != nil {
 						if p2pMsg.SourceNodeID != "" {
 							fmt.Printf("  from: %s\n", p2pMsg.SourceNodeID)
 						}
-						fmt.Printf("  via:  %s (%s)\n", connectedNode.Address, region)
+						fmt.Printf("  via:  %s (%s)\n", via.Address, nodeRegion(via))
+						if verification != nil {
+							fmt.Printf("  sig:  %s\n", verification)
+						}
 						if p2pMsg.MessageID != "" {
 							id := p2pMsg.MessageID
 							if len(id) > 12 {
@@ -327,7 +554,7 @@ var subscribeCmd = &cobra.Command{
 						}
 					}
 				} else {
-					if !isReadable(decodedMsg) {
+					if len(decodedMsg) == 0 {
 						continue
 					}
 					atomic.AddInt32(&messageCount, 1)
@@ -336,29 +563,47 @@ var subscribeCmd = &cobra.Command{
 					if msgTopic != "" {
 						displayTopic = msgTopic
 					}
-					fmt.Printf("[%s] %s\n", displayTopic, string(decodedMsg))
+					switch {
+					case encoding == payload.Raw:
+						os.Stdout.Write(decodedMsg) //nolint:errcheck
+					case verification != nil && subVerifyPolicy == verifyAnnotate:
+						fmt.Printf("[%s] %s (%s)\n", displayTopic, payload.Display(decodedMsg, encoding), verification)
+					default:
+						fmt.Printf("[%s] %s\n", displayTopic, payload.Display(decodedMsg, encoding))
+					}
 				}
 
-				// Unreadable payloads are filtered from stdout in non-debug mode above; skip
-				// persistence and webhook for them in debug mode too (same as filtered topics).
-				if !isReadable(decodedMsg) {
+				// Traces are only shown with --debug; they are not persisted
+				// or forwarded, and neither are empty payloads.
+				if isTrace || len(decodedMsg) == 0 {
 					continue
 				}
 
-				msgStr := formatMessage(decodedMsg)
-
 				if persistFile != nil {
-					timestamp := time.Now().Format(time.RFC3339)
-					if _, writeErr := fmt.Fprintf(persistFile, "[%s] %s\n", timestamp, msgStr); writeErr != nil {
-						fmt.Printf("Error writing to persistence file: %v\n", writeErr)
+					var writeErr error
+					if encoding == payload.Raw {
+						writeErr = payload.WriteFrame(persistFile, decodedMsg)
+					} else {
+						prefix := "[" + time.Now().Format(time.RFC3339) + "]"
+						if verification != nil {
+							prefix += " [" + verification.String() + "]"
+						}
+						_, writeErr = fmt.Fprintf(persistFile, "%s %s\n", prefix, payload.EncodeLine(decodedMsg, encoding))
+					}
+					if writeErr != nil {
+						fmt.Fprintf(statusOut(), "Error writing to persistence file: %v\n", writeErr)
 					}
 				}
 
 				if wq != nil {
+					msg := webhookMsg{data: decodedMsg}
+					if verification != nil {
+						msg.sig = webhook.Signature{Status: string(verification.Status), Signer: verification.Signer}
+					}
 					select {
-					case wq <- webhookMsg{data: decodedMsg}:
+					case wq <- msg:
 					default:
-						fmt.Println("Webhook queue full, message dropped")
+						fmt.Fprintln(statusOut(), "Webhook queue full, message dropped")
 					}
 				}
 			}
@@ -371,7 +616,37 @@ var subscribeCmd = &cobra.Command{
 		}
 
 		elapsed := time.Since(subscribeStart)
+		if assembler != nil {
+			for _, r := range assembler.Close() {
+				reportFile(r)
+			}
+		}
 		count := atomic.LoadInt32(&messageCount)
+		st := queue.Stats()
+
+		if records != nil {
+			rec := SubscribeDisconnected{
+				Type:          EventDisconnected,
+				Time:          time.Now(),
+				Messages:      int(count),
+				Duration:      elapsed.Seconds(),
+				Dropped:       st.Dropped,
+				Spilled:       st.Spilled,
+				Undecryptable: int(atomic.LoadInt32(&undecryptable)),
+				Rejected:      int(atomic.LoadInt32(&rejected)),
+				Files:         int(atomic.LoadInt32(&files)),
+				FailedFiles:   int(atomic.LoadInt32(&failedFiles)),
+			}
+			if len(connected) > 1 {
+				rec.Duplicates = merger.Duplicates()
+				rec.FirstArrivals = make(map[string]int, len(connected))
+				for i, w := range merger.Wins() {
+					rec.FirstArrivals[connected[i].Address] = w
+				}
+			}
+			records.write(rec)
+			return nil
+		}
 
 		throughput := ""
 		if elapsed > time.Second && count > 0 {
@@ -385,10 +660,82 @@ var subscribeCmd = &cobra.Command{
 			fmt.Printf("\nDisconnected — %d messages in %s%s\n", count, humanDuration(elapsed), throughput)
 		}
 
+		if st.Dropped > 0 || st.Spilled > 0 {
+			fmt.Printf("Backpressure (%s): %d dropped, %d spilled to disk\n", overflow, st.Dropped, st.Spilled)
+		}
+		if n, failed := atomic.LoadInt32(&files), atomic.LoadInt32(&failedFiles); n > 0 || failed > 0 {
+			fmt.Printf("Reassembled %d file(s), %d failed\n", n, failed)
+		}
+		if n := atomic.LoadInt32(&rejected); n > 0 {
+			fmt.Printf("Rejected %d message(s) that failed verification\n", n)
+		}
+		if n := atomic.LoadInt32(&undecryptable); n > 0 {
+			fmt.Printf("Skipped %d message(s) that could not be decrypted\n", n)
+		}
+		if len(connected) > 1 {
+			printFirstArrivals(connected, merger)
+		}
+
 		return nil
 	},
 }
 
+func nodeRegion(n session.Node) string {
+	if n.Region == "" {
+		return "unknown"
+	}
+	return n.Region
+}
+
+func containsNode(nodes []session.Node, n session.Node) bool {
+	for _, c := range nodes {
+		if c.Address == n.Address {
+			return true
+		}
+	}
+	return false
+}
+
+// printFirstArrivals reports which redundant stream delivered each message
+// first.
+func printFirstArrivals(connected []session.Node, merger *node.Merger) {
+	wins := merger.Wins()
+	total := 0
+	for _, w := range wins {
+		total += w
+	}
+	fmt.Printf("First arrivals (%d duplicate(s) suppressed):\n", merger.Duplicates())
+	for i, n := range connected {
+		pct := 0.0
+		if total > 0 {
+			pct = float64(wins[i]) * 100 / float64(total)
+		}
+		fmt.Printf("  %s (%s): %d (%.0f%%)\n", n.Address, nodeRegion(n), wins[i], pct)
+	}
+}
+
+// reportBackpressure prints the queue counters in debug mode whenever they
+// change.
+func reportBackpressure(ctx context.Context, queue *node.Queue) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	var last node.QueueStats
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		st := queue.Stats()
+		if st == last {
+			continue
+		}
+		last = st
+		fmt.Fprintf(statusOut(), "Backpressure: %d queued, %d dropped, %d spilled (%s on disk)\n",
+			st.Queued, st.Dropped, st.Spilled, humanBytes(uint64(st.SpillBytes)))
+	}
+}
+
 func init() {
 	subscribeCmd.Flags().StringVar(&subTopic, "topic", "", "Topic to subscribe to")
 	subscribeCmd.MarkFlagRequired("topic") //nolint:errcheck
@@ -397,7 +744,25 @@ func init() {
 	subscribeCmd.Flags().StringVar(&webhookSchema, "webhook-schema", "", "JSON template for webhook payload")
 	subscribeCmd.Flags().IntVar(&webhookQueueSize, "webhook-queue-size", 100, "Max number of webhook messages to queue before dropping")
 	subscribeCmd.Flags().IntVar(&webhookTimeoutSecs, "webhook-timeout", 3, "Timeout in seconds for each webhook POST request")
-	subscribeCmd.Flags().StringVar(&subServiceURL, "service-url", "", "Override the default proxy URL")
+	subscribeCmd.Flags().StringVar(&subServiceURL, "service-url", "", "Override the default proxy URL (comma-separated URLs fail over in turn)")
 	subscribeCmd.Flags().Uint32Var(&subExposeAmount, "expose-amount", 3, "Number of nodes to request from proxy (enables failover if >1)")
+	subscribeCmd.Flags().IntVar(&subRedundancy, "redundancy", 1, "Subscribe through this many nodes at once and deliver each message from the first to arrive")
+	subscribeCmd.Flags().StringVar(&subNodeStrategy, "node-strategy", "proxy", "Node selection: proxy (proxy order), rtt (measured latency and score) or region (nearest region first)")
+	subscribeCmd.Flags().StringVar(&subRegion, "region", "", "Ask the proxy for nodes in this region")
+	subscribeCmd.Flags().StringVar(&subProtocol, "protocol", "", "Ask the proxy for nodes running this protocol: mump2p or gossipsub")
+	subscribeCmd.Flags().StringVar(&subOverflow, "overflow", "block", "When output falls behind: block, drop-newest, drop-oldest or spill (to disk)")
+	subscribeCmd.Flags().IntVar(&subBufferSize, "buffer-size", node.DefaultQueueSize, "Number of messages to hold in memory before --overflow applies")
+	subscribeCmd.Flags().StringVar(&subSpillDir, "spill-dir", "", "Directory for the spill file (default: system temp dir)")
+	subscribeCmd.Flags().Int64Var(&subSpillMaxMB, "spill-max-mb", node.DefaultSpillMax>>20, "Maximum size of the spill file in MB; newer messages are dropped beyond it")
+	subscribeCmd.Flags().StringVar(&subPayloadEncoding, "payload-encoding", "auto", "How to show and persist payloads: auto (text, or binary summary), text, hex, base64 or raw (bytes as is)")
+	subscribeCmd.Flags().StringVar(&webhookBinary, "webhook-binary", webhook.BinaryRaw, "How to send binary payloads to a webhook without --webhook-schema: raw (application/octet-stream) or base64")
+	subscribeCmd.Flags().StringVar(&subProtoDescriptor, "proto-descriptor", "", "FileDescriptorSet (protoc --include_imports --descriptor_set_out) for decoding payloads to JSON")
+	subscribeCmd.Flags().StringVar(&subProtoType, "proto-type", "", "Full name of the payload message type in --proto-descriptor, e.g. pkg.Msg")
+	subscribeCmd.Flags().BoolVar(&subDecrypt, "decrypt", false, "Decrypt end-to-end encrypted messages with keys from the keyring; messages that cannot be decrypted are skipped")
+	subscribeCmd.Flags().BoolVar(&subVerify, "verify", false, "Check message signatures against the ed25519 keys in the trust store")
+	subscribeCmd.Flags().StringVar(&subVerifyPolicy, "verify-policy", verifyReject, "With --verify, what to do with unsigned or untrusted messages: reject (skip), warn or annotate")
+	subscribeCmd.Flags().StringVar(&subTrustStore, "trust-store", "", "Keyring file whose ed25519 keys are trusted publishers (default: the local keyring)")
+	subscribeCmd.Flags().StringVar(&subReassemble, "reassemble", "", "Rebuild files sent with publish --chunked into this directory")
+	subscribeCmd.Flags().DurationVar(&subReassembleWait, "reassemble-timeout", 2*time.Minute, "Give up on a chunked transfer after receiving nothing for this long")
 	rootCmd.AddCommand(subscribeCmd)
 }
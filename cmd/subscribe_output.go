@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getoptimum/mump2p-cli/internal/chunk"
+	"github.com/getoptimum/mump2p-cli/internal/entities"
+	"github.com/getoptimum/mump2p-cli/internal/keyring"
+	"github.com/getoptimum/mump2p-cli/internal/node"
+	"github.com/getoptimum/mump2p-cli/internal/payload"
+	"github.com/getoptimum/mump2p-cli/internal/session"
+	pb "github.com/getoptimum/mump2p-cli/proto"
+	"gopkg.in/yaml.v2"
+)
+
+// Record types written by `subscribe --output json`, one object per line.
+const (
+	EventConnected    = "connected"
+	EventFailover     = "failover"
+	EventStreamError  = "stream_error"
+	EventMessage      = "message"
+	EventTrace        = "trace"
+	EventFile         = "file"
+	EventDisconnected = "disconnected"
+)
+
+// SubscribeNode identifies a node in subscribe records.
+type SubscribeNode struct {
+	Address string `json:"address" yaml:"address"`
+	Region  string `json:"region" yaml:"region"`
+}
+
+// SubscribeConnected is emitted once the streams are open.
+type SubscribeConnected struct {
+	Type      string          `json:"type" yaml:"type"`
+	Time      time.Time       `json:"time" yaml:"time"`
+	Topic     string          `json:"topic" yaml:"topic"`
+	SessionID string          `json:"session_id" yaml:"session_id"`
+	Nodes     []SubscribeNode `json:"nodes" yaml:"nodes"`
+	Backups   []SubscribeNode `json:"backups,omitempty" yaml:"backups,omitempty"`
+}
+
+// SubscribeFailover is emitted when a node cannot be used and the next one
+// is tried.
+type SubscribeFailover struct {
+	Type  string        `json:"type" yaml:"type"`
+	Time  time.Time     `json:"time" yaml:"time"`
+	Node  SubscribeNode `json:"node" yaml:"node"`
+	Error string        `json:"error" yaml:"error"`
+}
+
+// SubscribeStreamError is emitted when a connected node's stream ends with
+// an error.
+type SubscribeStreamError struct {
+	Type  string        `json:"type" yaml:"type"`
+	Time  time.Time     `json:"time" yaml:"time"`
+	Node  SubscribeNode `json:"node" yaml:"node"`
+	Error string        `json:"error" yaml:"error"`
+}
+
+// SubscribeMessage is one received message. Payload is the message as text
+// when Encoding is "utf8", otherwise base64.
+type SubscribeMessage struct {
+	Type          string                `json:"type" yaml:"type"`
+	Topic         string                `json:"topic" yaml:"topic"`
+	MessageID     string                `json:"message_id,omitempty" yaml:"message_id,omitempty"`
+	SourceNode    string                `json:"source_node,omitempty" yaml:"source_node,omitempty"`
+	ReceivingNode string                `json:"receiving_node" yaml:"receiving_node"`
+	ReceivedAt    time.Time             `json:"received_at" yaml:"received_at"`
+	Size          int                   `json:"size" yaml:"size"`
+	Codec         string                `json:"codec,omitempty" yaml:"codec,omitempty"`
+	Encrypted     bool                  `json:"encrypted,omitempty" yaml:"encrypted,omitempty"`
+	Signature     *keyring.Verification `json:"signature,omitempty" yaml:"signature,omitempty"`
+	Encoding      string                `json:"encoding" yaml:"encoding"`
+	Payload       string                `json:"payload" yaml:"payload"`
+}
+
+// SubscribeTrace carries a node's trace report, emitted in debug mode.
+type SubscribeTrace struct {
+	Type          string    `json:"type" yaml:"type"`
+	Protocol      string    `json:"protocol" yaml:"protocol"`
+	ReceivingNode string    `json:"receiving_node" yaml:"receiving_node"`
+	ReceivedAt    time.Time `json:"received_at" yaml:"received_at"`
+	Trace         any       `json:"trace" yaml:"trace"`
+}
+
+// SubscribeFile reports a chunked transfer that was rebuilt under
+// --reassemble, or that failed or timed out. Error is empty on success.
+type SubscribeFile struct {
+	Type       string    `json:"type" yaml:"type"`
+	Time       time.Time `json:"time" yaml:"time"`
+	TransferID string    `json:"transfer_id" yaml:"transfer_id"`
+	Name       string    `json:"name" yaml:"name"`
+	Path       string    `json:"path,omitempty" yaml:"path,omitempty"`
+	Size       int64     `json:"size,omitempty" yaml:"size,omitempty"`
+	SHA256     string    `json:"sha256,omitempty" yaml:"sha256,omitempty"`
+	Received   int       `json:"chunks_received" yaml:"chunks_received"`
+	Total      int       `json:"chunks_total,omitempty" yaml:"chunks_total,omitempty"`
+	Error      string    `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+func newSubscribeFile(r chunk.Result) SubscribeFile {
+	rec := SubscribeFile{
+		Type:       EventFile,
+		Time:       time.Now(),
+		TransferID: r.ID,
+		Name:       r.Name(),
+		Path:       r.Path,
+		Received:   r.Received,
+	}
+	if m := r.Manifest; m != nil {
+		rec.Size, rec.SHA256, rec.Total = m.Size, m.SHA256, m.Total
+	}
+	if r.Err != nil {
+		rec.Error = r.Err.Error()
+	}
+	return rec
+}
+
+// SubscribeDisconnected summarizes the subscription on exit.
+type SubscribeDisconnected struct {
+	Type          string         `json:"type" yaml:"type"`
+	Time          time.Time      `json:"time" yaml:"time"`
+	Messages      int            `json:"messages" yaml:"messages"`
+	Duration      float64        `json:"duration_seconds" yaml:"duration_seconds"`
+	Dropped       uint64         `json:"dropped" yaml:"dropped"`
+	Spilled       uint64         `json:"spilled" yaml:"spilled"`
+	Undecryptable int            `json:"undecryptable,omitempty" yaml:"undecryptable,omitempty"`
+	Rejected      int            `json:"rejected,omitempty" yaml:"rejected,omitempty"`
+	Files         int            `json:"files,omitempty" yaml:"files,omitempty"`
+	FailedFiles   int            `json:"failed_files,omitempty" yaml:"failed_files,omitempty"`
+	Duplicates    int            `json:"duplicates,omitempty" yaml:"duplicates,omitempty"`
+	FirstArrivals map[string]int `json:"first_arrivals,omitempty" yaml:"first_arrivals,omitempty"`
+}
+
+func subscribeNode(n session.Node) SubscribeNode {
+	return SubscribeNode{Address: n.Address, Region: nodeRegion(n)}
+}
+
+func subscribeNodes(nodes []session.Node) []SubscribeNode {
+	out := make([]SubscribeNode, 0, len(nodes))
+	for _, n := range nodes {
+		out = append(out, subscribeNode(n))
+	}
+	return out
+}
+
+// newSubscribeMessage builds the record for a message delivered by via.
+// With auto (or raw) encoding, payloads that are not text are base64
+// encoded.
+func newSubscribeMessage(a node.Arrival, topic string, data []byte, msg *entities.P2PMessage, via session.Node, enc payload.Encoding) SubscribeMessage {
+	rec := SubscribeMessage{
+		Type:          EventMessage,
+		Topic:         topic,
+		ReceivingNode: via.Address,
+		ReceivedAt:    a.Received,
+		Size:          len(data),
+	}
+	if msg != nil {
+		rec.MessageID = msg.MessageID
+		rec.SourceNode = msg.SourceNodeID
+	}
+	switch {
+	case enc == payload.Hex || enc == payload.Base64:
+		rec.Encoding = string(enc)
+	case enc == payload.Text || payload.IsText(data) || len(data) == 0:
+		rec.Encoding = "utf8"
+		enc = payload.Text
+	default:
+		rec.Encoding = "base64"
+		enc = payload.Base64
+	}
+	rec.Payload = payload.Encode(data, enc)
+	return rec
+}
+
+// newSubscribeTrace builds the record for a trace report; ok is false if
+// the report is not JSON.
+func newSubscribeTrace(a node.Arrival, via session.Node) (rec SubscribeTrace, ok bool) {
+	rec = SubscribeTrace{
+		Type:          EventTrace,
+		Protocol:      "mump2p",
+		ReceivingNode: via.Address,
+		ReceivedAt:    a.Received,
+	}
+	if a.Response.GetCommand() == pb.ResponseType_MessageTraceGossipSub {
+		rec.Protocol = "gossipsub"
+	}
+	// numbers are kept as written; nanosecond timestamps do not fit a float64
+	dec := json.NewDecoder(bytes.NewReader(a.Response.GetData()))
+	dec.UseNumber()
+	if err := dec.Decode(&rec.Trace); err != nil {
+		return rec, false
+	}
+	return rec, true
+}
+
+// recordWriter writes subscribe records as JSON lines, or as a stream of
+// YAML documents for --output yaml. A nil writer discards records, which is
+// the case for table output.
+type recordWriter struct {
+	mu   sync.Mutex
+	w    io.Writer
+	yaml bool
+}
+
+func newRecordWriter(w io.Writer) *recordWriter {
+	switch strings.ToLower(GetOutputFormat()) {
+	case "json":
+		return &recordWriter{w: w}
+	case "yaml", "yml":
+		return &recordWriter{w: w, yaml: true}
+	default:
+		return nil
+	}
+}
+
+func (r *recordWriter) write(v any) {
+	if r == nil {
+		return
+	}
+	var (
+		out []byte
+		err error
+	)
+	if r.yaml {
+		out, err = yaml.Marshal(v)
+		out = append([]byte("---\n"), out...)
+	} else {
+		out, err = json.Marshal(v)
+		out = append(out, '\n')
+	}
+	if err != nil {
+		fmt.Fprintf(statusOut(), "failed to encode %T: %v\n", v, err)
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.w.Write(out) //nolint:errcheck
+}
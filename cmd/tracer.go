@@ -16,7 +16,8 @@ import (
 	"time"
 
 	"github.com/getoptimum/mump2p-cli/internal/auth"
-	"github.com/getoptimum/mump2p-cli/internal/config"
+	"github.com/getoptimum/mump2p-cli/internal/httpclient"
+	"github.com/getoptimum/mump2p-cli/internal/session"
 	ui "github.com/gizak/termui/v3"
 	"github.com/gizak/termui/v3/widgets"
 	"github.com/spf13/cobra"
@@ -78,7 +79,10 @@ var tracerDashboardCmd = &cobra.Command{
 	Use:   "dashboard",
 	Short: "Open the tracer TUI dashboard",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		baseURL := resolveServiceURL(tracerServiceURL)
+		baseURL, err := resolveServiceURL(tracerServiceURL)
+		if err != nil {
+			return err
+		}
 
 		jwtToken, err := resolveJWT()
 		if err != nil {
@@ -109,7 +113,10 @@ var tracerResetCmd = &cobra.Command{
 	Use:   "reset",
 	Short: "Reset tracer statistics on the proxy",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		baseURL := resolveServiceURL(tracerServiceURL)
+		baseURL, err := resolveServiceURL(tracerServiceURL)
+		if err != nil {
+			return err
+		}
 
 		jwtToken, err := resolveJWT()
 		if err != nil {
@@ -125,7 +132,10 @@ var tracerLoadCmd = &cobra.Command{
 	Use:   "load",
 	Short: "Generate random traffic to the proxy for tracer",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		baseURL := resolveServiceURL(tracerServiceURL)
+		baseURL, err := resolveServiceURL(tracerServiceURL)
+		if err != nil {
+			return err
+		}
 
 		jwtToken, clientID, err := resolveJWTAndClientID()
 		if err != nil {
@@ -168,7 +178,7 @@ func init() {
 	tracerCmd.AddCommand(tracerResetCmd)
 	tracerCmd.AddCommand(tracerLoadCmd)
 
-	tracerCmd.PersistentFlags().StringVar(&tracerServiceURL, "service-url", "", "Override the default service URL")
+	tracerCmd.PersistentFlags().StringVar(&tracerServiceURL, "service-url", "", "Override the default service URL (comma-separated URLs: the first healthy one is used)")
 
 	tracerDashboardCmd.Flags().StringVar(&tracerWindow, "window", "10s", "Sliding window size (e.g. 10s, 1m)")
 	tracerDashboardCmd.Flags().IntVar(&tracerTickMs, "tick-ms", 500, "UI refresh tick in milliseconds")
@@ -241,7 +251,7 @@ func safeDiv(a, b float64) float64 {
 func streamSnapshots(ctx context.Context, base, jwt, window string, out chan<- Snapshot, statusCh chan<- string, pollInterval time.Duration) {
 	defer close(out)
 	url := fmt.Sprintf("%s/api/v1/tracer/stream?window=%s", strings.TrimRight(base, "/"), window)
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := httpclient.Client(0)
 	backoff := 2 * time.Second
 	backoffMax := 30 * time.Second
 
@@ -592,8 +602,8 @@ func resetStats(ctx context.Context, base, jwt string) error {
 	if jwt != "" {
 		req.Header.Set("Authorization", "Bearer "+jwt)
 	}
-	// Use HTTP client with timeout to prevent hanging during fuzzing
-	client := &http.Client{Timeout: 10 * time.Second}
+	// Use the HTTP timeout to prevent hanging during fuzzing
+	client := httpclient.Client(0)
 	resp, err := client.Do(req)
 	if err != nil {
 		return err
@@ -606,12 +616,18 @@ func resetStats(ctx context.Context, base, jwt string) error {
 	return nil
 }
 
-func resolveServiceURL(override string) string {
-	base := config.LoadConfig().ServiceUrl
-	if override != "" {
-		base = override
+// resolveServiceURL picks the proxy the tracer talks to: the first healthy
+// one of the --service-url or profile proxies. Tracer stats are kept per
+// proxy, so a run stays on the one it starts with.
+func resolveServiceURL(override string) (string, error) {
+	endpoints, err := proxyEndpoints(override)
+	if err != nil {
+		return "", err
+	}
+	if len(endpoints) > 1 {
+		endpoints = session.OrderByHealth(context.Background(), endpoints)
 	}
-	return base
+	return endpoints[0], nil
 }
 
 func loadTokenAndClaims(storagePath string) (tokenStr string, claims *auth.TokenClaims, err error) {
@@ -620,16 +636,16 @@ func loadTokenAndClaims(storagePath string) (tokenStr string, claims *auth.Token
 
 	token, err := authClient.GetValidToken(storage)
 	if err != nil {
-		return "", nil, fmt.Errorf("authentication required: %v", err)
+		return "", nil, withExitCode(ExitAuth, fmt.Errorf("authentication required: %v", err))
 	}
 
 	parser := auth.NewTokenParser()
 	claims, err = parser.ParseToken(token.Token)
 	if err != nil {
-		return "", nil, fmt.Errorf("error parsing token: %v", err)
+		return "", nil, withExitCode(ExitAuth, fmt.Errorf("error parsing token: %v", err))
 	}
 	if !claims.IsActive {
-		return "", nil, fmt.Errorf("your account is inactive, please contact support")
+		return "", nil, withExitCode(ExitAuth, fmt.Errorf("your account is inactive, please contact support"))
 	}
 
 	return token.Token, claims, nil
@@ -679,8 +695,8 @@ func proxyPublishRandom(base, jwt, clientID, topic string, length uint64) error
 	if !IsAuthDisabled() && jwt != "" {
 		req.Header.Set("Authorization", "Bearer "+jwt)
 	}
-	// Use HTTP client with timeout to prevent hanging during fuzzing
-	client := &http.Client{Timeout: 10 * time.Second}
+	// Use the HTTP timeout to prevent hanging during fuzzing
+	client := httpclient.Client(0)
 	resp, err := client.Do(req)
 	if err != nil {
 		return err
@@ -712,8 +728,8 @@ func proxySubscribe(base, jwt, topic, clientID string, threshold int) error {
 	if !IsAuthDisabled() && jwt != "" {
 		req.Header.Set("Authorization", "Bearer "+jwt)
 	}
-	// Use HTTP client with timeout to prevent hanging during fuzzing
-	client := &http.Client{Timeout: 10 * time.Second}
+	// Use the HTTP timeout to prevent hanging during fuzzing
+	client := httpclient.Client(0)
 	resp, err := client.Do(req)
 	if err != nil {
 		return err
@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/getoptimum/mump2p-cli/internal/config"
+	"github.com/getoptimum/mump2p-cli/internal/httpclient"
 	"github.com/getoptimum/mump2p-cli/internal/version"
 	"github.com/spf13/cobra"
 )
@@ -136,17 +137,13 @@ func init() {
 func fetchLatestRelease() (*GitHubRelease, error) {
 	url := "https://api.github.com/repos/getoptimum/mump2p-cli/releases/latest"
 
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
+	client := httpclient.Client(0)
 
 	req, err := http.NewRequestWithContext(context.Background(), "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("User-Agent", "mump2p-cli-updater")
-
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch release info: %w\n"+
@@ -183,17 +180,13 @@ func getBinaryNameForOS() (string, error) {
 }
 
 func downloadBinary(url string) (string, error) {
-	client := &http.Client{
-		Timeout: 5 * time.Minute,
-	}
+	client := httpclient.Client(5 * time.Minute)
 
 	req, err := http.NewRequestWithContext(context.Background(), "GET", url, nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to create download request: %w", err)
 	}
-	
-	req.Header.Set("User-Agent", "mump2p-cli-updater")
-	
+
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to download binary: %w", err)
@@ -1,7 +1,19 @@
 package cmd
 
 import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"path/filepath"
 	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/getoptimum/mump2p-cli/internal/auth"
+	"github.com/getoptimum/mump2p-cli/internal/config"
+	"github.com/getoptimum/mump2p-cli/internal/node"
+	"github.com/getoptimum/mump2p-cli/internal/payload"
+	"github.com/getoptimum/mump2p-cli/internal/session"
 )
 
 // extractIPFromURL extracts IP address from URL string
@@ -9,3 +21,239 @@ func extractIPFromURL(url string) string {
 	ipRegex := regexp.MustCompile(`\d+\.\d+\.\d+\.\d+`)
 	return ipRegex.FindString(url)
 }
+
+// sessionStore returns the session cache next to the active auth file.
+func sessionStore() *session.Store {
+	s := session.NewStore(GetAuthDir())
+	s.OnSaveError = func(err error) {
+		fmt.Fprintf(statusOut(), "Warning: could not cache session: %v\n", err)
+	}
+	return s
+}
+
+// orderSessionNodes reorders the session's nodes according to the
+// --node-strategy value.
+func orderSessionNodes(sess *session.Session, strategy string) error {
+	st, err := session.ParseStrategy(strategy)
+	if err != nil {
+		return err
+	}
+	if st == session.StrategyProxy {
+		return nil
+	}
+	sel := session.NewSelector(st)
+	sel.CachePath = filepath.Join(GetAuthDir(), "probes.json")
+	dial := make(map[string][]node.DialOption, len(sess.Nodes))
+	for _, n := range sess.Nodes {
+		opts, err := nodeDialOptions(n)
+		if err != nil {
+			return err
+		}
+		dial[n.Address] = opts
+	}
+	sel.Prober = session.HealthProbeWith(func(addr string) []node.DialOption { return dial[addr] })
+	nodes, probes := sel.Order(context.Background(), sess.Nodes)
+	sess.Nodes = nodes
+	if IsDebugMode() {
+		fmt.Fprintf(statusOut(), "Node order (%s):\n", st)
+		for i, n := range nodes {
+			p := probes[n.Address]
+			if !p.OK() {
+				fmt.Fprintf(statusOut(), "  %d. %s (%s) unreachable: %s\n", i+1, n.Address, n.Region, p.Error)
+				continue
+			}
+			fmt.Fprintf(statusOut(), "  %d. %s (%s) rtt %s, connect %s, score %.2f\n",
+				i+1, n.Address, n.Region, humanDuration(p.RTT), humanDuration(p.Connect), n.Score)
+		}
+	}
+	return nil
+}
+
+var (
+	nodeDialMu         sync.Mutex
+	nodeTLSConfig      *tls.Config
+	nodeMaxMessageSize int64
+	nodeTuningShown    bool
+)
+
+// limitNodeMessages caps messages received from nodes to what the account
+// may publish. Without a token the node package default applies.
+func limitNodeMessages(claims *auth.TokenClaims) {
+	if claims != nil {
+		nodeMaxMessageSize = claims.MaxMessageSize
+	}
+}
+
+// subscribeMinMessageSize is the smallest message limit a subscriber assumes
+// for other publishers on the topic.
+const subscribeMinMessageSize = 4 << 20
+
+// limitSubscribedMessages caps messages received by a subscriber. Other
+// publishers may have higher limits than the account, so the cap leaves
+// headroom: twice the account's limit, and at least subscribeMinMessageSize.
+func limitSubscribedMessages(claims *auth.TokenClaims) {
+	if claims != nil {
+		nodeMaxMessageSize = max(2*claims.MaxMessageSize, subscribeMinMessageSize)
+	}
+}
+
+// nodeTuning returns the gRPC settings from the profile, with the receive
+// cap derived from the token unless the profile sets one.
+func nodeTuning(profile *config.Profile) node.Tuning {
+	g := profile.GRPC
+	t := node.Tuning{
+		Keepalive:        g.Keepalive,
+		KeepaliveTimeout: g.KeepaliveTimeout,
+		MaxRecvSize:      g.MaxRecvSize,
+		ConnectTimeout:   g.ConnectTimeout,
+		BackoffBase:      g.BackoffBase,
+		BackoffMax:       g.BackoffMax,
+	}
+	if t.MaxRecvSize == 0 {
+		t.MaxRecvSize = node.RecvSizeFor(nodeMaxMessageSize)
+	}
+	return t.WithDefaults()
+}
+
+// nodeDialOptions returns the options for connecting to n: the profile's
+// gRPC tuning, and TLS when the node's transport asks for it or --tls (or
+// the profile) forces it.
+func nodeDialOptions(n session.Node) ([]node.DialOption, error) {
+	nodeDialMu.Lock()
+	defer nodeDialMu.Unlock()
+	profile, err := GetProfile()
+	if err != nil {
+		return nil, err
+	}
+	tuning := nodeTuning(profile)
+	if IsDebugMode() && !nodeTuningShown {
+		nodeTuningShown = true
+		fmt.Fprintf(statusOut(), "gRPC: %s\n", tuning)
+	}
+	opts := []node.DialOption{node.WithTuning(tuning)}
+	if !nodeTLS && !profile.TLS.Enabled && !node.UsesTLS(n.Transport) {
+		return opts, nil
+	}
+	if nodeTLSConfig == nil {
+		cfg, err := node.TLSConfig{
+			CAFile:     firstNonEmpty(nodeTLSCA, profile.TLS.CAFile),
+			CertFile:   firstNonEmpty(nodeTLSCert, profile.TLS.CertFile),
+			KeyFile:    firstNonEmpty(nodeTLSKey, profile.TLS.KeyFile),
+			ServerName: firstNonEmpty(nodeTLSServerName, profile.TLS.ServerName),
+		}.Load()
+		if err != nil {
+			return nil, fmt.Errorf("node TLS: %v", err)
+		}
+		nodeTLSConfig = cfg
+	}
+	return append(opts, node.WithTLS(nodeTLSConfig)), nil
+}
+
+// dialNode connects to a session node with nodeDialOptions.
+func dialNode(n session.Node) (*node.Client, error) {
+	opts, err := nodeDialOptions(n)
+	if err != nil {
+		return nil, err
+	}
+	return node.NewClient(n.Address, opts...)
+}
+
+// proxyEndpoints returns the proxies to use, in order: the comma-separated
+// override, else service_urls from the profile, else the default proxy.
+func proxyEndpoints(override string) ([]string, error) {
+	if endpoints := session.ParseEndpoints(override); len(endpoints) > 0 {
+		return endpoints, nil
+	}
+	profile, err := GetProfile()
+	if err != nil {
+		return nil, err
+	}
+	if endpoints := session.ParseEndpoints(strings.Join(profile.ServiceURLs, ",")); len(endpoints) > 0 {
+		return endpoints, nil
+	}
+	return []string{config.LoadConfig().ServiceUrl}, nil
+}
+
+// newSessionRequest builds a session request, filling the proxies,
+// transport, region and protocol from flags first and the profile second.
+// serviceURL is the --service-url value, a comma-separated list of proxies
+// to fail over between.
+func newSessionRequest(serviceURL, clientID, accessToken string, topics, capabilities []string, exposeAmount uint32, region, protocol string) (session.Request, error) {
+	profile, err := GetProfile()
+	if err != nil {
+		return session.Request{}, err
+	}
+
+	transport, err := session.ParseTransport(firstNonEmpty(sessionTransport, profile.SessionTransport))
+	if err != nil {
+		return session.Request{}, err
+	}
+	protocol, err = session.ParseProtocol(firstNonEmpty(protocol, profile.Protocol))
+	if err != nil {
+		return session.Request{}, err
+	}
+
+	var endpoints []string
+	if transport == session.TransportGRPC {
+		endpoints = session.ParseEndpoints(firstNonEmpty(sessionAddress, profile.SessionAddress))
+		if len(endpoints) == 0 {
+			return session.Request{}, fmt.Errorf("--session-address (or session_address in the profile) is required for the grpc session transport")
+		}
+	} else if endpoints, err = proxyEndpoints(serviceURL); err != nil {
+		return session.Request{}, err
+	}
+
+	req := session.Request{
+		Endpoint:     endpoints[0],
+		Fallbacks:    endpoints[1:],
+		Transport:    transport,
+		ClientID:     clientID,
+		AccessToken:  accessToken,
+		Topics:       topics,
+		Capabilities: capabilities,
+		ExposeAmount: exposeAmount,
+		Protocol:     protocol,
+		Region:       firstNonEmpty(region, profile.Region),
+		Plaintext:    sessionPlaintext || profile.SessionPlaintext,
+	}
+	if transport == session.TransportGRPC && !req.Plaintext {
+		// a private CA or client certificate for the nodes is used for the
+		// SessionService too; without one, the system roots apply
+		tc := node.TLSConfig{
+			CAFile:   firstNonEmpty(nodeTLSCA, profile.TLS.CAFile),
+			CertFile: firstNonEmpty(nodeTLSCert, profile.TLS.CertFile),
+			KeyFile:  firstNonEmpty(nodeTLSKey, profile.TLS.KeyFile),
+		}
+		if tc != (node.TLSConfig{}) {
+			if req.TLS, err = tc.Load(); err != nil {
+				return session.Request{}, fmt.Errorf("session TLS: %v", err)
+			}
+		}
+	}
+	return req, nil
+}
+
+// loadProtoCodec returns the codec for --proto-descriptor and --proto-type,
+// or nil when neither is set.
+func loadProtoCodec(descriptor, typeName string) (*payload.ProtoCodec, error) {
+	if descriptor == "" && typeName == "" {
+		return nil, nil
+	}
+	if descriptor == "" || typeName == "" {
+		return nil, withExitCode(ExitUsage, fmt.Errorf("--proto-descriptor and --proto-type must be used together"))
+	}
+	codec, err := payload.LoadProtoCodec(descriptor, typeName)
+	if err != nil {
+		return nil, withExitCode(ExitUsage, err)
+	}
+	return codec, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -5,8 +5,10 @@ go 1.24.6
 require (
 	github.com/gizak/termui/v3 v3.1.0
 	github.com/golang-jwt/jwt/v4 v4.5.2
+	github.com/klauspost/compress v1.18.0
 	github.com/spf13/cobra v1.9.1
 	github.com/stretchr/testify v1.11.1
+	golang.org/x/term v0.37.0
 	google.golang.org/grpc v1.73.0
 	google.golang.org/protobuf v1.36.9
 	gopkg.in/yaml.v2 v2.4.0
@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// BundleVersion is the current token bundle format version.
+	BundleVersion = 1
+
+	bundleKDF        = "pbkdf2-sha256"
+	bundleCipher     = "aes-256-gcm"
+	bundleIterations = 600000
+	// Imported bundles may use other counts within these bounds: fewer is
+	// too weak, and more would stall the import.
+	bundleMinIterations = 100000
+	bundleMaxIterations = 10 * bundleIterations
+	bundleSaltSize      = 16
+	bundleKeySize       = 32
+)
+
+// ErrPassphraseRequired is returned by ImportBundle for an encrypted bundle
+// when no passphrase is given.
+var ErrPassphraseRequired = errors.New("bundle is encrypted, a passphrase is required")
+
+// Bundle is a portable, optionally encrypted, export of a StoredToken.
+type Bundle struct {
+	Version    int          `json:"version"`
+	Encrypted  bool         `json:"encrypted"`
+	KDF        string       `json:"kdf,omitempty"`
+	Cipher     string       `json:"cipher,omitempty"`
+	Iterations int          `json:"iterations,omitempty"`
+	Salt       []byte       `json:"salt,omitempty"`
+	Nonce      []byte       `json:"nonce,omitempty"`
+	Ciphertext []byte       `json:"ciphertext,omitempty"`
+	Token      *StoredToken `json:"token,omitempty"`
+}
+
+// ExportBundle serializes a token into a bundle. When passphrase is not
+// empty the token is sealed with AES-256-GCM using a PBKDF2 derived key.
+func ExportBundle(token *StoredToken, passphrase string) ([]byte, error) {
+	if token == nil || token.Token == "" {
+		return nil, fmt.Errorf("no token to export")
+	}
+
+	b := Bundle{Version: BundleVersion}
+	if passphrase == "" {
+		b.Token = token
+		return json.MarshalIndent(b, "", "  ")
+	}
+
+	plain, err := yaml.Marshal(token)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding token: %v", err)
+	}
+
+	salt := make([]byte, bundleSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("error generating salt: %v", err)
+	}
+	gcm, err := bundleAEAD(passphrase, salt, bundleIterations)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("error generating nonce: %v", err)
+	}
+
+	b.Encrypted = true
+	b.KDF = bundleKDF
+	b.Cipher = bundleCipher
+	b.Iterations = bundleIterations
+	b.Salt = salt
+	b.Nonce = nonce
+	b.Ciphertext = gcm.Seal(nil, nonce, plain, nil)
+	return json.MarshalIndent(b, "", "  ")
+}
+
+// ImportBundle restores a token from a bundle produced by ExportBundle. If
+// data is not a bundle it is treated as a raw JWT access token, and its
+// expiry is taken from the exp claim.
+func ImportBundle(data []byte, passphrase string) (*StoredToken, error) {
+	trimmed := strings.TrimSpace(string(data))
+	if trimmed == "" {
+		return nil, fmt.Errorf("empty input")
+	}
+
+	if !strings.HasPrefix(trimmed, "{") {
+		return tokenFromJWT(trimmed)
+	}
+
+	var b Bundle
+	if err := json.Unmarshal([]byte(trimmed), &b); err != nil {
+		return nil, fmt.Errorf("error parsing bundle: %v", err)
+	}
+	if b.Version != BundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d", b.Version)
+	}
+
+	if !b.Encrypted {
+		if b.Token == nil || b.Token.Token == "" {
+			return nil, fmt.Errorf("bundle does not contain a token")
+		}
+		return b.Token, nil
+	}
+
+	if passphrase == "" {
+		return nil, ErrPassphraseRequired
+	}
+	if b.KDF != bundleKDF || b.Cipher != bundleCipher {
+		return nil, fmt.Errorf("unsupported bundle encryption %s/%s", b.KDF, b.Cipher)
+	}
+	if b.Iterations < bundleMinIterations || b.Iterations > bundleMaxIterations {
+		return nil, fmt.Errorf("bundle uses %d key derivation iterations, outside %d..%d", b.Iterations, bundleMinIterations, bundleMaxIterations)
+	}
+	gcm, err := bundleAEAD(passphrase, b.Salt, b.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	if len(b.Nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid bundle nonce")
+	}
+	plain, err := gcm.Open(nil, b.Nonce, b.Ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting bundle: wrong passphrase or corrupted data")
+	}
+
+	var token StoredToken
+	if err := yaml.Unmarshal(plain, &token); err != nil {
+		return nil, fmt.Errorf("error parsing token: %v", err)
+	}
+	if token.Token == "" {
+		return nil, fmt.Errorf("bundle does not contain a token")
+	}
+	return &token, nil
+}
+
+// tokenFromJWT wraps a bare access token into a StoredToken.
+func tokenFromJWT(raw string) (*StoredToken, error) {
+	claims, err := NewTokenParser().ParseToken(raw)
+	if err != nil {
+		return nil, err
+	}
+	if claims.ExpiresAt.IsZero() {
+		return nil, fmt.Errorf("token has no exp claim")
+	}
+	if time.Now().After(claims.ExpiresAt) {
+		return nil, fmt.Errorf("token has expired")
+	}
+	return &StoredToken{
+		Token:     raw,
+		ExpiresAt: claims.ExpiresAt,
+	}, nil
+}
+
+func bundleAEAD(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	if iterations <= 0 || len(salt) == 0 {
+		return nil, fmt.Errorf("invalid bundle key parameters")
+	}
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, bundleKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("error deriving key: %v", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("error creating cipher: %v", err)
+	}
+	return cipher.NewGCM(block)
+}
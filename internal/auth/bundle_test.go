@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBundleRoundTrip tests ExportBundle and ImportBundle.
+func TestBundleRoundTrip(t *testing.T) {
+	token := &StoredToken{
+		Token:        "header.payload.signature",
+		RefreshToken: "refresh",
+		ExpiresAt:    time.Now().Add(time.Hour).Truncate(time.Second),
+	}
+
+	t.Run("plain bundle", func(t *testing.T) {
+		data, err := ExportBundle(token, "")
+		require.NoError(t, err)
+
+		var b Bundle
+		require.NoError(t, json.Unmarshal(data, &b))
+		require.False(t, b.Encrypted)
+
+		restored, err := ImportBundle(data, "")
+		require.NoError(t, err)
+		require.Equal(t, token.Token, restored.Token)
+		require.Equal(t, token.RefreshToken, restored.RefreshToken)
+		require.True(t, token.ExpiresAt.Equal(restored.ExpiresAt))
+	})
+
+	t.Run("encrypted bundle", func(t *testing.T) {
+		data, err := ExportBundle(token, "s3cret")
+		require.NoError(t, err)
+		require.NotContains(t, string(data), token.Token)
+
+		restored, err := ImportBundle(data, "s3cret")
+		require.NoError(t, err)
+		require.Equal(t, token.Token, restored.Token)
+		require.True(t, token.ExpiresAt.Equal(restored.ExpiresAt))
+	})
+
+	t.Run("encrypted bundle with wrong passphrase", func(t *testing.T) {
+		data, err := ExportBundle(token, "s3cret")
+		require.NoError(t, err)
+
+		_, err = ImportBundle(data, "nope")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "wrong passphrase")
+
+		_, err = ImportBundle(data, "")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "passphrase is required")
+	})
+
+	t.Run("encrypted bundle with out of range iterations", func(t *testing.T) {
+		data, err := ExportBundle(token, "s3cret")
+		require.NoError(t, err)
+		for _, n := range []int{1, bundleMaxIterations + 1} {
+			var b Bundle
+			require.NoError(t, json.Unmarshal(data, &b))
+			b.Iterations = n
+			tampered, err := json.Marshal(b)
+			require.NoError(t, err)
+
+			_, err = ImportBundle(tampered, "s3cret")
+			require.ErrorContains(t, err, "key derivation iterations")
+		}
+	})
+
+	t.Run("raw jwt", func(t *testing.T) {
+		exp := time.Now().Add(time.Hour).Unix()
+		raw := generateFakeToken(jwt.MapClaims{"sub": "user123", "exp": float64(exp)})
+
+		restored, err := ImportBundle([]byte(raw+"\n"), "")
+		require.NoError(t, err)
+		require.Equal(t, raw, restored.Token)
+		require.Equal(t, exp, restored.ExpiresAt.Unix())
+	})
+
+	t.Run("expired raw jwt", func(t *testing.T) {
+		raw := generateFakeToken(jwt.MapClaims{"sub": "user123", "exp": float64(time.Now().Add(-time.Hour).Unix())})
+
+		_, err := ImportBundle([]byte(raw), "")
+		require.Error(t, err)
+	})
+
+	t.Run("empty input", func(t *testing.T) {
+		_, err := ImportBundle([]byte("  \n"), "")
+		require.Error(t, err)
+	})
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/getoptimum/mump2p-cli/internal/config"
+	"github.com/getoptimum/mump2p-cli/internal/httpclient"
 )
 
 // Client handles Auth0 API interactions
@@ -68,7 +69,7 @@ func (c *Client) requestDeviceCode() (*DeviceCodeResponse, error) {
 	}
 
 	// Request device code from Auth0
-	resp, err := http.Post(
+	resp, err := httpclient.Client(0).Post(
 		fmt.Sprintf("https://%s/oauth/device/code", c.domain),
 		"application/json",
 		bytes.NewBuffer(payloadBytes),
@@ -119,7 +120,7 @@ func (c *Client) pollForToken(deviceCode *DeviceCodeResponse) (*StoredToken, err
 		time.Sleep(interval)
 
 		// token request
-		resp, err := http.Post(
+		resp, err := httpclient.Client(0).Post(
 			fmt.Sprintf("https://%s/oauth/token", c.domain),
 			"application/json",
 			bytes.NewBuffer(payloadBytes),
@@ -191,7 +192,7 @@ func (c *Client) RefreshToken(refreshToken string) (*StoredToken, error) {
 		return nil, fmt.Errorf("error creating refresh payload: %v", err)
 	}
 
-	resp, err := http.Post(
+	resp, err := httpclient.Client(0).Post(
 		fmt.Sprintf("https://%s/oauth/token", c.domain),
 		"application/json",
 		bytes.NewBuffer(payloadBytes),
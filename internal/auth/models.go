@@ -13,9 +13,9 @@ type TokenResponse struct {
 
 // StoredToken represents the token stored locally
 type StoredToken struct {
-	Token        string    `yaml:"token"`
-	RefreshToken string    `yaml:"refresh_token,omitempty"`
-	ExpiresAt    time.Time `yaml:"expires_at"`
+	Token        string    `yaml:"token" json:"token"`
+	RefreshToken string    `yaml:"refresh_token,omitempty" json:"refresh_token,omitempty"`
+	ExpiresAt    time.Time `yaml:"expires_at" json:"expires_at"`
 }
 
 // DeviceCodeResponse represents the initial device code response
@@ -18,35 +18,9 @@ func NewTokenParser() *TokenParser {
 
 // ParseToken extracts claims from a JWT token without verifying signature
 func (p *TokenParser) ParseToken(tokenString string) (*TokenClaims, error) {
-	// parse the token without validation
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// we don't have the key, so we can't validate signature
-		// we just want to read the claims
-		return nil, nil
-	})
-
-	// expect signature validation to fail
-	// extract claims anyway if possible
-	var claims jwt.MapClaims
+	_, claims, err := parseUnverified(tokenString)
 	if err != nil {
-		if validationError, ok := err.(*jwt.ValidationError); ok {
-			if validationError.Errors == jwt.ValidationErrorSignatureInvalid {
-				// this is expected, extract claims anyway
-				claims, ok = token.Claims.(jwt.MapClaims)
-				if !ok {
-					return nil, fmt.Errorf("invalid token claims format")
-				}
-			} else {
-				return nil, fmt.Errorf("error parsing token: %v", err)
-			}
-		} else {
-			return nil, fmt.Errorf("error parsing token: %v", err)
-		}
-	} else {
-		_, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			return nil, fmt.Errorf("invalid token claims format")
-		}
+		return nil, err
 	}
 
 	tc := &TokenClaims{
@@ -78,6 +52,58 @@ func (p *TokenParser) ParseToken(tokenString string) (*TokenClaims, error) {
 	return tc, nil
 }
 
+// ParseRawClaims returns the JWT header and every claim in the payload,
+// including claims that TokenClaims does not know about.
+func (p *TokenParser) ParseRawClaims(tokenString string) (map[string]interface{}, map[string]interface{}, error) {
+	token, claims, err := parseUnverified(tokenString)
+	if err != nil {
+		return nil, nil, err
+	}
+	return token.Header, claims, nil
+}
+
+// KnownClaims lists the claim keys that ParseToken maps onto TokenClaims.
+var KnownClaims = []string{
+	"sub", "iat", "exp", "client_id", "limits_set_at", "is_active",
+	"max_publish_per_hour", "max_publish_per_sec", "max_message_size", "daily_quota",
+}
+
+// parseUnverified decodes a JWT without validating its signature.
+func parseUnverified(tokenString string) (*jwt.Token, jwt.MapClaims, error) {
+	// parse the token without validation
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		// we don't have the key, so we can't validate signature
+		// we just want to read the claims
+		return nil, nil
+	})
+
+	// expect signature validation to fail
+	// extract claims anyway if possible
+	var claims jwt.MapClaims
+	if err != nil {
+		if validationError, ok := err.(*jwt.ValidationError); ok {
+			if validationError.Errors == jwt.ValidationErrorSignatureInvalid {
+				// this is expected, extract claims anyway
+				claims, ok = token.Claims.(jwt.MapClaims)
+				if !ok {
+					return nil, nil, fmt.Errorf("invalid token claims format")
+				}
+			} else {
+				return nil, nil, fmt.Errorf("error parsing token: %v", err)
+			}
+		} else {
+			return nil, nil, fmt.Errorf("error parsing token: %v", err)
+		}
+	} else {
+		var ok bool
+		claims, ok = token.Claims.(jwt.MapClaims)
+		if !ok {
+			return nil, nil, fmt.Errorf("invalid token claims format")
+		}
+	}
+	return token, claims, nil
+}
+
 func intFromClaims(c jwt.MapClaims, key string, def int) int {
 	if v, ok := c[key].(float64); ok {
 		return int(v)
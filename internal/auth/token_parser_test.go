@@ -104,3 +104,22 @@ func TestParseToken(t *testing.T) {
 		})
 	}
 }
+
+// TestParseRawClaims tests ParseRawClaims keeps unknown claims.
+func TestParseRawClaims(t *testing.T) {
+	raw := generateFakeToken(jwt.MapClaims{
+		"sub":              "user123",
+		"max_message_size": float64(1024),
+		"org":              "optimum",
+	})
+
+	header, claims, err := NewTokenParser().ParseRawClaims(raw)
+	require.NoError(t, err)
+	require.Equal(t, "HS256", header["alg"])
+	require.Equal(t, "user123", claims["sub"])
+	require.Equal(t, float64(1024), claims["max_message_size"])
+	require.Equal(t, "optimum", claims["org"])
+
+	_, _, err = NewTokenParser().ParseRawClaims("not-a-jwt")
+	require.Error(t, err)
+}
@@ -0,0 +1,286 @@
+// Package bench implements the payload framing and statistics used by the
+// end-to-end latency benchmark.
+package bench
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// magic prefixes every benchmark payload so foreign traffic on the topic is
+// ignored.
+var magic = []byte("MBNCH1")
+
+// HeaderSize is the minimum payload size: magic, run ID, sequence and send
+// timestamp.
+const HeaderSize = 6 + 8 + 8 + 8
+
+// NewRunID returns a random identifier for a benchmark run.
+func NewRunID() [8]byte {
+	var id [8]byte
+	_, _ = rand.Read(id[:])
+	return id
+}
+
+// Encode builds a payload of at least size bytes carrying seq and the send
+// time. Bytes after the header are random padding.
+func Encode(runID [8]byte, seq uint64, sent time.Time, size int) []byte {
+	if size < HeaderSize {
+		size = HeaderSize
+	}
+	buf := make([]byte, size)
+	copy(buf, magic)
+	copy(buf[6:], runID[:])
+	binary.BigEndian.PutUint64(buf[14:], seq)
+	binary.BigEndian.PutUint64(buf[22:], uint64(sent.UnixNano()))
+	_, _ = rand.Read(buf[HeaderSize:])
+	return buf
+}
+
+// Decode extracts the sequence and send time from a payload belonging to
+// runID. ok is false for payloads from other runs or other publishers.
+func Decode(runID [8]byte, data []byte) (seq uint64, sent time.Time, ok bool) {
+	if len(data) < HeaderSize || !bytes.Equal(data[:6], magic) || !bytes.Equal(data[6:14], runID[:]) {
+		return 0, time.Time{}, false
+	}
+	seq = binary.BigEndian.Uint64(data[14:])
+	sent = time.Unix(0, int64(binary.BigEndian.Uint64(data[22:])))
+	return seq, sent, true
+}
+
+// Sample is one received copy of a benchmark message.
+type Sample struct {
+	Seq        uint64    `json:"seq" yaml:"seq"`
+	SentAt     time.Time `json:"sent_at" yaml:"sent_at"`
+	RecvAt     time.Time `json:"recv_at" yaml:"recv_at"`
+	LatencyMs  float64   `json:"latency_ms" yaml:"latency_ms"`
+	Duplicate  bool      `json:"duplicate" yaml:"duplicate"`
+	OutOfOrder bool      `json:"out_of_order" yaml:"out_of_order"`
+}
+
+// Recorder collects receptions while a benchmark runs. It is safe for
+// concurrent use.
+type Recorder struct {
+	mu      sync.Mutex
+	runID   [8]byte
+	seen    map[uint64]bool
+	maxSeq  uint64
+	anySeen bool
+	samples []Sample
+}
+
+// NewRecorder creates a recorder for one run.
+func NewRecorder(runID [8]byte) *Recorder {
+	return &Recorder{runID: runID, seen: make(map[uint64]bool)}
+}
+
+// Observe records a received payload and reports whether it belonged to the
+// run.
+func (r *Recorder) Observe(data []byte, recv time.Time) bool {
+	seq, sent, ok := Decode(r.runID, data)
+	if !ok {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := Sample{
+		Seq:       seq,
+		SentAt:    sent,
+		RecvAt:    recv,
+		LatencyMs: float64(recv.Sub(sent)) / float64(time.Millisecond),
+		Duplicate: r.seen[seq],
+	}
+	if !s.Duplicate {
+		if r.anySeen && seq < r.maxSeq {
+			s.OutOfOrder = true
+		}
+		if !r.anySeen || seq > r.maxSeq {
+			r.maxSeq = seq
+		}
+		r.anySeen = true
+		r.seen[seq] = true
+	}
+	r.samples = append(r.samples, s)
+	return true
+}
+
+// Received returns the number of distinct messages seen so far.
+func (r *Recorder) Received() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.seen)
+}
+
+// LatencyStats summarizes first-arrival latencies in milliseconds.
+type LatencyStats struct {
+	Min  float64 `json:"min_ms" yaml:"min_ms"`
+	Mean float64 `json:"mean_ms" yaml:"mean_ms"`
+	P50  float64 `json:"p50_ms" yaml:"p50_ms"`
+	P90  float64 `json:"p90_ms" yaml:"p90_ms"`
+	P99  float64 `json:"p99_ms" yaml:"p99_ms"`
+	Max  float64 `json:"max_ms" yaml:"max_ms"`
+}
+
+// Bucket is one histogram bin counting latencies up to UpperMs.
+type Bucket struct {
+	UpperMs float64 `json:"upper_ms" yaml:"upper_ms"`
+	Count   int     `json:"count" yaml:"count"`
+}
+
+// Report is the result of a benchmark run.
+type Report struct {
+	RunID         string       `json:"run_id" yaml:"run_id"`
+	Topic         string       `json:"topic" yaml:"topic"`
+	PublishNode   string       `json:"publish_node" yaml:"publish_node"`
+	SubscribeNode string       `json:"subscribe_node" yaml:"subscribe_node"`
+	PayloadSize   int          `json:"payload_size" yaml:"payload_size"`
+	Sent          int          `json:"sent" yaml:"sent"`
+	PublishErrors int          `json:"publish_errors" yaml:"publish_errors"`
+	Received      int          `json:"received" yaml:"received"`
+	Lost          int          `json:"lost" yaml:"lost"`
+	Duplicates    int          `json:"duplicates" yaml:"duplicates"`
+	OutOfOrder    int          `json:"out_of_order" yaml:"out_of_order"`
+	DeliveryRatio float64      `json:"delivery_ratio" yaml:"delivery_ratio"`
+	DurationMs    float64      `json:"duration_ms" yaml:"duration_ms"`
+	Latency       LatencyStats `json:"latency" yaml:"latency"`
+	Histogram     []Bucket     `json:"histogram" yaml:"histogram"`
+	Samples       []Sample     `json:"samples,omitempty" yaml:"samples,omitempty"`
+	StartedAt     time.Time    `json:"started_at" yaml:"started_at"`
+}
+
+// bucketBounds are the histogram upper bounds in milliseconds; the last
+// bucket is unbounded.
+var bucketBounds = []float64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000, 2000, 5000, math.Inf(1)}
+
+// Report builds the run summary. sent is the number of successful publishes.
+func (r *Recorder) Report(sent, publishErrors int) *Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rep := &Report{
+		RunID:         hex.EncodeToString(r.runID[:]),
+		Sent:          sent,
+		PublishErrors: publishErrors,
+		Received:      len(r.seen),
+		Samples:       append([]Sample(nil), r.samples...),
+	}
+	var lat []float64
+	for _, s := range r.samples {
+		switch {
+		case s.Duplicate:
+			rep.Duplicates++
+			continue
+		case s.OutOfOrder:
+			rep.OutOfOrder++
+		}
+		lat = append(lat, s.LatencyMs)
+	}
+	if sent > 0 {
+		rep.DeliveryRatio = float64(rep.Received) / float64(sent)
+		if lost := sent - rep.Received; lost > 0 {
+			rep.Lost = lost
+		}
+	}
+	rep.Latency = summarize(lat)
+	rep.Histogram = histogram(lat)
+	return rep
+}
+
+func summarize(lat []float64) LatencyStats {
+	if len(lat) == 0 {
+		return LatencyStats{}
+	}
+	sorted := append([]float64(nil), lat...)
+	sort.Float64s(sorted)
+	var sum float64
+	for _, v := range sorted {
+		sum += v
+	}
+	return LatencyStats{
+		Min:  sorted[0],
+		Mean: sum / float64(len(sorted)),
+		P50:  percentile(sorted, 50),
+		P90:  percentile(sorted, 90),
+		P99:  percentile(sorted, 99),
+		Max:  sorted[len(sorted)-1],
+	}
+}
+
+// percentile uses the nearest-rank method on sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func histogram(lat []float64) []Bucket {
+	buckets := make([]Bucket, len(bucketBounds))
+	for i, b := range bucketBounds {
+		buckets[i].UpperMs = b
+	}
+	for _, v := range lat {
+		for i, b := range bucketBounds {
+			if v <= b {
+				buckets[i].Count++
+				break
+			}
+		}
+	}
+	// JSON cannot carry +Inf; the open bucket is reported with upper 0.
+	buckets[len(buckets)-1].UpperMs = 0
+	return buckets
+}
+
+// WriteJSON writes the full report, including samples.
+func (rep *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rep)
+}
+
+// WriteCSV writes one row per received sample, for spreadsheets and
+// cross-run comparison.
+func (rep *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"run_id", "topic", "payload_size", "seq", "sent_at_ns", "recv_at_ns", "latency_ms", "duplicate", "out_of_order"}); err != nil {
+		return err
+	}
+	for _, s := range rep.Samples {
+		if err := cw.Write([]string{
+			rep.RunID,
+			rep.Topic,
+			strconv.Itoa(rep.PayloadSize),
+			strconv.FormatUint(s.Seq, 10),
+			strconv.FormatInt(s.SentAt.UnixNano(), 10),
+			strconv.FormatInt(s.RecvAt.UnixNano(), 10),
+			strconv.FormatFloat(s.LatencyMs, 'f', 3, 64),
+			strconv.FormatBool(s.Duplicate),
+			strconv.FormatBool(s.OutOfOrder),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Label describes a histogram bucket, e.g. "≤10ms" or ">5000ms".
+func (b Bucket) Label(prev float64) string {
+	if b.UpperMs == 0 {
+		return fmt.Sprintf(">%gms", prev)
+	}
+	return fmt.Sprintf("≤%gms", b.UpperMs)
+}
@@ -0,0 +1,83 @@
+package bench
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestEncodeDecode tests payload framing.
+func TestEncodeDecode(t *testing.T) {
+	run := NewRunID()
+	sent := time.Unix(0, 1700000000123456789)
+
+	data := Encode(run, 42, sent, 1024)
+	require.Len(t, data, 1024)
+
+	seq, gotSent, ok := Decode(run, data)
+	require.True(t, ok)
+	require.Equal(t, uint64(42), seq)
+	require.True(t, sent.Equal(gotSent))
+
+	require.Len(t, Encode(run, 1, sent, 4), HeaderSize, "size is raised to the header size")
+
+	_, _, ok = Decode(NewRunID(), data)
+	require.False(t, ok, "payload from another run")
+	_, _, ok = Decode(run, []byte("hello"))
+	require.False(t, ok, "foreign payload")
+}
+
+// TestRecorderReport tests loss, duplicate, reordering and latency accounting.
+func TestRecorderReport(t *testing.T) {
+	run := NewRunID()
+	base := time.Now()
+	r := NewRecorder(run)
+
+	recv := func(seq uint64, latency time.Duration) {
+		sent := base.Add(time.Duration(seq) * time.Second)
+		require.True(t, r.Observe(Encode(run, seq, sent, 64), sent.Add(latency)))
+	}
+	recv(0, 10*time.Millisecond)
+	recv(2, 30*time.Millisecond)
+	recv(1, 20*time.Millisecond) // out of order
+	recv(2, 50*time.Millisecond) // duplicate
+	recv(3, 40*time.Millisecond)
+	require.False(t, r.Observe([]byte("noise"), base))
+
+	rep := r.Report(5, 1)
+	require.Equal(t, 5, rep.Sent)
+	require.Equal(t, 1, rep.PublishErrors)
+	require.Equal(t, 4, rep.Received)
+	require.Equal(t, 1, rep.Lost)
+	require.Equal(t, 1, rep.Duplicates)
+	require.Equal(t, 1, rep.OutOfOrder)
+	require.InDelta(t, 0.8, rep.DeliveryRatio, 1e-9)
+	require.InDelta(t, 10, rep.Latency.Min, 0.01)
+	require.InDelta(t, 20, rep.Latency.P50, 0.01)
+	require.InDelta(t, 40, rep.Latency.P99, 0.01)
+	require.InDelta(t, 40, rep.Latency.Max, 0.01)
+	require.InDelta(t, 25, rep.Latency.Mean, 0.01)
+
+	var total int
+	for _, b := range rep.Histogram {
+		total += b.Count
+	}
+	require.Equal(t, 4, total, "histogram counts first arrivals only")
+
+	var buf bytes.Buffer
+	require.NoError(t, rep.WriteJSON(&buf))
+	var decoded Report
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	require.Len(t, decoded.Samples, 5)
+
+	buf.Reset()
+	require.NoError(t, rep.WriteCSV(&buf))
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 6)
+	require.Equal(t, "seq", rows[0][3])
+}
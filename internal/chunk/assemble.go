@@ -0,0 +1,256 @@
+package chunk
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxFileSize caps the size of a file the Assembler will rebuild.
+const MaxFileSize = 16 << 30
+
+const (
+	// MaxChunks caps the chunk count of a transfer.
+	MaxChunks = 1 << 20
+	// MaxEarlyChunks caps the chunks held for a transfer whose manifest
+	// has not arrived.
+	MaxEarlyChunks = 4096
+)
+
+// Result reports a transfer that finished, failed or timed out.
+type Result struct {
+	ID string
+	// Manifest is nil when the manifest never arrived.
+	Manifest *Manifest
+	// Path is where the file was written; set when Err is nil.
+	Path     string
+	Received int
+	Err      error
+}
+
+// Name is the file name, or the transfer ID when the manifest is missing.
+func (r Result) Name() string {
+	if r.Manifest != nil {
+		return r.Manifest.Name
+	}
+	return r.ID
+}
+
+// Assembler rebuilds files from chunk messages into a directory. Chunks are
+// written to a hidden part file as they arrive, in any order, and the file
+// is moved into place once every chunk is there and the SHA-256 matches.
+type Assembler struct {
+	dir     string
+	timeout time.Duration
+
+	mu        sync.Mutex
+	transfers map[string]*transfer
+	// finished remembers when recent transfers ended, so their late and
+	// duplicate messages are ignored; entries expire after the timeout.
+	finished map[string]time.Time
+}
+
+type transfer struct {
+	id       string
+	manifest *Manifest
+	part     *os.File
+	path     string
+	sizes    map[int]int64 // chunk length by seq, checked against the manifest
+	offsets  map[int]int64
+	last     time.Time
+}
+
+// NewAssembler writes files into dir, creating it if needed. Transfers that
+// receive nothing for timeout are abandoned.
+func NewAssembler(dir string, timeout time.Duration) (*Assembler, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Assembler{
+		dir:       dir,
+		timeout:   timeout,
+		transfers: make(map[string]*transfer),
+		finished:  make(map[string]time.Time),
+	}, nil
+}
+
+// Add handles a parsed message. It returns a result when the message
+// completes its transfer or makes it fail, and nil otherwise.
+func (a *Assembler) Add(m Message, now time.Time) *Result {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	id := m.ID
+	if m.Manifest != nil {
+		id = m.Manifest.ID
+	}
+	if _, done := a.finished[id]; done {
+		return nil
+	}
+	t, ok := a.transfers[id]
+	if !ok {
+		part, err := os.CreateTemp(a.dir, ".mump2p-"+id[:8]+"-*.part")
+		if err != nil {
+			return a.finish(&transfer{id: id}, now, fmt.Errorf("failed to create part file: %v", err))
+		}
+		t = &transfer{id: id, part: part, sizes: make(map[int]int64), offsets: make(map[int]int64)}
+		a.transfers[id] = t
+	}
+	t.last = now
+
+	if m.Manifest != nil {
+		if t.manifest != nil {
+			return nil
+		}
+		if m.Manifest.Size > MaxFileSize {
+			return a.finish(t, now, fmt.Errorf("file is %d bytes, more than the %d byte limit", m.Manifest.Size, int64(MaxFileSize)))
+		}
+		if m.Manifest.Total > MaxChunks {
+			return a.finish(t, now, fmt.Errorf("file is in %d chunks, more than the %d chunk limit", m.Manifest.Total, MaxChunks))
+		}
+		t.manifest = m.Manifest
+		for seq := range t.sizes {
+			if err := t.check(seq, t.offsets[seq], t.sizes[seq]); err != nil {
+				return a.finish(t, now, err)
+			}
+		}
+	} else {
+		if _, dup := t.sizes[m.Seq]; dup {
+			return nil
+		}
+		n := int64(len(m.Data))
+		if m.Offset+n > MaxFileSize {
+			return a.finish(t, now, fmt.Errorf("chunk %d is beyond the %d byte limit", m.Seq, int64(MaxFileSize)))
+		}
+		if t.manifest != nil {
+			if err := t.check(m.Seq, m.Offset, n); err != nil {
+				return a.finish(t, now, err)
+			}
+		} else if len(t.sizes) >= MaxEarlyChunks {
+			return a.finish(t, now, a.incomplete(t, "too many chunks before the manifest"))
+		}
+		if _, err := t.part.WriteAt(m.Data, m.Offset); err != nil {
+			return a.finish(t, now, fmt.Errorf("failed to write chunk %d: %v", m.Seq, err))
+		}
+		t.sizes[m.Seq] = n
+		t.offsets[m.Seq] = m.Offset
+	}
+
+	if t.manifest != nil && len(t.sizes) == t.manifest.Total {
+		return a.finish(t, now, a.complete(t))
+	}
+	return nil
+}
+
+// check validates a chunk against the manifest.
+func (t *transfer) check(seq int, off, n int64) error {
+	m := t.manifest
+	if seq >= m.Total {
+		return fmt.Errorf("chunk %d of %d is out of range", seq, m.Total)
+	}
+	want := min(int64(m.ChunkSize), m.Size-int64(seq)*int64(m.ChunkSize))
+	if off != int64(seq)*int64(m.ChunkSize) || n != want {
+		return fmt.Errorf("chunk %d does not match the manifest", seq)
+	}
+	return nil
+}
+
+// complete verifies the part file and moves it into place.
+func (a *Assembler) complete(t *transfer) error {
+	if err := t.part.Truncate(t.manifest.Size); err != nil {
+		return err
+	}
+	if _, err := t.part.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, t.part); err != nil {
+		return err
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != t.manifest.SHA256 {
+		return fmt.Errorf("checksum mismatch: got %s, manifest has %s", sum, t.manifest.SHA256)
+	}
+	// CreateTemp makes the part file private; the result is a normal file.
+	if err := t.part.Chmod(0644); err != nil {
+		return err
+	}
+	if err := t.part.Close(); err != nil {
+		return err
+	}
+	dest := a.destination(t.manifest)
+	if err := os.Rename(t.part.Name(), dest); err != nil {
+		return err
+	}
+	t.part, t.path = nil, dest
+	return nil
+}
+
+// destination picks a path in the directory for the file, without
+// overwriting an existing one.
+func (a *Assembler) destination(m *Manifest) string {
+	name := filepath.Base(filepath.Clean("/" + m.Name))
+	if name == "/" || name == "." || strings.HasPrefix(name, ".") {
+		name = m.ID
+	}
+	dest := filepath.Join(a.dir, name)
+	if _, err := os.Stat(dest); err == nil {
+		ext := filepath.Ext(name)
+		dest = filepath.Join(a.dir, strings.TrimSuffix(name, ext)+"."+m.ID[:8]+ext)
+	}
+	return dest
+}
+
+// finish ends a transfer at now; err is nil when the file was written.
+func (a *Assembler) finish(t *transfer, now time.Time, err error) *Result {
+	delete(a.transfers, t.id)
+	a.finished[t.id] = now
+	if t.part != nil {
+		t.part.Close()
+		os.Remove(t.part.Name())
+	}
+	return &Result{ID: t.id, Manifest: t.manifest, Path: t.path, Received: len(t.sizes), Err: err}
+}
+
+// Expire abandons transfers that have received nothing for the timeout,
+// and forgets transfers that ended longer ago than that.
+func (a *Assembler) Expire(now time.Time) []Result {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for id, at := range a.finished {
+		if now.Sub(at) >= a.timeout {
+			delete(a.finished, id)
+		}
+	}
+	var out []Result
+	for _, t := range a.transfers {
+		if now.Sub(t.last) >= a.timeout {
+			out = append(out, *a.finish(t, now, a.incomplete(t, "timed out")))
+		}
+	}
+	return out
+}
+
+// Close abandons every transfer still in progress.
+func (a *Assembler) Close() []Result {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now()
+	var out []Result
+	for _, t := range a.transfers {
+		out = append(out, *a.finish(t, now, a.incomplete(t, "incomplete")))
+	}
+	return out
+}
+
+func (a *Assembler) incomplete(t *transfer, why string) error {
+	if t.manifest == nil {
+		return fmt.Errorf("%s: %d chunk(s) received, manifest missing", why, len(t.sizes))
+	}
+	return fmt.Errorf("%s: %d of %d chunk(s) received", why, len(t.sizes), t.manifest.Total)
+}
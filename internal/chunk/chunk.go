@@ -0,0 +1,186 @@
+// Package chunk splits files too large for one message into a manifest and
+// sequenced chunks, and reassembles them on the subscriber side.
+package chunk
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Messages start with magic, a version and a kind:
+//
+//	manifest: JSON Manifest
+//	chunk:    transferID(16) uvarint(seq) uvarint(offset) data
+//
+// Chunks carry their offset so they can be written out even when they
+// arrive before the manifest.
+var magic = []byte{0xc3, 'M', 'C'}
+
+const (
+	version = 1
+
+	kindManifest = 1
+	kindChunk    = 2
+
+	idSize = 16
+)
+
+// DefaultSize is the chunk size used when none is given.
+const DefaultSize = 256 << 10
+
+// MaxHeaderSize is the longest a chunk message is without its data.
+const MaxHeaderSize = 3 + 2 + idSize + 2*binary.MaxVarintLen64
+
+// ErrCorrupt means a chunk message could not be parsed.
+var ErrCorrupt = errors.New("corrupt chunk message")
+
+// Manifest describes a chunked file. It is published before the chunks.
+type Manifest struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Size      int64  `json:"size"`
+	ChunkSize int    `json:"chunk_size"`
+	Total     int    `json:"total"`
+	SHA256    string `json:"sha256"`
+}
+
+// NewManifest hashes the file at path and describes it in chunks of
+// chunkSize bytes.
+func NewManifest(path string, chunkSize int) (Manifest, error) {
+	if chunkSize <= 0 {
+		return Manifest{}, fmt.Errorf("chunk size must be positive, got %d", chunkSize)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return Manifest{}, err
+	}
+	id := make([]byte, idSize)
+	if _, err := rand.Read(id); err != nil {
+		return Manifest{}, err
+	}
+	return Manifest{
+		ID:        hex.EncodeToString(id),
+		Name:      filepath.Base(path),
+		Size:      size,
+		ChunkSize: chunkSize,
+		Total:     max(1, int((size+int64(chunkSize)-1)/int64(chunkSize))),
+		SHA256:    hex.EncodeToString(h.Sum(nil)),
+	}, nil
+}
+
+func (m Manifest) validate() error {
+	id, err := hex.DecodeString(m.ID)
+	switch {
+	case err != nil || len(id) != idSize:
+		return fmt.Errorf("invalid transfer id %q", m.ID)
+	case m.Size < 0 || m.ChunkSize <= 0 || m.Total <= 0:
+		return fmt.Errorf("invalid manifest for %s", m.ID)
+	case int64(m.Total) != max(1, (m.Size+int64(m.ChunkSize)-1)/int64(m.ChunkSize)):
+		return fmt.Errorf("manifest %s: %d chunks do not match size %d", m.ID, m.Total, m.Size)
+	}
+	return nil
+}
+
+// Encode returns the manifest message.
+func (m Manifest) Encode() []byte {
+	body, _ := json.Marshal(m)
+	return append(header(kindManifest), body...)
+}
+
+// Chunk reads chunk seq of the file from r and returns the chunk message.
+func (m Manifest) Chunk(r io.ReaderAt, seq int) ([]byte, error) {
+	if seq < 0 || seq >= m.Total {
+		return nil, fmt.Errorf("chunk %d out of range", seq)
+	}
+	off := int64(seq) * int64(m.ChunkSize)
+	n := min(int64(m.ChunkSize), m.Size-off)
+	id, _ := hex.DecodeString(m.ID)
+
+	out := header(kindChunk)
+	out = append(out, id...)
+	out = binary.AppendUvarint(out, uint64(seq))
+	out = binary.AppendUvarint(out, uint64(off))
+	start := len(out)
+	out = append(out, make([]byte, n)...)
+	if _, err := r.ReadAt(out[start:], off); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return out, nil
+}
+
+func header(kind byte) []byte {
+	return append(append([]byte{}, magic...), version, kind)
+}
+
+// Message is a parsed manifest or chunk message.
+type Message struct {
+	// Manifest is set for manifest messages.
+	Manifest *Manifest
+	// ID, Seq, Offset and Data are set for chunk messages.
+	ID     string
+	Seq    int
+	Offset int64
+	Data   []byte
+}
+
+// Is reports whether b looks like a manifest or chunk message.
+func Is(b []byte) bool {
+	return len(b) > len(magic)+2 && bytes.HasPrefix(b, magic)
+}
+
+// Parse decodes a message produced by Manifest.Encode or Manifest.Chunk.
+func Parse(b []byte) (Message, error) {
+	if !Is(b) || b[len(magic)] != version {
+		return Message{}, ErrCorrupt
+	}
+	body := b[len(magic)+2:]
+	switch b[len(magic)+1] {
+	case kindManifest:
+		var m Manifest
+		if err := json.Unmarshal(body, &m); err != nil {
+			return Message{}, fmt.Errorf("%w: %v", ErrCorrupt, err)
+		}
+		if err := m.validate(); err != nil {
+			return Message{}, fmt.Errorf("%w: %v", ErrCorrupt, err)
+		}
+		return Message{Manifest: &m}, nil
+	case kindChunk:
+		if len(body) < idSize+1 {
+			return Message{}, ErrCorrupt
+		}
+		rest := body[idSize:]
+		seq, n := binary.Uvarint(rest)
+		if n <= 0 || seq > 1<<31 {
+			return Message{}, ErrCorrupt
+		}
+		rest = rest[n:]
+		off, n := binary.Uvarint(rest)
+		if n <= 0 || off > 1<<62 {
+			return Message{}, ErrCorrupt
+		}
+		return Message{
+			ID:     hex.EncodeToString(body[:idSize]),
+			Seq:    int(seq),
+			Offset: int64(off),
+			Data:   rest[n:],
+		}, nil
+	default:
+		return Message{}, ErrCorrupt
+	}
+}
@@ -0,0 +1,176 @@
+package chunk
+
+import (
+	"bytes"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeFile writes size random bytes to a temp file.
+func writeFile(t *testing.T, name string, size int) (string, []byte) {
+	data := make([]byte, size)
+	_, err := rand.Read(data)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, data, 0600))
+	return path, data
+}
+
+// messages returns the manifest and chunk messages for the file at path.
+func messages(t *testing.T, path string, chunkSize int) (Manifest, [][]byte) {
+	m, err := NewManifest(path, chunkSize)
+	require.NoError(t, err)
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	msgs := [][]byte{m.Encode()}
+	for seq := range m.Total {
+		c, err := m.Chunk(f, seq)
+		require.NoError(t, err)
+		msgs = append(msgs, c)
+	}
+	return m, msgs
+}
+
+func parse(t *testing.T, b []byte) Message {
+	msg, err := Parse(b)
+	require.NoError(t, err)
+	return msg
+}
+
+// TestManifest tests chunk counts and parsing of both message kinds.
+func TestManifest(t *testing.T) {
+	path, data := writeFile(t, "big.bin", 2500)
+	m, msgs := messages(t, path, 1000)
+	require.Equal(t, "big.bin", m.Name)
+	require.EqualValues(t, 2500, m.Size)
+	require.Equal(t, 3, m.Total)
+	require.Len(t, msgs, 4)
+
+	got := parse(t, msgs[0])
+	require.Equal(t, m, *got.Manifest)
+
+	last := parse(t, msgs[3])
+	require.Equal(t, m.ID, last.ID)
+	require.Equal(t, 2, last.Seq)
+	require.EqualValues(t, 2000, last.Offset)
+	require.Equal(t, data[2000:], last.Data)
+
+	empty, _ := writeFile(t, "empty", 0)
+	m, msgs = messages(t, empty, 1000)
+	require.Equal(t, 1, m.Total)
+	require.Empty(t, parse(t, msgs[1]).Data)
+
+	for _, bad := range [][]byte{[]byte("hello"), append(header(kindManifest), "{}"...), header(kindChunk), append(header(9), 1)} {
+		_, err := Parse(bad)
+		require.ErrorIs(t, err, ErrCorrupt)
+	}
+	require.False(t, Is([]byte("plain")))
+}
+
+// TestAssemble tests rebuilding a file from chunks arriving out of order,
+// before the manifest, and duplicated.
+func TestAssemble(t *testing.T) {
+	path, data := writeFile(t, "big.bin", 5000)
+	m, msgs := messages(t, path, 1024)
+	dir := filepath.Join(t.TempDir(), "out")
+	a, err := NewAssembler(dir, time.Minute)
+	require.NoError(t, err)
+
+	now := time.Now()
+	order := []int{3, 1, 0, 3, 5, 2}
+	for _, i := range order {
+		require.Nil(t, a.Add(parse(t, msgs[i]), now))
+	}
+	r := a.Add(parse(t, msgs[4]), now)
+	require.NotNil(t, r)
+	require.NoError(t, r.Err)
+	require.Equal(t, filepath.Join(dir, "big.bin"), r.Path)
+	require.Equal(t, m.Total, r.Received)
+
+	got, err := os.ReadFile(r.Path)
+	require.NoError(t, err)
+	require.Equal(t, data, got)
+
+	// late duplicates of a finished transfer are ignored, and the transfer
+	// is forgotten once the timeout has passed
+	require.Nil(t, a.Add(parse(t, msgs[1]), now))
+	require.Empty(t, a.Expire(now.Add(time.Minute)))
+	require.Empty(t, a.finished)
+
+	// the same name again does not overwrite the first file
+	_, msgs2 := messages(t, path, 4096)
+	var r2 *Result
+	for _, b := range msgs2 {
+		r2 = a.Add(parse(t, b), now)
+	}
+	require.NotNil(t, r2)
+	require.NoError(t, r2.Err)
+	require.NotEqual(t, r.Path, r2.Path)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+}
+
+// TestAssembleFailures tests checksum mismatches, chunks that do not fit
+// the manifest, and timeouts.
+func TestAssembleFailures(t *testing.T) {
+	path, _ := writeFile(t, "f.bin", 3000)
+	dir := t.TempDir()
+	a, err := NewAssembler(dir, time.Minute)
+	require.NoError(t, err)
+	now := time.Now()
+
+	_, msgs := messages(t, path, 1000)
+	tampered := parse(t, msgs[2])
+	tampered.Data = bytes.Repeat([]byte{'x'}, len(tampered.Data))
+	a.Add(parse(t, msgs[0]), now)
+	a.Add(parse(t, msgs[1]), now)
+	a.Add(tampered, now)
+	r := a.Add(parse(t, msgs[3]), now)
+	require.ErrorContains(t, r.Err, "checksum mismatch")
+
+	_, msgs = messages(t, path, 1000)
+	wrong := parse(t, msgs[1])
+	wrong.Offset = 10
+	a.Add(parse(t, msgs[0]), now)
+	r = a.Add(wrong, now)
+	require.ErrorContains(t, r.Err, "does not match the manifest")
+
+	_, msgs = messages(t, path, 1000)
+	a.Add(parse(t, msgs[0]), now)
+	a.Add(parse(t, msgs[1]), now)
+	require.Empty(t, a.Expire(now.Add(30*time.Second)))
+	expired := a.Expire(now.Add(time.Minute))
+	require.Len(t, expired, 1)
+	require.Equal(t, "f.bin", expired[0].Name())
+	require.ErrorContains(t, expired[0].Err, "timed out: 1 of 3")
+
+	_, msgs = messages(t, path, 1000)
+	a.Add(parse(t, msgs[2]), now)
+	closed := a.Close()
+	require.Len(t, closed, 1)
+	require.ErrorContains(t, closed[0].Err, "manifest missing")
+
+	// chunks without a manifest are held up to a limit
+	_, msgs = messages(t, path, 1000)
+	early := parse(t, msgs[1])
+	for seq := range MaxEarlyChunks {
+		early.Seq = seq
+		require.Nil(t, a.Add(early, now))
+	}
+	early.Seq = MaxEarlyChunks
+	r = a.Add(early, now)
+	require.ErrorContains(t, r.Err, "too many chunks before the manifest")
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries, "part files are removed")
+}
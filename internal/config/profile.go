@@ -0,0 +1,125 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ProfileFileName is the name of the profile file, kept next to auth.yml.
+const ProfileFileName = "config.yml"
+
+// DefaultProfile is used when no profile is selected.
+const DefaultProfile = "default"
+
+// Profile holds user settings that would otherwise be repeated as flags.
+// Flags always take precedence over the profile.
+type Profile struct {
+	// ServiceURLs are the proxies to request sessions from, tried healthy
+	// first when one is down.
+	ServiceURLs []string `yaml:"service_urls,omitempty"`
+	// SessionTransport is "http" (default) or "grpc".
+	SessionTransport string `yaml:"session_transport,omitempty"`
+	// SessionAddress is the host:port of the gRPC SessionService.
+	SessionAddress string `yaml:"session_address,omitempty"`
+	// SessionPlaintext connects to a remote SessionService without TLS.
+	SessionPlaintext bool `yaml:"session_plaintext,omitempty"`
+	// Region and Protocol are hints sent with session requests.
+	Region   string `yaml:"region,omitempty"`
+	Protocol string `yaml:"protocol,omitempty"`
+	// TLS secures node connections.
+	TLS TLSProfile `yaml:"tls,omitempty"`
+	// HTTP configures requests to the proxy, Auth0 and GitHub.
+	HTTP HTTPProfile `yaml:"http,omitempty"`
+	// GRPC tunes node connections.
+	GRPC GRPCProfile `yaml:"grpc,omitempty"`
+}
+
+// GRPCProfile tunes gRPC connections to nodes. Zero values keep the
+// defaults; a negative Keepalive turns keepalive pings off.
+type GRPCProfile struct {
+	Keepalive        time.Duration `yaml:"keepalive,omitempty"`
+	KeepaliveTimeout time.Duration `yaml:"keepalive_timeout,omitempty"`
+	// MaxRecvSize overrides the cap derived from the token's max message size.
+	MaxRecvSize    int           `yaml:"max_recv_size,omitempty"`
+	ConnectTimeout time.Duration `yaml:"connect_timeout,omitempty"`
+	BackoffBase    time.Duration `yaml:"backoff_base,omitempty"`
+	BackoffMax     time.Duration `yaml:"backoff_max,omitempty"`
+}
+
+// HTTPProfile mirrors the --http flags. Proxies come from HTTPS_PROXY,
+// HTTP_PROXY and NO_PROXY.
+type HTTPProfile struct {
+	CAFile  string        `yaml:"ca_file,omitempty"`
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// TLSProfile mirrors the --tls flags.
+type TLSProfile struct {
+	// Enabled forces TLS to every node; nodes whose transport asks for TLS
+	// use it regardless.
+	Enabled    bool   `yaml:"enabled,omitempty"`
+	CAFile     string `yaml:"ca_file,omitempty"`
+	CertFile   string `yaml:"cert_file,omitempty"`
+	KeyFile    string `yaml:"key_file,omitempty"`
+	ServerName string `yaml:"server_name,omitempty"`
+}
+
+// ProfileFile is the on-disk layout of config.yml:
+//
+//	profile: staging
+//	profiles:
+//	  staging:
+//	    service_urls: [http://eu1.example.com:8080, http://eu2.example.com:8080]
+//	    session_transport: grpc
+//	    session_address: proxy.example.com:50051
+//	    region: eu-west
+//	    tls:
+//	      ca_file: /etc/mump2p/ca.pem
+type ProfileFile struct {
+	Profile  string             `yaml:"profile,omitempty"`
+	Profiles map[string]Profile `yaml:"profiles"`
+}
+
+// LoadProfile reads the named profile from config.yml in dir. An empty name
+// selects the file's active profile, falling back to "default". A missing
+// file or missing default profile yields an empty profile; asking for a
+// profile that does not exist is an error.
+func LoadProfile(dir, name string) (*Profile, error) {
+	path := filepath.Join(dir, ProfileFileName)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if name != "" && name != DefaultProfile {
+			return nil, fmt.Errorf("profile %q not found: %s does not exist", name, path)
+		}
+		return &Profile{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	var f ProfileFile
+	if err := yaml.UnmarshalStrict(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	explicit := name != ""
+	if name == "" {
+		name = f.Profile
+	}
+	if name == "" {
+		name = DefaultProfile
+	}
+	p, ok := f.Profiles[name]
+	if !ok {
+		if explicit || f.Profile != "" {
+			return nil, fmt.Errorf("profile %q not found in %s", name, path)
+		}
+		return &Profile{}, nil
+	}
+	return &p, nil
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestLoadProfile tests profile selection and fallbacks.
+func TestLoadProfile(t *testing.T) {
+	dir := t.TempDir()
+
+	p, err := LoadProfile(dir, "")
+	require.NoError(t, err)
+	require.Equal(t, &Profile{}, p, "missing file yields an empty profile")
+
+	_, err = LoadProfile(dir, "staging")
+	require.Error(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ProfileFileName), []byte(`
+profile: staging
+profiles:
+  default:
+    region: us-east
+  staging:
+    session_transport: grpc
+    session_address: localhost:50051
+    protocol: gossipsub
+`), 0600))
+
+	p, err = LoadProfile(dir, "")
+	require.NoError(t, err)
+	require.Equal(t, "grpc", p.SessionTransport)
+	require.Equal(t, "localhost:50051", p.SessionAddress)
+	require.Equal(t, "gossipsub", p.Protocol)
+
+	p, err = LoadProfile(dir, "default")
+	require.NoError(t, err)
+	require.Equal(t, "us-east", p.Region)
+
+	_, err = LoadProfile(dir, "prod")
+	require.ErrorContains(t, err, `profile "prod" not found`)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ProfileFileName), []byte("profiles:\n  default:\n    unknown_key: 1\n"), 0600))
+	_, err = LoadProfile(dir, "")
+	require.Error(t, err, "unknown keys are rejected")
+}
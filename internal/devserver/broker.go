@@ -0,0 +1,224 @@
+package devserver
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/getoptimum/mump2p-cli/internal/entities"
+	pb "github.com/getoptimum/mump2p-cli/proto"
+)
+
+// subscription is a single topic subscription held by a node stream.
+type subscription struct {
+	id       uint64
+	node     *Node
+	clientID string
+	topic    string
+	out      chan *pb.Response
+	dropped  atomic.Int64
+}
+
+// traceRecord tracks the delivery of one published message for the tracer.
+type traceRecord struct {
+	Topic      string
+	Published  time.Time
+	Delivered  time.Time
+	PeersSeen  map[string]struct{}
+	Duplicates int
+	BytesMoved uint64
+}
+
+// Broker fans published messages out to every subscription on every node,
+// which stands in for the mesh between real nodes.
+type Broker struct {
+	mu     sync.RWMutex
+	nextID uint64
+	seq    uint64
+	subs   map[string]map[uint64]*subscription
+
+	traceMu sync.Mutex
+	traces  map[string]*traceRecord
+	order   []string
+	resetAt time.Time
+}
+
+const maxTraceRecords = 10000
+
+// NewBroker creates an empty broker.
+func NewBroker() *Broker {
+	return &Broker{
+		subs:    make(map[string]map[uint64]*subscription),
+		traces:  make(map[string]*traceRecord),
+		resetAt: time.Now(),
+	}
+}
+
+func (b *Broker) subscribe(n *Node, clientID, topic string, out chan *pb.Response) *subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextID++
+	s := &subscription{id: b.nextID, node: n, clientID: clientID, topic: topic, out: out}
+	if b.subs[topic] == nil {
+		b.subs[topic] = make(map[uint64]*subscription)
+	}
+	b.subs[topic][s.id] = s
+	return s
+}
+
+func (b *Broker) unsubscribe(s *subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.subs[s.topic], s.id)
+	if len(b.subs[s.topic]) == 0 {
+		delete(b.subs, s.topic)
+	}
+}
+
+// Publish delivers data to all subscribers of topic and returns the message.
+func (b *Broker) Publish(source *Node, topic string, data []byte) *entities.P2PMessage {
+	b.mu.Lock()
+	b.seq++
+	seq := b.seq
+	targets := make([]*subscription, 0, len(b.subs[topic]))
+	for _, s := range b.subs[topic] {
+		if s.node.partition.Load() != source.partition.Load() {
+			continue
+		}
+		targets = append(targets, s)
+	}
+	b.mu.Unlock()
+
+	msg := &entities.P2PMessage{
+		SourceNodeID: source.PeerID(),
+		Topic:        topic,
+		MessageID:    messageID(seq, data),
+		Message:      data,
+	}
+	b.recordPublish(msg)
+
+	encoded, err := msg.Marshal()
+	if err != nil {
+		return msg
+	}
+	for _, s := range targets {
+		resp := &pb.Response{Command: pb.ResponseType_Message, Data: encoded}
+		if s.node.deliver(s, resp) {
+			b.recordDelivery(msg.MessageID, s.node.PeerID(), len(data))
+			s.node.deliver(s, deliveryTrace(msg, source, s.node))
+		}
+	}
+	return msg
+}
+
+// Topics returns the topics the client currently has subscriptions on. An
+// empty clientID returns every active topic.
+func (b *Broker) Topics(clientID string) []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	var topics []string
+	for t, subs := range b.subs {
+		for _, s := range subs {
+			if clientID == "" || s.clientID == clientID {
+				topics = append(topics, t)
+				break
+			}
+		}
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// Subscribers returns the number of active subscriptions on topic.
+func (b *Broker) Subscribers(topic string) int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subs[topic])
+}
+
+// NodeTopics returns the topics subscribed through the given node.
+func (b *Broker) NodeTopics(n *Node) []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	var topics []string
+	for t, subs := range b.subs {
+		for _, s := range subs {
+			if s.node == n {
+				topics = append(topics, t)
+				break
+			}
+		}
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+func (b *Broker) recordPublish(msg *entities.P2PMessage) {
+	b.traceMu.Lock()
+	defer b.traceMu.Unlock()
+	b.traces[msg.MessageID] = &traceRecord{
+		Topic:     msg.Topic,
+		Published: time.Now(),
+		PeersSeen: make(map[string]struct{}),
+	}
+	b.order = append(b.order, msg.MessageID)
+	if len(b.order) > maxTraceRecords {
+		delete(b.traces, b.order[0])
+		b.order = b.order[1:]
+	}
+}
+
+func (b *Broker) recordDelivery(id, peer string, size int) {
+	b.traceMu.Lock()
+	defer b.traceMu.Unlock()
+	r, ok := b.traces[id]
+	if !ok {
+		return
+	}
+	if _, seen := r.PeersSeen[peer]; seen {
+		r.Duplicates++
+	} else {
+		r.PeersSeen[peer] = struct{}{}
+	}
+	if r.Delivered.IsZero() {
+		r.Delivered = time.Now()
+	}
+	r.BytesMoved += uint64(size)
+}
+
+// resetTraces clears tracer statistics.
+func (b *Broker) resetTraces() {
+	b.traceMu.Lock()
+	defer b.traceMu.Unlock()
+	b.traces = make(map[string]*traceRecord)
+	b.order = nil
+	b.resetAt = time.Now()
+}
+
+func messageID(seq uint64, data []byte) string {
+	h := sha256.New()
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], seq)
+	h.Write(buf[:])
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// deliveryTrace builds the MessageTraceMumP2P event a node emits when it
+// hands a message to a local subscriber.
+func deliveryTrace(msg *entities.P2PMessage, from, to *Node) *pb.Response {
+	data, _ := json.Marshal(map[string]interface{}{
+		"type":         "DELIVER_MESSAGE",
+		"messageID":    msg.MessageID,
+		"topic":        msg.Topic,
+		"peerID":       to.PeerID(),
+		"receivedFrom": from.PeerID(),
+		"timestamp":    time.Now().UnixNano(),
+	})
+	return &pb.Response{Command: pb.ResponseType_MessageTraceMumP2P, Data: data}
+}
@@ -0,0 +1,332 @@
+package devserver
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// LinkFaults describes the network conditions between a node and its
+// clients. Every response a node sends (publish acks, messages and traces)
+// passes through its link.
+type LinkFaults struct {
+	Latency       time.Duration
+	Jitter        time.Duration
+	DropRate      float64
+	DuplicateRate float64
+}
+
+// Chaos actions that can be scheduled in a ChaosPlan.
+const (
+	ActionSetLink   = "set_link"
+	ActionClear     = "clear_link"
+	ActionReset     = "reset"
+	ActionKill      = "kill"
+	ActionRestart   = "restart"
+	ActionPartition = "partition"
+	ActionHeal      = "heal"
+)
+
+// ChaosEvent is a single scheduled fault. Node is 1-based; 0 targets every
+// node. Groups lists 1-based node indexes per side of a partition.
+type ChaosEvent struct {
+	At     time.Duration
+	Action string
+	Node   int
+	Link   LinkFaults
+	Groups [][]int
+}
+
+// ChaosPlan is a set of initial link faults plus a timeline of events.
+type ChaosPlan struct {
+	// Links maps a 1-based node index (0 for all nodes) to its faults.
+	Links  map[int]LinkFaults
+	Events []ChaosEvent
+}
+
+// SetFaults replaces the link faults of the node.
+func (n *Node) SetFaults(f LinkFaults) {
+	n.faults.Store(&f)
+}
+
+// ClearFaults restores a perfect link.
+func (n *Node) ClearFaults() {
+	n.faults.Store(nil)
+}
+
+// ResetStreams aborts every open stream on the node with codes.Unavailable.
+func (n *Node) ResetStreams() {
+	n.streams.Range(func(key, _ interface{}) bool {
+		if v, ok := n.streams.LoadAndDelete(key); ok {
+			close(v.(chan struct{}))
+		}
+		return true
+	})
+}
+
+// Kill stops the node abruptly; its address refuses connections until
+// Restart is called.
+func (n *Node) Kill() {
+	n.Stop()
+}
+
+// Restart brings a killed node back on its previous address.
+func (n *Node) Restart() error {
+	n.mu.Lock()
+	killed := n.killed
+	n.mu.Unlock()
+	if !killed {
+		return nil
+	}
+	return n.serve()
+}
+
+// IsUp reports whether the node is accepting connections.
+func (n *Node) IsUp() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return !n.killed
+}
+
+// Partition splits the mesh so messages only reach subscribers on nodes in
+// the publisher's group. Nodes are 1-based; unlisted nodes form one more
+// group of their own.
+func (s *Server) Partition(groups ...[]int) error {
+	nodes := s.Nodes()
+	for _, n := range nodes {
+		n.partition.Store(0)
+	}
+	for g, members := range groups {
+		for _, idx := range members {
+			if idx < 1 || idx > len(nodes) {
+				return fmt.Errorf("node %d out of range (1-%d)", idx, len(nodes))
+			}
+			nodes[idx-1].partition.Store(int32(g + 1))
+		}
+	}
+	return nil
+}
+
+// Heal removes any partition.
+func (s *Server) Heal() {
+	for _, n := range s.Nodes() {
+		n.partition.Store(0)
+	}
+}
+
+// ApplyChaos installs the plan's link faults and runs its events in the
+// background until ctx is done or the last event has fired.
+func (s *Server) ApplyChaos(ctx context.Context, plan *ChaosPlan) error {
+	for idx, f := range plan.Links {
+		targets, err := s.targets(idx)
+		if err != nil {
+			return err
+		}
+		for _, n := range targets {
+			n.SetFaults(f)
+		}
+	}
+	for _, ev := range plan.Events {
+		if _, err := s.targets(ev.Node); err != nil {
+			return err
+		}
+		switch ev.Action {
+		case ActionSetLink, ActionClear, ActionReset, ActionKill, ActionRestart, ActionPartition, ActionHeal:
+		default:
+			return fmt.Errorf("unknown chaos action %q", ev.Action)
+		}
+	}
+
+	events := append([]ChaosEvent(nil), plan.Events...)
+	sort.SliceStable(events, func(i, j int) bool { return events[i].At < events[j].At })
+	go func() {
+		start := time.Now()
+		for _, ev := range events {
+			select {
+			case <-time.After(time.Until(start.Add(ev.At))):
+			case <-ctx.Done():
+				return
+			}
+			if err := s.RunEvent(ev); err != nil {
+				fmt.Printf("dev-server: chaos %s on node %d: %v\n", ev.Action, ev.Node, err)
+			}
+		}
+	}()
+	return nil
+}
+
+// RunEvent applies a single chaos event immediately.
+func (s *Server) RunEvent(ev ChaosEvent) error {
+	switch ev.Action {
+	case ActionPartition:
+		return s.Partition(ev.Groups...)
+	case ActionHeal:
+		s.Heal()
+		return nil
+	}
+	targets, err := s.targets(ev.Node)
+	if err != nil {
+		return err
+	}
+	for _, n := range targets {
+		switch ev.Action {
+		case ActionSetLink:
+			n.SetFaults(ev.Link)
+		case ActionClear:
+			n.ClearFaults()
+		case ActionReset:
+			n.ResetStreams()
+		case ActionKill:
+			n.Kill()
+		case ActionRestart:
+			if err := n.Restart(); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown chaos action %q", ev.Action)
+		}
+	}
+	return nil
+}
+
+func (s *Server) targets(idx int) ([]*Node, error) {
+	nodes := s.Nodes()
+	if idx == 0 {
+		return nodes, nil
+	}
+	if idx < 0 || idx > len(nodes) {
+		return nil, fmt.Errorf("node %d out of range (1-%d)", idx, len(nodes))
+	}
+	return nodes[idx-1 : idx], nil
+}
+
+// chaosFile is the YAML layout accepted by LoadChaosFile:
+//
+//	links:
+//	  - node: 2
+//	    latency: 50ms
+//	    jitter: 20ms
+//	    drop_rate: 0.1
+//	    duplicate_rate: 0.05
+//	events:
+//	  - at: 10s
+//	    action: kill
+//	    node: 1
+//	  - at: 20s
+//	    action: restart
+//	    node: 1
+//	  - at: 30s
+//	    action: partition
+//	    groups: [[1, 2], [3]]
+//	  - at: 40s
+//	    action: heal
+type chaosFile struct {
+	Links  []chaosLink  `yaml:"links"`
+	Events []chaosEntry `yaml:"events"`
+}
+
+type chaosLink struct {
+	Node          int     `yaml:"node"`
+	Latency       string  `yaml:"latency"`
+	Jitter        string  `yaml:"jitter"`
+	DropRate      float64 `yaml:"drop_rate"`
+	DuplicateRate float64 `yaml:"duplicate_rate"`
+}
+
+type chaosEntry struct {
+	chaosLink `yaml:",inline"`
+	At        string  `yaml:"at"`
+	Action    string  `yaml:"action"`
+	Groups    [][]int `yaml:"groups"`
+}
+
+// LoadChaosFile parses a YAML chaos plan.
+func LoadChaosFile(path string) (*ChaosPlan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chaos file: %w", err)
+	}
+	return ParseChaos(data)
+}
+
+// ParseChaos parses a YAML chaos plan from memory.
+func ParseChaos(data []byte) (*ChaosPlan, error) {
+	var f chaosFile
+	if err := yaml.UnmarshalStrict(data, &f); err != nil {
+		return nil, fmt.Errorf("invalid chaos file: %w", err)
+	}
+	plan := &ChaosPlan{Links: make(map[int]LinkFaults)}
+	for _, l := range f.Links {
+		lf, err := l.faults()
+		if err != nil {
+			return nil, err
+		}
+		plan.Links[l.Node] = lf
+	}
+	for _, e := range f.Events {
+		at, err := parseDuration(e.At)
+		if err != nil {
+			return nil, fmt.Errorf("event %q: invalid at: %w", e.Action, err)
+		}
+		lf, err := e.faults()
+		if err != nil {
+			return nil, err
+		}
+		plan.Events = append(plan.Events, ChaosEvent{At: at, Action: e.Action, Node: e.Node, Link: lf, Groups: e.Groups})
+	}
+	return plan, nil
+}
+
+func (l chaosLink) faults() (LinkFaults, error) {
+	latency, err := parseDuration(l.Latency)
+	if err != nil {
+		return LinkFaults{}, fmt.Errorf("node %d: invalid latency: %w", l.Node, err)
+	}
+	jitter, err := parseDuration(l.Jitter)
+	if err != nil {
+		return LinkFaults{}, fmt.Errorf("node %d: invalid jitter: %w", l.Node, err)
+	}
+	if l.DropRate < 0 || l.DropRate > 1 || l.DuplicateRate < 0 || l.DuplicateRate > 1 {
+		return LinkFaults{}, fmt.Errorf("node %d: rates must be between 0 and 1", l.Node)
+	}
+	return LinkFaults{Latency: latency, Jitter: jitter, DropRate: l.DropRate, DuplicateRate: l.DuplicateRate}, nil
+}
+
+func parseDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// lockedRand is a math/rand source safe for concurrent use, so fault
+// injection stays reproducible for a given seed.
+type lockedRand struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+func newLockedRand(seed int64) *lockedRand {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &lockedRand{r: rand.New(rand.NewSource(seed))}
+}
+
+func (l *lockedRand) Float64() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.r.Float64()
+}
+
+func (l *lockedRand) Int63n(n int64) int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.r.Int63n(n)
+}
@@ -0,0 +1,338 @@
+package devserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/getoptimum/mump2p-cli/internal/node"
+	pb "github.com/getoptimum/mump2p-cli/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// streamBufferSize is the per-stream outbound queue. Messages for a
+// subscriber whose queue is full are dropped, as a congested node would.
+const streamBufferSize = 1024
+
+// Node is an in-process CommandStream server.
+type Node struct {
+	pb.UnimplementedCommandStreamServer
+
+	id      string
+	region  string
+	score   float32
+	broker  *Broker
+	tickets *ticketSigner
+
+	faults    atomic.Pointer[LinkFaults]
+	partition atomic.Int32
+	rng       *lockedRand
+	streams   sync.Map // stream id -> chan struct{} closed on reset
+	nextSID   atomic.Uint64
+
+	mu     sync.Mutex
+	addr   string
+	lis    net.Listener
+	server *grpc.Server
+	killed bool
+}
+
+func newNode(index int, addr string, broker *Broker, tickets *ticketSigner, rng *lockedRand) (*Node, error) {
+	n := &Node{
+		id:      fmt.Sprintf("dev-node-%d", index+1),
+		region:  fmt.Sprintf("local-%d", index+1),
+		score:   1 - float32(index)*0.05,
+		broker:  broker,
+		tickets: tickets,
+		rng:     rng,
+		addr:    addr,
+	}
+	if err := n.serve(); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// serve binds the node address and starts the gRPC server. The first call
+// resolves a ":0" port so that restarts come back on the same address.
+func (n *Node) serve() error {
+	lis, err := net.Listen("tcp", n.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", n.addr, err)
+	}
+	// The default keepalive enforcement matches real nodes, so client pings
+	// that are too frequent fail here too.
+	srv := grpc.NewServer()
+	pb.RegisterCommandStreamServer(srv, n)
+
+	n.mu.Lock()
+	n.addr = lis.Addr().String()
+	n.lis = lis
+	n.server = srv
+	n.killed = false
+	n.mu.Unlock()
+
+	go srv.Serve(lis) //nolint:errcheck
+	return nil
+}
+
+// ID returns the node identifier used in tickets.
+func (n *Node) ID() string { return n.id }
+
+// PeerID returns the identifier reported as the message source.
+func (n *Node) PeerID() string { return "12D3KooWDev" + strings.TrimPrefix(n.id, "dev-node-") }
+
+// Addr returns the host:port the node listens on.
+func (n *Node) Addr() string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.addr
+}
+
+// Region returns the region label reported to clients.
+func (n *Node) Region() string { return n.region }
+
+// Stop closes the listener and terminates all open streams.
+func (n *Node) Stop() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.server != nil && !n.killed {
+		n.server.Stop()
+		n.killed = true
+	}
+}
+
+// streamState is the per-stream bookkeeping for ListenCommands.
+type streamState struct {
+	mu   sync.Mutex
+	subs []*subscription
+	out  chan *pb.Response
+}
+
+func (s *streamState) hasSubs() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.subs) > 0
+}
+
+// ListenCommands serves publish and subscribe commands on a bidi stream.
+func (n *Node) ListenCommands(stream pb.CommandStream_ListenCommandsServer) error {
+	ctx := stream.Context()
+	st := &streamState{out: make(chan *pb.Response, streamBufferSize)}
+
+	sid := n.nextSID.Add(1)
+	reset := make(chan struct{})
+	n.streams.Store(sid, reset)
+	defer n.streams.Delete(sid)
+
+	defer func() {
+		st.mu.Lock()
+		for _, s := range st.subs {
+			n.broker.unsubscribe(s)
+		}
+		st.mu.Unlock()
+	}()
+
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			if err := n.handle(req, st); err != nil {
+				recvErr <- err
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case resp := <-st.out:
+			if err := stream.Send(resp); err != nil {
+				return err
+			}
+		case err := <-recvErr:
+			if err != io.EOF {
+				return err
+			}
+			if !st.hasSubs() {
+				return n.flush(stream, st)
+			}
+			// Client half-closed but still subscribed: keep delivering.
+			recvErr = nil
+		case <-reset:
+			return status.Error(codes.Unavailable, "stream reset by node")
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (n *Node) flush(stream pb.CommandStream_ListenCommandsServer, st *streamState) error {
+	for {
+		select {
+		case resp := <-st.out:
+			if err := stream.Send(resp); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+func (n *Node) handle(req *pb.Request, st *streamState) error {
+	switch req.GetCommand() {
+	case node.CommandPublishData:
+		t, err := n.tickets.Verify(req.GetJwtToken(), n.id, req.GetTopic(), "publish")
+		if err != nil {
+			return status.Error(codes.PermissionDenied, err.Error())
+		}
+		msg := n.broker.Publish(n, req.GetTopic(), req.GetData())
+		trace, _ := json.Marshal(map[string]interface{}{
+			"type":      "PUBLISH_MESSAGE",
+			"messageID": msg.MessageID,
+			"topic":     msg.Topic,
+			"peerID":    n.PeerID(),
+			"clientID":  t.ClientID,
+			"size":      len(req.GetData()),
+			"timestamp": time.Now().UnixNano(),
+		})
+		n.reply(st.out, &pb.Response{Command: pb.ResponseType_MessageTraceMumP2P, Data: trace})
+	case node.CommandSubscribeToTopic, node.CommandSubscribeToTopics:
+		for _, topic := range strings.Split(req.GetTopic(), ",") {
+			topic = strings.TrimSpace(topic)
+			if topic == "" {
+				continue
+			}
+			t, err := n.tickets.Verify(req.GetJwtToken(), n.id, topic, "subscribe")
+			if err != nil {
+				return status.Error(codes.PermissionDenied, err.Error())
+			}
+			s := n.broker.subscribe(n, t.ClientID, topic, st.out)
+			st.mu.Lock()
+			st.subs = append(st.subs, s)
+			st.mu.Unlock()
+		}
+	default:
+		return status.Errorf(codes.InvalidArgument, "unknown command %d", req.GetCommand())
+	}
+	return nil
+}
+
+// deliver queues a response for a subscription and reports whether it was
+// accepted.
+func (n *Node) deliver(s *subscription, resp *pb.Response) bool {
+	return n.send(s.out, resp, &s.dropped)
+}
+
+// send pushes a response onto a stream queue through the node's link faults.
+// Dropped responses are counted in dropped when it is not nil.
+func (n *Node) send(out chan *pb.Response, resp *pb.Response, dropped *atomic.Int64) bool {
+	copies, delay, ok := n.linkFaults(dropped)
+	if !ok {
+		return false
+	}
+	if delay <= 0 {
+		ok := enqueue(out, resp, dropped)
+		for i := 1; i < copies; i++ {
+			enqueue(out, resp, dropped)
+		}
+		return ok
+	}
+	for i := 0; i < copies; i++ {
+		time.AfterFunc(delay, func() { enqueue(out, resp, dropped) })
+	}
+	return true
+}
+
+// reply is send for command acknowledgements: the link delay is spent on
+// the calling goroutine, so a half-closed stream is not flushed before the
+// delayed reply is queued.
+func (n *Node) reply(out chan *pb.Response, resp *pb.Response) {
+	copies, delay, ok := n.linkFaults(nil)
+	if !ok {
+		return
+	}
+	time.Sleep(delay)
+	for i := 0; i < copies; i++ {
+		enqueue(out, resp, nil)
+	}
+}
+
+// linkFaults rolls the node's link faults for one response. ok is false
+// when the response is dropped.
+func (n *Node) linkFaults(dropped *atomic.Int64) (copies int, delay time.Duration, ok bool) {
+	f := n.faults.Load()
+	if f == nil {
+		return 1, 0, true
+	}
+	if f.DropRate > 0 && n.rng.Float64() < f.DropRate {
+		if dropped != nil {
+			dropped.Add(1)
+		}
+		return 0, 0, false
+	}
+	copies = 1
+	if f.DuplicateRate > 0 && n.rng.Float64() < f.DuplicateRate {
+		copies = 2
+	}
+	delay = f.Latency
+	if f.Jitter > 0 {
+		delay += time.Duration(n.rng.Int63n(int64(f.Jitter)))
+	}
+	return copies, delay, true
+}
+
+func enqueue(out chan *pb.Response, resp *pb.Response, dropped *atomic.Int64) bool {
+	select {
+	case out <- resp:
+		return true
+	default:
+		if dropped != nil {
+			dropped.Add(1)
+		}
+		return false
+	}
+}
+
+// Health reports synthetic node metrics.
+func (n *Node) Health(ctx context.Context, _ *pb.Void) (*pb.HealthResponse, error) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return &pb.HealthResponse{
+		Status:     true,
+		NodeMode:   "dev",
+		MemoryUsed: float32(ms.Alloc) / float32(ms.Sys) * 100,
+		CpuUsed:    float32(runtime.NumGoroutine()%100) / 10,
+		DiskUsed:   0,
+		P2PAddress: fmt.Sprintf("/ip4/127.0.0.1/tcp/%s/p2p/%s", port(n.Addr()), n.PeerID()),
+		Country:    "Localhost",
+		CountryIso: "LO",
+	}, nil
+}
+
+// ListTopics returns the topics subscribed through this node.
+func (n *Node) ListTopics(ctx context.Context, _ *pb.Void) (*pb.TopicList, error) {
+	return &pb.TopicList{Topics: n.broker.NodeTopics(n)}, nil
+}
+
+func port(addr string) string {
+	_, p, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return p
+}
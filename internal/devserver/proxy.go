@@ -0,0 +1,293 @@
+package devserver
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	sessionTTL     = time.Hour
+	sessionRefresh = 45 * time.Minute
+)
+
+type sessionRequest struct {
+	ClientID     string   `json:"client_id"`
+	Topics       []string `json:"topics"`
+	Capabilities []string `json:"capabilities"`
+	ExposeAmount uint32   `json:"expose_amount"`
+	Protocol     string   `json:"protocol,omitempty"`
+	RegionHint   string   `json:"region_hint,omitempty"`
+}
+
+type sessionNode struct {
+	ID        string  `json:"id"`
+	Address   string  `json:"address"`
+	Transport string  `json:"transport"`
+	Region    string  `json:"region"`
+	Ticket    string  `json:"ticket"`
+	Score     float32 `json:"score"`
+}
+
+type sessionResponse struct {
+	SessionID    string        `json:"session_id"`
+	Nodes        []sessionNode `json:"nodes"`
+	ExpiresAt    string        `json:"expires_at"`
+	RefreshAfter string        `json:"refresh_after"`
+	Error        string        `json:"error,omitempty"`
+}
+
+// snapshot mirrors the JSON the tracer dashboard polls for.
+type snapshot struct {
+	Algorithm           string             `json:"algorithm"`
+	ActiveNodes         int                `json:"active_nodes"`
+	UnhealthyNodes      int                `json:"unhealthy_nodes"`
+	PublishedMessages   int64              `json:"published_messages"`
+	DeliveredMessages   int64              `json:"delivered_messages"`
+	DuplicateMessages   int64              `json:"duplicate_messages"`
+	AverageDelaySeconds float64            `json:"average_delay_seconds"`
+	P75                 float64            `json:"p75"`
+	P95                 float64            `json:"p95"`
+	TotalBytesMoved     uint64             `json:"total_bytes_moved"`
+	BloatFactor         float64            `json:"bloat_factor"`
+	IdealByteComplexity uint64             `json:"ideal_byte_complexity"`
+	LastUpdated         time.Time          `json:"last_updated"`
+	Messages            map[string]msgInfo `json:"messages"`
+	WindowSeconds       int                `json:"window_seconds"`
+}
+
+type msgInfo struct {
+	Topic      string              `json:"topic"`
+	Published  time.Time           `json:"published"`
+	Delivered  time.Time           `json:"delivered"`
+	DelaySec   float64             `json:"delay_sec"`
+	PeersSeen  map[string]struct{} `json:"peers_seen"`
+	Duplicates int                 `json:"duplicates"`
+	BytesMoved uint64              `json:"bytes_moved"`
+}
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/session", s.handleSession)
+	mux.HandleFunc("/api/v1/topics", s.handleTopics)
+	mux.HandleFunc("/api/v1/health", s.handleHealth)
+	mux.HandleFunc("/api/v1/publish", s.handlePublish)
+	mux.HandleFunc("/api/v1/subscribe", s.handleSubscribe)
+	mux.HandleFunc("/api/v1/tracer/stream", s.handleTracerStream)
+	mux.HandleFunc("/api/v1/tracer/reset", s.handleTracerReset)
+	return mux
+}
+
+func (s *Server) handleSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req sessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, sessionResponse{Error: "invalid request body"})
+		return
+	}
+	resp, err := s.createSession(req)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, sessionResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// createSession picks nodes and issues tickets. It backs both the HTTP
+// endpoint and the gRPC SessionService.
+func (s *Server) createSession(req sessionRequest) (sessionResponse, error) {
+	if req.ClientID == "" {
+		return sessionResponse{}, errors.New("client_id is required")
+	}
+	switch req.Protocol {
+	case "", "mump2p", "gossipsub":
+	default:
+		return sessionResponse{}, fmt.Errorf("unsupported protocol %q", req.Protocol)
+	}
+	if len(req.Capabilities) == 0 {
+		req.Capabilities = []string{"publish", "subscribe"}
+	}
+
+	nodes := s.Nodes()
+	if req.RegionHint != "" {
+		sort.SliceStable(nodes, func(i, j int) bool {
+			return nodes[i].Region() == req.RegionHint && nodes[j].Region() != req.RegionHint
+		})
+	}
+	amount := int(req.ExposeAmount)
+	if amount <= 0 || amount > len(nodes) {
+		amount = len(nodes)
+	}
+
+	now := time.Now().UTC()
+	resp := sessionResponse{
+		SessionID:    newSessionID(),
+		ExpiresAt:    now.Add(sessionTTL).Format(time.RFC3339),
+		RefreshAfter: now.Add(sessionRefresh).Format(time.RFC3339),
+	}
+	for _, n := range nodes[:amount] {
+		ticket, err := s.tickets.Issue(Ticket{
+			ClientID:     req.ClientID,
+			NodeID:       n.ID(),
+			Topics:       req.Topics,
+			Capabilities: req.Capabilities,
+			ExpiresAt:    now.Add(sessionTTL).Unix(),
+		})
+		if err != nil {
+			return sessionResponse{}, err
+		}
+		resp.Nodes = append(resp.Nodes, sessionNode{
+			ID:        n.ID(),
+			Address:   n.Addr(),
+			Transport: "grpc",
+			Region:    n.Region(),
+			Ticket:    ticket,
+			Score:     n.score,
+		})
+	}
+	return resp, nil
+}
+
+func (s *Server) handleTopics(w http.ResponseWriter, r *http.Request) {
+	clientID := r.URL.Query().Get("client_id")
+	topics := s.broker.Topics(clientID)
+	if topics == nil {
+		topics = []string{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"client_id": clientID,
+		"topics":    topics,
+		"count":     len(topics),
+	})
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	writeJSON(w, http.StatusOK, map[string]string{
+		"status":      "ok",
+		"memory_used": strconv.FormatFloat(float64(ms.Alloc)/float64(ms.Sys)*100, 'f', 2, 64),
+		"cpu_used":    strconv.FormatFloat(float64(runtime.NumGoroutine()%100)/10, 'f', 2, 64),
+		"disk_used":   "0.00",
+		"country":     "Localhost",
+		"country_iso": "LO",
+	})
+}
+
+// handlePublish serves the tracer load generator, which asks the proxy to
+// publish random payloads of a given length.
+func (s *Server) handlePublish(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ClientID      string `json:"client_id"`
+		Topic         string `json:"topic"`
+		Message       string `json:"message"`
+		MessageLength uint64 `json:"message_length"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Topic == "" {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	data := []byte(req.Message)
+	if len(data) == 0 && req.MessageLength > 0 {
+		data = make([]byte, req.MessageLength)
+		_, _ = rand.Read(data)
+	}
+	nodes := s.Nodes()
+	if len(nodes) == 0 {
+		http.Error(w, "no nodes running", http.StatusServiceUnavailable)
+		return
+	}
+	msg := s.broker.Publish(nodes[0], req.Topic, data)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "published", "message_id": msg.MessageID})
+}
+
+func (s *Server) handleSubscribe(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "subscribed"})
+}
+
+func (s *Server) handleTracerStream(w http.ResponseWriter, r *http.Request) {
+	window := 10 * time.Second
+	if v := r.URL.Query().Get("window"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			window = d
+		}
+	}
+	writeJSON(w, http.StatusOK, s.snapshot(window))
+}
+
+func (s *Server) handleTracerReset(w http.ResponseWriter, r *http.Request) {
+	s.broker.resetTraces()
+	writeJSON(w, http.StatusOK, map[string]string{"status": "reset"})
+}
+
+func (s *Server) snapshot(window time.Duration) snapshot {
+	b := s.broker
+	b.traceMu.Lock()
+	defer b.traceMu.Unlock()
+
+	since := time.Now().Add(-window)
+	snap := snapshot{
+		Algorithm:     "mump2p",
+		ActiveNodes:   len(s.Nodes()),
+		LastUpdated:   time.Now().UTC(),
+		Messages:      make(map[string]msgInfo),
+		WindowSeconds: int(window.Seconds()),
+	}
+	var delays []float64
+	for id, rec := range b.traces {
+		if rec.Published.Before(since) {
+			continue
+		}
+		snap.PublishedMessages++
+		info := msgInfo{
+			Topic:      rec.Topic,
+			Published:  rec.Published,
+			Delivered:  rec.Delivered,
+			PeersSeen:  rec.PeersSeen,
+			Duplicates: rec.Duplicates,
+			BytesMoved: rec.BytesMoved,
+		}
+		if !rec.Delivered.IsZero() {
+			snap.DeliveredMessages++
+			info.DelaySec = rec.Delivered.Sub(rec.Published).Seconds()
+			delays = append(delays, info.DelaySec)
+		}
+		snap.DuplicateMessages += int64(rec.Duplicates)
+		snap.TotalBytesMoved += rec.BytesMoved
+		snap.Messages[id] = info
+	}
+	if len(delays) > 0 {
+		sort.Float64s(delays)
+		var sum float64
+		for _, d := range delays {
+			sum += d
+		}
+		snap.AverageDelaySeconds = sum / float64(len(delays))
+		snap.P75 = delays[len(delays)*75/100]
+		snap.P95 = delays[len(delays)*95/100]
+	}
+	return snap
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func newSessionID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	h := hex.EncodeToString(b)
+	return fmt.Sprintf("%s-%s-%s-%s-%s", h[:8], h[8:12], h[12:16], h[16:20], h[20:])
+}
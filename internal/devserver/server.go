@@ -0,0 +1,156 @@
+// Package devserver runs a local stand-in for the Optimum proxy and a set of
+// in-process CommandStream nodes, so the CLI can be exercised offline.
+package devserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	pb "github.com/getoptimum/mump2p-cli/proto"
+	"google.golang.org/grpc"
+)
+
+// Config holds dev server settings.
+type Config struct {
+	// HTTPAddr is the proxy listen address, e.g. "127.0.0.1:8080".
+	HTTPAddr string
+	// Nodes is the number of CommandStream nodes to start.
+	Nodes int
+	// GRPCHost is the interface nodes listen on.
+	GRPCHost string
+	// GRPCBasePort is the port of the first node; following nodes use
+	// consecutive ports. Zero picks free ports.
+	GRPCBasePort int
+	// Seed makes link fault injection reproducible. Zero uses the clock.
+	Seed int64
+	// SessionGRPCAddr is the listen address of the gRPC SessionService.
+	// Empty disables it.
+	SessionGRPCAddr string
+}
+
+// Server is a running dev stack: one HTTP proxy and N gRPC nodes.
+type Server struct {
+	cfg     Config
+	broker  *Broker
+	tickets *ticketSigner
+
+	mu    sync.RWMutex
+	nodes []*Node
+
+	lis  net.Listener
+	http *http.Server
+
+	sessionLis  net.Listener
+	sessionGRPC *grpc.Server
+}
+
+// Start launches the proxy and nodes and returns once they are listening.
+func Start(cfg Config) (*Server, error) {
+	if cfg.Nodes <= 0 {
+		cfg.Nodes = 1
+	}
+	if cfg.HTTPAddr == "" {
+		cfg.HTTPAddr = "127.0.0.1:0"
+	}
+	if cfg.GRPCHost == "" {
+		cfg.GRPCHost = "127.0.0.1"
+	}
+
+	tickets, err := newTicketSigner()
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		cfg:     cfg,
+		broker:  NewBroker(),
+		tickets: tickets,
+	}
+
+	rng := newLockedRand(cfg.Seed)
+	for i := 0; i < cfg.Nodes; i++ {
+		port := 0
+		if cfg.GRPCBasePort > 0 {
+			port = cfg.GRPCBasePort + i
+		}
+		n, err := newNode(i, fmt.Sprintf("%s:%d", cfg.GRPCHost, port), s.broker, s.tickets, rng)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		s.nodes = append(s.nodes, n)
+	}
+
+	lis, err := net.Listen("tcp", cfg.HTTPAddr)
+	if err != nil {
+		s.Close()
+		return nil, fmt.Errorf("failed to listen on %s: %w", cfg.HTTPAddr, err)
+	}
+	s.lis = lis
+	s.http = &http.Server{Handler: s.routes(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := s.http.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Printf("dev-server: http: %v\n", err)
+		}
+	}()
+
+	if cfg.SessionGRPCAddr != "" {
+		slis, err := net.Listen("tcp", cfg.SessionGRPCAddr)
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("failed to listen on %s: %w", cfg.SessionGRPCAddr, err)
+		}
+		s.sessionLis = slis
+		s.sessionGRPC = grpc.NewServer()
+		pb.RegisterSessionServiceServer(s.sessionGRPC, &sessionService{srv: s})
+		go s.sessionGRPC.Serve(slis) //nolint:errcheck
+	}
+	return s, nil
+}
+
+// SessionAddr returns the gRPC SessionService address, or "" when it is
+// disabled.
+func (s *Server) SessionAddr() string {
+	if s.sessionLis == nil {
+		return ""
+	}
+	return s.sessionLis.Addr().String()
+}
+
+// URL returns the base URL to pass as --service-url.
+func (s *Server) URL() string {
+	return "http://" + s.lis.Addr().String()
+}
+
+// Nodes returns the running nodes in proxy order.
+func (s *Server) Nodes() []*Node {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]*Node, len(s.nodes))
+	copy(out, s.nodes)
+	return out
+}
+
+// Broker returns the shared message broker.
+func (s *Server) Broker() *Broker {
+	return s.broker
+}
+
+// Close stops the proxy and all nodes.
+func (s *Server) Close() {
+	if s.http != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		_ = s.http.Shutdown(ctx)
+		cancel()
+	}
+	if s.sessionGRPC != nil {
+		s.sessionGRPC.Stop()
+	}
+	for _, n := range s.Nodes() {
+		n.Stop()
+	}
+}
@@ -0,0 +1,127 @@
+package devserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/getoptimum/mump2p-cli/internal/entities"
+	"github.com/getoptimum/mump2p-cli/internal/node"
+	"github.com/getoptimum/mump2p-cli/internal/session"
+	pb "github.com/getoptimum/mump2p-cli/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func startTestServer(t *testing.T, nodes int) *Server {
+	t.Helper()
+	srv, err := Start(Config{Nodes: nodes})
+	require.NoError(t, err)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// TestPublishSubscribe tests fan-out across nodes through the dev stack.
+func TestPublishSubscribe(t *testing.T) {
+	srv := startTestServer(t, 3)
+
+	subSess, err := session.CreateSession(srv.URL(), "sub-client", "", []string{"demo"}, []string{"subscribe"}, 3)
+	require.NoError(t, err)
+	require.Len(t, subSess.Nodes, 3)
+
+	pubSess, err := session.CreateSession(srv.URL(), "pub-client", "", []string{"demo"}, []string{"publish"}, 1)
+	require.NoError(t, err)
+	require.Len(t, pubSess.Nodes, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// subscribe on the last node so delivery crosses nodes
+	subNode := subSess.Nodes[2]
+	sc, err := node.NewClient(subNode.Address)
+	require.NoError(t, err)
+	defer sc.Close()
+	ch, err := sc.Subscribe(ctx, subNode.Ticket, "demo", 10, nil)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return len(srv.Broker().Topics("sub-client")) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	pubNode := pubSess.Nodes[0]
+	pc, err := node.NewClient(pubNode.Address)
+	require.NoError(t, err)
+	defer pc.Close()
+	resp, err := pc.Publish(ctx, pubNode.Ticket, "demo", []byte("hello"))
+	require.NoError(t, err)
+	require.Equal(t, pb.ResponseType_MessageTraceMumP2P, resp.GetCommand())
+
+	var trace map[string]interface{}
+	require.NoError(t, json.Unmarshal(resp.GetData(), &trace))
+	require.NotEmpty(t, trace["messageID"])
+
+	for {
+		select {
+		case r := <-ch:
+			if r.GetCommand() != pb.ResponseType_Message {
+				continue
+			}
+			msg, err := entities.UnmarshalP2PMessage(r.GetData())
+			require.NoError(t, err)
+			require.Equal(t, "demo", msg.Topic)
+			require.Equal(t, []byte("hello"), msg.Message)
+			require.Equal(t, trace["messageID"], msg.MessageID)
+			return
+		case <-ctx.Done():
+			t.Fatal("message not delivered")
+		}
+	}
+}
+
+// TestTicketEnforcement tests that nodes reject tickets that do not cover the request.
+func TestTicketEnforcement(t *testing.T) {
+	srv := startTestServer(t, 2)
+
+	sess, err := session.CreateSession(srv.URL(), "client", "", []string{"demo"}, []string{"subscribe"}, 2)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c, err := node.NewClient(sess.Nodes[0].Address)
+	require.NoError(t, err)
+	defer c.Close()
+
+	_, err = c.Publish(ctx, sess.Nodes[0].Ticket, "demo", []byte("x"))
+	require.Error(t, err, "subscribe-only ticket must not publish")
+
+	_, err = c.Publish(ctx, sess.Nodes[1].Ticket, "demo", []byte("x"))
+	require.Error(t, err, "ticket for another node must be rejected")
+
+	_, err = c.Publish(ctx, "garbage", "demo", []byte("x"))
+	require.Error(t, err)
+}
+
+// TestProxyEndpoints tests the health and tracer endpoints.
+func TestProxyEndpoints(t *testing.T) {
+	srv := startTestServer(t, 1)
+
+	resp, err := http.Get(srv.URL() + "/api/v1/health")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var health map[string]string
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&health))
+	require.Equal(t, "ok", health["status"])
+
+	srv.Broker().Publish(srv.Nodes()[0], "demo", []byte("x"))
+
+	resp2, err := http.Get(srv.URL() + "/api/v1/tracer/stream?window=1m")
+	require.NoError(t, err)
+	defer resp2.Body.Close()
+	var snap snapshot
+	require.NoError(t, json.NewDecoder(resp2.Body).Decode(&snap))
+	require.Equal(t, int64(1), snap.PublishedMessages)
+	require.Equal(t, 1, snap.ActiveNodes)
+}
@@ -0,0 +1,46 @@
+package devserver
+
+import (
+	"context"
+
+	pb "github.com/getoptimum/mump2p-cli/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// sessionService serves SessionService.CreateSession from the same node
+// pool and ticket signer as the HTTP endpoint.
+type sessionService struct {
+	pb.UnimplementedSessionServiceServer
+	srv *Server
+}
+
+func (s *sessionService) CreateSession(ctx context.Context, req *pb.SessionRequest) (*pb.SessionResponse, error) {
+	resp, err := s.srv.createSession(sessionRequest{
+		ClientID:     req.GetClientId(),
+		Topics:       req.GetTopics(),
+		Capabilities: req.GetCapabilities(),
+		ExposeAmount: req.GetExposeAmount(),
+		Protocol:     req.GetProtocol(),
+		RegionHint:   req.GetRegionHint(),
+	})
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	out := &pb.SessionResponse{
+		SessionId:    resp.SessionID,
+		ExpiresAt:    resp.ExpiresAt,
+		RefreshAfter: resp.RefreshAfter,
+	}
+	for _, n := range resp.Nodes {
+		out.Nodes = append(out.Nodes, &pb.SessionNode{
+			Id:        n.ID,
+			Address:   n.Address,
+			Transport: n.Transport,
+			Region:    n.Region,
+			Ticket:    n.Ticket,
+			Score:     n.Score,
+		})
+	}
+	return out, nil
+}
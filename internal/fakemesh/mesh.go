@@ -0,0 +1,162 @@
+// Package fakemesh is test support for code that talks to CommandStream
+// nodes. It starts an in-process dev stack and exposes its chaos controls
+// (latency, jitter, loss, duplication, stream resets, node kills and
+// partitions) through a testing.TB friendly API.
+package fakemesh
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/getoptimum/mump2p-cli/internal/devserver"
+	"github.com/getoptimum/mump2p-cli/internal/entities"
+	"github.com/getoptimum/mump2p-cli/internal/node"
+	"github.com/getoptimum/mump2p-cli/internal/session"
+	pb "github.com/getoptimum/mump2p-cli/proto"
+)
+
+// Link is an alias so tests don't have to import devserver.
+type Link = devserver.LinkFaults
+
+// Option configures a Mesh.
+type Option func(*devserver.Config)
+
+// WithSeed makes fault injection deterministic.
+func WithSeed(seed int64) Option {
+	return func(c *devserver.Config) { c.Seed = seed }
+}
+
+// Mesh is a running fake network of nodes behind a local proxy.
+type Mesh struct {
+	*devserver.Server
+}
+
+// New starts a mesh with the given number of nodes. It is stopped when the
+// test finishes.
+func New(t testing.TB, nodes int, opts ...Option) *Mesh {
+	t.Helper()
+	cfg := devserver.Config{Nodes: nodes, SessionGRPCAddr: "127.0.0.1:0"}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	srv, err := devserver.Start(cfg)
+	if err != nil {
+		t.Fatalf("fakemesh: %v", err)
+	}
+	t.Cleanup(srv.Close)
+	return &Mesh{Server: srv}
+}
+
+// Node returns the node with the given 1-based index, matching the
+// numbering used in chaos plans.
+func (m *Mesh) Node(i int) *devserver.Node {
+	return m.Nodes()[i-1]
+}
+
+// SetLink sets the faults on the link of node i (1-based, 0 for all).
+func (m *Mesh) SetLink(t testing.TB, i int, l Link) {
+	t.Helper()
+	if err := m.RunEvent(devserver.ChaosEvent{Action: devserver.ActionSetLink, Node: i, Link: l}); err != nil {
+		t.Fatalf("fakemesh: %v", err)
+	}
+}
+
+// Script applies a YAML chaos plan (see devserver.LoadChaosFile). Events are
+// timed from the moment Script is called and stop with the test.
+func (m *Mesh) Script(t testing.TB, yaml string) {
+	t.Helper()
+	plan, err := devserver.ParseChaos([]byte(yaml))
+	if err != nil {
+		t.Fatalf("fakemesh: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := m.ApplyChaos(ctx, plan); err != nil {
+		t.Fatalf("fakemesh: %v", err)
+	}
+}
+
+// Session creates a session through the mesh proxy, exposing every node.
+func (m *Mesh) Session(t testing.TB, clientID string, capabilities []string, topics ...string) *session.Session {
+	t.Helper()
+	sess, err := session.CreateSession(m.URL(), clientID, "", topics, capabilities, uint32(len(m.Nodes())))
+	if err != nil {
+		t.Fatalf("fakemesh: create session: %v", err)
+	}
+	return sess
+}
+
+// WaitSubscribers blocks until topic has at least count subscriptions.
+func (m *Mesh) WaitSubscribers(t testing.TB, topic string, count int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for m.Broker().Subscribers(topic) < count {
+		if time.Now().After(deadline) {
+			t.Fatalf("fakemesh: timed out waiting for %d subscriber(s) on %q", count, topic)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// Subscribe opens a subscription on a session node and returns decoded
+// messages, skipping trace events. The channel closes when the stream ends.
+func Subscribe(ctx context.Context, t testing.TB, n session.Node, topic string) <-chan *entities.P2PMessage {
+	t.Helper()
+	c, err := node.NewClient(n.Address)
+	if err != nil {
+		t.Fatalf("fakemesh: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	ch, err := c.Subscribe(ctx, n.Ticket, topic, 100, nil)
+	if err != nil {
+		t.Fatalf("fakemesh: subscribe: %v", err)
+	}
+	out := make(chan *entities.P2PMessage, 100)
+	go func() {
+		defer close(out)
+		for resp := range ch {
+			if resp.GetCommand() != pb.ResponseType_Message {
+				continue
+			}
+			msg, err := entities.UnmarshalP2PMessage(resp.GetData())
+			if err != nil {
+				continue
+			}
+			select {
+			case out <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Publish publishes data through a session node.
+func Publish(ctx context.Context, n session.Node, topic string, data []byte) (*pb.Response, error) {
+	c, err := node.NewClient(n.Address)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	return c.Publish(ctx, n.Ticket, topic, data)
+}
+
+// Collect reads from ch until it closes or d elapses.
+func Collect(ch <-chan *entities.P2PMessage, d time.Duration) []*entities.P2PMessage {
+	var msgs []*entities.P2PMessage
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	for {
+		select {
+		case m, ok := <-ch:
+			if !ok {
+				return msgs
+			}
+			msgs = append(msgs, m)
+		case <-timer.C:
+			return msgs
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package fakemesh
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func publishN(t *testing.T, m *Mesh, topic string, n int) {
+	t.Helper()
+	pub := m.Session(t, "pub", []string{"publish"}, topic)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i := 0; i < n; i++ {
+		_, err := Publish(ctx, pub.Nodes[0], topic, []byte(fmt.Sprintf("msg-%d", i)))
+		require.NoError(t, err)
+	}
+}
+
+// TestLinkFaults tests drop, duplicate and latency injection.
+func TestLinkFaults(t *testing.T) {
+	tests := []struct {
+		name   string
+		link   Link
+		check  func(t *testing.T, got int)
+		settle time.Duration
+	}{
+		{
+			name:   "drop everything",
+			link:   Link{DropRate: 1},
+			check:  func(t *testing.T, got int) { require.Equal(t, 0, got) },
+			settle: 200 * time.Millisecond,
+		},
+		{
+			name:   "duplicate everything",
+			link:   Link{DuplicateRate: 1},
+			check:  func(t *testing.T, got int) { require.Equal(t, 20, got) },
+			settle: 300 * time.Millisecond,
+		},
+		{
+			name:   "latency delays delivery",
+			link:   Link{Latency: 100 * time.Millisecond, Jitter: 10 * time.Millisecond},
+			check:  func(t *testing.T, got int) { require.Equal(t, 10, got) },
+			settle: 500 * time.Millisecond,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m := New(t, 2, WithSeed(1))
+			sub := m.Session(t, "sub", []string{"subscribe"}, "demo")
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			ch := Subscribe(ctx, t, sub.Nodes[1], "demo")
+			m.WaitSubscribers(t, "demo", 1)
+
+			m.SetLink(t, 2, tc.link)
+			publishN(t, m, "demo", 10)
+
+			tc.check(t, len(Collect(ch, tc.settle)))
+		})
+	}
+}
+
+// TestKillAndRestart tests that a killed node drops its streams and comes back on the same address.
+func TestKillAndRestart(t *testing.T) {
+	m := New(t, 2)
+	sub := m.Session(t, "sub", []string{"subscribe"}, "demo")
+	addr := m.Node(1).Addr()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := Subscribe(ctx, t, sub.Nodes[0], "demo")
+	m.WaitSubscribers(t, "demo", 1)
+
+	m.Node(1).Kill()
+	require.False(t, m.Node(1).IsUp())
+	_, open := <-ch
+	require.False(t, open, "stream should close when the node is killed")
+
+	require.NoError(t, m.Node(1).Restart())
+	require.Equal(t, addr, m.Node(1).Addr())
+
+	ch = Subscribe(ctx, t, sub.Nodes[0], "demo")
+	m.WaitSubscribers(t, "demo", 1)
+	publishN(t, m, "demo", 1)
+	require.Len(t, Collect(ch, 500*time.Millisecond), 1)
+}
+
+// TestScript tests a YAML chaos plan with a stream reset and a partition.
+func TestScript(t *testing.T) {
+	m := New(t, 3)
+	sub := m.Session(t, "sub", []string{"subscribe"}, "demo")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch3 := Subscribe(ctx, t, sub.Nodes[2], "demo")
+	ch2 := Subscribe(ctx, t, sub.Nodes[1], "demo")
+	m.WaitSubscribers(t, "demo", 2)
+
+	m.Script(t, `
+events:
+  - at: 0s
+    action: partition
+    groups: [[1, 2], [3]]
+  - at: 0s
+    action: reset
+    node: 2
+`)
+	_, open := <-ch2
+	require.False(t, open, "reset should end the stream")
+
+	// node 1 publishes; node 3 is on the other side of the partition
+	publishN(t, m, "demo", 3)
+	require.Empty(t, Collect(ch3, 200*time.Millisecond))
+
+	m.Heal()
+	publishN(t, m, "demo", 3)
+	require.Len(t, Collect(ch3, 500*time.Millisecond), 3)
+}
@@ -0,0 +1,145 @@
+// Package httpclient provides the HTTP client shared by every command, so
+// proxy settings, extra CAs, timeouts and the user agent apply everywhere.
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/getoptimum/mump2p-cli/internal/config"
+)
+
+// DefaultTimeout bounds requests that do not ask for their own timeout.
+const DefaultTimeout = 30 * time.Second
+
+// Options configures the shared transport.
+type Options struct {
+	// CAFile is a PEM bundle trusted in addition to the system roots, e.g.
+	// a corporate TLS-inspecting proxy.
+	CAFile string
+	// Timeout replaces DefaultTimeout; zero keeps it.
+	Timeout time.Duration
+}
+
+var (
+	mu        sync.Mutex
+	transport http.RoundTripper
+	timeout   = DefaultTimeout
+	pending   func() (Options, error)
+)
+
+// Configure replaces the shared transport. Without it the transport honors
+// HTTPS_PROXY, HTTP_PROXY and NO_PROXY and trusts the system roots.
+func Configure(o Options) error {
+	mu.Lock()
+	defer mu.Unlock()
+	pending = nil
+	return apply(o)
+}
+
+// ConfigureLazily defers Configure until the first Client call, so commands
+// that make no HTTP requests never load the options. If load or Configure
+// fails, every request returns that error.
+func ConfigureLazily(load func() (Options, error)) {
+	mu.Lock()
+	defer mu.Unlock()
+	pending = load
+}
+
+func apply(o Options) error {
+	rt, err := newTransport(o)
+	if err != nil {
+		return err
+	}
+	transport = rt
+	timeout = DefaultTimeout
+	if o.Timeout > 0 {
+		timeout = o.Timeout
+	}
+	return nil
+}
+
+func newTransport(o Options) (http.RoundTripper, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = http.ProxyFromEnvironment
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", o.CAFile)
+		}
+		t.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12, RootCAs: pool}
+	}
+	return &userAgentTransport{base: t}, nil
+}
+
+// Client returns a client on the shared transport. A zero timeout uses the
+// configured one, a positive one is used as given, and a negative one
+// disables it for long-lived streams.
+func Client(d time.Duration) *http.Client {
+	mu.Lock()
+	defer mu.Unlock()
+	if load := pending; load != nil {
+		pending = nil
+		o, err := load()
+		if err == nil {
+			err = apply(o)
+		}
+		if err != nil {
+			transport = failedTransport{err}
+		}
+	}
+	if transport == nil {
+		transport, _ = newTransport(Options{})
+	}
+	switch {
+	case d < 0:
+		d = 0
+	case d == 0:
+		d = timeout
+	}
+	return &http.Client{Transport: transport, Timeout: d}
+}
+
+// UserAgent identifies the CLI and its version.
+func UserAgent() string {
+	v := config.Version
+	if v == "" {
+		v = "dev"
+	}
+	return fmt.Sprintf("mump2p-cli/%s (%s/%s)", v, runtime.GOOS, runtime.GOARCH)
+}
+
+// failedTransport fails every request with the error that kept the shared
+// transport from being configured.
+type failedTransport struct {
+	err error
+}
+
+func (t failedTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, t.err
+}
+
+type userAgentTransport struct {
+	base http.RoundTripper
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", UserAgent())
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,136 @@
+package httpclient
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/getoptimum/mump2p-cli/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+// TestProxyFromEnvironment tests HTTP_PROXY and NO_PROXY. It must run first:
+// net/http reads the proxy variables once per process.
+func TestProxyFromEnvironment(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+	}))
+	defer proxy.Close()
+	t.Setenv("HTTP_PROXY", proxy.URL)
+	t.Setenv("NO_PROXY", "direct.invalid")
+	require.NoError(t, Configure(Options{}))
+
+	resp, err := Client(0).Get("http://proxied.invalid/api/v1/health")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, []string{"http://proxied.invalid/api/v1/health"}, proxied)
+
+	_, err = Client(time.Second).Get("http://direct.invalid/")
+	require.Error(t, err, "NO_PROXY hosts are dialed directly")
+	require.Len(t, proxied, 1)
+}
+
+// TestUserAgent tests that requests carry the CLI version unless they set
+// their own user agent.
+func TestUserAgent(t *testing.T) {
+	old := config.Version
+	config.Version = "v1.2.3"
+	defer func() { config.Version = old }()
+
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.UserAgent()
+	}))
+	defer srv.Close()
+	require.NoError(t, Configure(Options{}))
+
+	resp, err := Client(0).Get(srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Contains(t, got, "mump2p-cli/v1.2.3")
+
+	req, err := http.NewRequest("GET", srv.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("User-Agent", "custom")
+	resp, err = Client(0).Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, "custom", got)
+}
+
+// TestCAFile tests trusting an extra CA bundle.
+func TestCAFile(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	require.NoError(t, Configure(Options{}))
+	_, err := Client(0).Get(srv.URL)
+	require.Error(t, err, "test CA is not in the system roots")
+
+	ca := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(ca, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0600))
+	require.NoError(t, Configure(Options{CAFile: ca}))
+	resp, err := Client(0).Get(srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	require.Error(t, Configure(Options{CAFile: filepath.Join(t.TempDir(), "missing.pem")}))
+	notPEM := filepath.Join(t.TempDir(), "ca.txt")
+	require.NoError(t, os.WriteFile(notPEM, []byte("not a certificate"), 0600))
+	require.Error(t, Configure(Options{CAFile: notPEM}))
+}
+
+// TestTimeouts tests the configured default and per-client timeouts.
+func TestTimeouts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	require.NoError(t, Configure(Options{Timeout: 50 * time.Millisecond}))
+	defer Configure(Options{}) //nolint:errcheck
+	_, err := Client(0).Get(srv.URL)
+	require.Error(t, err)
+
+	resp, err := Client(time.Second).Get(srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	resp, err = Client(-1).Get(srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Zero(t, Client(-1).Timeout)
+
+	// a per-client timeout wins over the configured one, shorter or longer
+	require.NoError(t, Configure(Options{Timeout: time.Minute}))
+	require.Equal(t, time.Minute, Client(0).Timeout)
+	require.Equal(t, time.Second, Client(time.Second).Timeout)
+	require.Equal(t, 5*time.Minute, Client(5*time.Minute).Timeout)
+}
+
+// TestConfigureLazily tests that options are loaded on the first client, and
+// that a load failure is returned by requests rather than earlier.
+func TestConfigureLazily(t *testing.T) {
+	defer Configure(Options{}) //nolint:errcheck
+
+	loads := 0
+	ConfigureLazily(func() (Options, error) {
+		loads++
+		return Options{Timeout: time.Minute}, nil
+	})
+	require.Zero(t, loads)
+	require.Equal(t, time.Minute, Client(0).Timeout)
+	require.Equal(t, time.Minute, Client(0).Timeout)
+	require.Equal(t, 1, loads)
+
+	ConfigureLazily(func() (Options, error) {
+		return Options{CAFile: filepath.Join(t.TempDir(), "missing.pem")}, nil
+	})
+	_, err := Client(0).Get("http://127.0.0.1:1/")
+	require.ErrorContains(t, err, "failed to read CA bundle")
+}
@@ -0,0 +1,250 @@
+// Package keyring stores the keys used for end-to-end payload encryption
+// and message signing.
+package keyring
+
+import (
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FileName is the keyring file inside the CLI's config directory.
+const FileName = "keyring.json"
+
+// Type is the kind of key.
+type Type string
+
+const (
+	// Symmetric is a 256-bit secret shared by everyone on a topic.
+	Symmetric Type = "symmetric"
+	// X25519 is a key pair; messages are encrypted to its public half.
+	X25519 Type = "x25519"
+	// Ed25519 is a signing key pair; subscribers trust its public half.
+	Ed25519 Type = "ed25519"
+)
+
+// ErrNotFound is returned when no key has the requested name.
+var ErrNotFound = errors.New("key not found")
+
+// Key is one keyring entry. Private is empty for keys imported from other
+// people; for symmetric keys it holds the secret and for ed25519 keys the
+// seed.
+type Key struct {
+	Name    string    `json:"name"`
+	Type    Type      `json:"type"`
+	Topic   string    `json:"topic,omitempty"`
+	Public  []byte    `json:"public,omitempty"`
+	Private []byte    `json:"private,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+// ID is a short fingerprint of the key, also written into encrypted
+// payloads so the receiver can find the right key.
+func (k Key) ID() string {
+	material := k.Public
+	if k.Type == Symmetric {
+		material = k.Private
+	}
+	return Fingerprint(k.Type, material)
+}
+
+// Fingerprint returns the ID of a key of type t with the given public key,
+// or secret for symmetric keys.
+func Fingerprint(t Type, material []byte) string {
+	sum := sha256.Sum256(append([]byte("mump2p-"+string(t)+":"), material...))
+	return hex.EncodeToString(sum[:8])
+}
+
+// HasPrivate reports whether the key can decrypt.
+func (k Key) HasPrivate() bool {
+	return len(k.Private) > 0
+}
+
+// Generate creates a new key of type t.
+func Generate(name string, t Type, topic string) (Key, error) {
+	k := Key{Name: name, Type: t, Topic: topic, Created: time.Now().UTC()}
+	switch t {
+	case Symmetric:
+		k.Private = make([]byte, 32)
+		if _, err := rand.Read(k.Private); err != nil {
+			return Key{}, err
+		}
+	case X25519:
+		priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+		if err != nil {
+			return Key{}, err
+		}
+		k.Private = priv.Bytes()
+		k.Public = priv.PublicKey().Bytes()
+	case Ed25519:
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return Key{}, err
+		}
+		k.Private = priv.Seed()
+		k.Public = pub
+	default:
+		return Key{}, fmt.Errorf("unknown key type %q (use %s, %s or %s)", t, Symmetric, X25519, Ed25519)
+	}
+	return k, nil
+}
+
+// Export encodes the key as "<type>-public:<base64>" or, with private,
+// "<type>-private:<base64>". Symmetric keys only have a private form.
+func (k Key) Export(private bool) (string, error) {
+	if k.Type == Symmetric || private {
+		if !k.HasPrivate() {
+			return "", fmt.Errorf("key %s has no private part", k.Name)
+		}
+		return fmt.Sprintf("%s-private:%s", k.Type, base64.StdEncoding.EncodeToString(k.Private)), nil
+	}
+	return fmt.Sprintf("%s-public:%s", k.Type, base64.StdEncoding.EncodeToString(k.Public)), nil
+}
+
+// Parse decodes a key written by Export.
+func Parse(name, s string) (Key, error) {
+	kind, data, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		return Key{}, errors.New("key must look like <type>-public:<base64> or <type>-private:<base64>")
+	}
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return Key{}, fmt.Errorf("invalid key encoding: %v", err)
+	}
+	k := Key{Name: name, Created: time.Now().UTC()}
+	switch kind {
+	case "symmetric-private":
+		if len(raw) != 32 {
+			return Key{}, fmt.Errorf("symmetric key must be 32 bytes, got %d", len(raw))
+		}
+		k.Type, k.Private = Symmetric, raw
+	case "x25519-public":
+		if _, err := ecdh.X25519().NewPublicKey(raw); err != nil {
+			return Key{}, fmt.Errorf("invalid x25519 public key: %v", err)
+		}
+		k.Type, k.Public = X25519, raw
+	case "x25519-private":
+		priv, err := ecdh.X25519().NewPrivateKey(raw)
+		if err != nil {
+			return Key{}, fmt.Errorf("invalid x25519 private key: %v", err)
+		}
+		k.Type, k.Private, k.Public = X25519, raw, priv.PublicKey().Bytes()
+	case "ed25519-public":
+		if len(raw) != ed25519.PublicKeySize {
+			return Key{}, fmt.Errorf("ed25519 public key must be %d bytes, got %d", ed25519.PublicKeySize, len(raw))
+		}
+		k.Type, k.Public = Ed25519, raw
+	case "ed25519-private":
+		if len(raw) != ed25519.SeedSize {
+			return Key{}, fmt.Errorf("ed25519 private key must be %d bytes, got %d", ed25519.SeedSize, len(raw))
+		}
+		k.Type, k.Private = Ed25519, raw
+		k.Public = ed25519.NewKeyFromSeed(raw).Public().(ed25519.PublicKey)
+	default:
+		return Key{}, fmt.Errorf("unknown key kind %q", kind)
+	}
+	return k, nil
+}
+
+// Keyring is the set of keys in a keyring file.
+type Keyring struct {
+	path string
+	Keys []Key `json:"keys"`
+}
+
+// Load reads the keyring at path; a missing file is an empty keyring.
+func Load(path string) (*Keyring, error) {
+	kr := &Keyring{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return kr, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyring: %w", err)
+	}
+	if err := json.Unmarshal(data, kr); err != nil {
+		return nil, fmt.Errorf("invalid keyring %s: %w", path, err)
+	}
+	return kr, nil
+}
+
+// Save writes the keyring, readable only by the current user.
+func (kr *Keyring) Save() error {
+	if err := os.MkdirAll(filepath.Dir(kr.path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(kr, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := kr.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, kr.path)
+}
+
+// Add stores k; names are unique.
+func (kr *Keyring) Add(k Key) error {
+	if k.Name == "" {
+		return errors.New("key name is required")
+	}
+	if _, err := kr.Get(k.Name); err == nil {
+		return fmt.Errorf("a key named %s already exists", k.Name)
+	}
+	kr.Keys = append(kr.Keys, k)
+	sort.Slice(kr.Keys, func(i, j int) bool { return kr.Keys[i].Name < kr.Keys[j].Name })
+	return nil
+}
+
+// Remove deletes the key called name.
+func (kr *Keyring) Remove(name string) error {
+	for i, k := range kr.Keys {
+		if k.Name == name {
+			kr.Keys = append(kr.Keys[:i], kr.Keys[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+// Get returns the key called name.
+func (kr *Keyring) Get(name string) (Key, error) {
+	for _, k := range kr.Keys {
+		if k.Name == name {
+			return k, nil
+		}
+	}
+	return Key{}, fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+// ByID returns the key of type t with the given ID.
+func (kr *Keyring) ByID(t Type, id string) (Key, bool) {
+	for _, k := range kr.Keys {
+		if k.Type == t && k.ID() == id {
+			return k, true
+		}
+	}
+	return Key{}, false
+}
+
+// ForTopic returns the symmetric key bound to topic.
+func (kr *Keyring) ForTopic(topic string) (Key, error) {
+	for _, k := range kr.Keys {
+		if k.Type == Symmetric && k.Topic == topic {
+			return k, nil
+		}
+	}
+	return Key{}, fmt.Errorf("%w: no symmetric key for topic %s", ErrNotFound, topic)
+}
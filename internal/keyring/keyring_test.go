@@ -0,0 +1,91 @@
+package keyring
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestKeyringPersistence tests adding, saving, reloading and removing keys.
+func TestKeyringPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cfg", FileName)
+	kr, err := Load(path)
+	require.NoError(t, err)
+	require.Empty(t, kr.Keys)
+
+	sym, err := Generate("team", Symmetric, "alerts")
+	require.NoError(t, err)
+	pair, err := Generate("alice", X25519, "")
+	require.NoError(t, err)
+	require.NoError(t, kr.Add(sym))
+	require.NoError(t, kr.Add(pair))
+	require.Error(t, kr.Add(sym))
+	require.NoError(t, kr.Save())
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	kr, err = Load(path)
+	require.NoError(t, err)
+	require.Len(t, kr.Keys, 2)
+	require.Equal(t, "alice", kr.Keys[0].Name)
+
+	got, err := kr.ForTopic("alerts")
+	require.NoError(t, err)
+	require.Equal(t, sym.ID(), got.ID())
+	_, err = kr.ForTopic("other")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	byID, ok := kr.ByID(X25519, pair.ID())
+	require.True(t, ok)
+	require.Equal(t, "alice", byID.Name)
+
+	require.NoError(t, kr.Remove("alice"))
+	require.ErrorIs(t, kr.Remove("alice"), ErrNotFound)
+	_, err = kr.Get("alice")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+// TestExportParse tests that exported keys import with the same ID and
+// that public exports carry no secret.
+func TestExportParse(t *testing.T) {
+	pair, err := Generate("bob", X25519, "")
+	require.NoError(t, err)
+
+	pub, err := pair.Export(false)
+	require.NoError(t, err)
+	imported, err := Parse("bob-pub", pub)
+	require.NoError(t, err)
+	require.False(t, imported.HasPrivate())
+	require.Equal(t, pair.ID(), imported.ID())
+
+	priv, err := pair.Export(true)
+	require.NoError(t, err)
+	imported, err = Parse("bob", priv)
+	require.NoError(t, err)
+	require.Equal(t, pair.Public, imported.Public)
+	require.Equal(t, pair.Private, imported.Private)
+
+	sym, err := Generate("s", Symmetric, "")
+	require.NoError(t, err)
+	exp, err := sym.Export(false)
+	require.NoError(t, err)
+	imported, err = Parse("s", exp)
+	require.NoError(t, err)
+	require.Equal(t, sym.ID(), imported.ID())
+
+	_, err = imported.Export(false)
+	require.NoError(t, err)
+	_, err = Key{Name: "p", Type: X25519, Public: pair.Public}.Export(true)
+	require.Error(t, err)
+
+	for _, bad := range []string{"nope", "rsa-public:AAAA", "symmetric-private:!!", "symmetric-private:AAAA"} {
+		_, err := Parse("x", bad)
+		require.Error(t, err, bad)
+	}
+	_, err = Generate("x", Type("rsa"), "")
+	require.Error(t, err)
+}
@@ -0,0 +1,264 @@
+package keyring
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// Encrypted payloads start with sealMagic, a version and a mode:
+//
+//	symmetric:  keyID(8) nonce(12) ciphertext
+//	recipients: ephemeral(32) n(1) n*[keyID(8) wrappedKey(48)] nonce(12) ciphertext
+//
+// The payload is encrypted with AES-256-GCM. For recipients a random
+// content key is wrapped for each of them with a key derived by HKDF from
+// X25519 between an ephemeral key and theirs. Everything before the
+// ciphertext and the topic are authenticated, so a message cannot be
+// replayed on another topic.
+var sealMagic = []byte{0xc3, 'M', 'E'}
+
+const (
+	sealVersion = 1
+
+	modeSymmetric  = 1
+	modeRecipients = 2
+
+	idSize      = 8
+	nonceSize   = 12
+	wrappedSize = 32 + 16
+	maxRecips   = 255
+)
+
+var (
+	// ErrNoKey means no key in the keyring can decrypt the payload.
+	ErrNoKey = errors.New("no matching key in keyring")
+	// ErrCorrupt means the payload is not a valid encrypted message.
+	ErrCorrupt = errors.New("corrupt encrypted payload")
+)
+
+// IsSealed reports whether b looks like an encrypted payload.
+func IsSealed(b []byte) bool {
+	return len(b) > len(sealMagic)+2 && bytes.HasPrefix(b, sealMagic)
+}
+
+// SealSymmetric encrypts plaintext for topic with a symmetric key.
+func SealSymmetric(k Key, topic string, plaintext []byte) ([]byte, error) {
+	if k.Type != Symmetric || len(k.Private) != 32 {
+		return nil, fmt.Errorf("key %s is not a symmetric key", k.Name)
+	}
+	header := sealHeader(modeSymmetric)
+	header = append(header, idBytes(k)...)
+	return seal(k.Private, header, topic, plaintext)
+}
+
+// SealFor encrypts plaintext for topic so that any of recipients can read
+// it.
+func SealFor(recipients []Key, topic string, plaintext []byte) ([]byte, error) {
+	if len(recipients) == 0 || len(recipients) > maxRecips {
+		return nil, fmt.Errorf("need 1 to %d recipients, got %d", maxRecips, len(recipients))
+	}
+	eph, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	contentKey := make([]byte, 32)
+	if _, err := rand.Read(contentKey); err != nil {
+		return nil, err
+	}
+
+	header := sealHeader(modeRecipients)
+	header = append(header, eph.PublicKey().Bytes()...)
+	header = append(header, byte(len(recipients)))
+	for _, r := range recipients {
+		if r.Type != X25519 {
+			return nil, fmt.Errorf("recipient %s is not an x25519 key", r.Name)
+		}
+		pub, err := ecdh.X25519().NewPublicKey(r.Public)
+		if err != nil {
+			return nil, fmt.Errorf("recipient %s: %v", r.Name, err)
+		}
+		kek, err := wrapKey(eph, pub)
+		if err != nil {
+			return nil, err
+		}
+		wrapped, err := gcmSeal(kek, make([]byte, nonceSize), contentKey, nil)
+		if err != nil {
+			return nil, err
+		}
+		header = append(header, idBytes(r)...)
+		header = append(header, wrapped...)
+	}
+	return seal(contentKey, header, topic, plaintext)
+}
+
+// Open decrypts a payload from topic with whichever key it was sealed for.
+func (kr *Keyring) Open(b []byte, topic string) ([]byte, error) {
+	if !IsSealed(b) {
+		return nil, ErrCorrupt
+	}
+	r := &reader{b: b, off: len(sealMagic)}
+	if v := r.byte(); v != sealVersion {
+		return nil, fmt.Errorf("unsupported encryption version %d", v)
+	}
+	switch r.byte() {
+	case modeSymmetric:
+		id := r.next(idSize)
+		if r.err {
+			return nil, ErrCorrupt
+		}
+		k, ok := kr.ByID(Symmetric, hex.EncodeToString(id))
+		if !ok {
+			return nil, fmt.Errorf("%w (symmetric key %x)", ErrNoKey, id)
+		}
+		return open(k.Private, r, topic)
+	case modeRecipients:
+		ephPub := r.next(32)
+		n := int(r.byte())
+		var contentKey []byte
+		for range n {
+			id, wrapped := r.next(idSize), r.next(wrappedSize)
+			if r.err || contentKey != nil {
+				continue
+			}
+			k, ok := kr.ByID(X25519, hex.EncodeToString(id))
+			if !ok || !k.HasPrivate() {
+				continue
+			}
+			if ck, err := unwrapKey(k, ephPub, wrapped); err == nil {
+				contentKey = ck
+			}
+		}
+		if r.err {
+			return nil, ErrCorrupt
+		}
+		if contentKey == nil {
+			return nil, fmt.Errorf("%w (sealed for %d recipient(s))", ErrNoKey, n)
+		}
+		return open(contentKey, r, topic)
+	default:
+		return nil, ErrCorrupt
+	}
+}
+
+func sealHeader(mode byte) []byte {
+	return append(append([]byte{}, sealMagic...), sealVersion, mode)
+}
+
+func idBytes(k Key) []byte {
+	id, _ := hex.DecodeString(k.ID())
+	return id
+}
+
+func seal(key, header []byte, topic string, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	header = append(header, nonce...)
+	return gcmSeal(key, nonce, plaintext, aad(header, topic), header...)
+}
+
+func open(key []byte, r *reader, topic string) ([]byte, error) {
+	nonce := r.next(nonceSize)
+	if r.err {
+		return nil, ErrCorrupt
+	}
+	header := r.b[:r.off]
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	out, err := gcm.Open(nil, nonce, r.b[r.off:], aad(header, topic))
+	if err != nil {
+		return nil, fmt.Errorf("decryption failed: %w", err)
+	}
+	return out, nil
+}
+
+func aad(header []byte, topic string) []byte {
+	return append(append([]byte{}, header...), topic...)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// gcmSeal appends the ciphertext of plaintext to prefix.
+func gcmSeal(key, nonce, plaintext, additional []byte, prefix ...byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return gcm.Seal(prefix, nonce, plaintext, additional), nil
+}
+
+func wrapKey(eph *ecdh.PrivateKey, recipient *ecdh.PublicKey) ([]byte, error) {
+	shared, err := eph.ECDH(recipient)
+	if err != nil {
+		return nil, err
+	}
+	salt := append(eph.PublicKey().Bytes(), recipient.Bytes()...)
+	return hkdf.Key(sha256.New, shared, salt, "mump2p-e2e-v1", 32)
+}
+
+func unwrapKey(k Key, ephPub, wrapped []byte) ([]byte, error) {
+	priv, err := ecdh.X25519().NewPrivateKey(k.Private)
+	if err != nil {
+		return nil, err
+	}
+	eph, err := ecdh.X25519().NewPublicKey(ephPub)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := priv.ECDH(eph)
+	if err != nil {
+		return nil, err
+	}
+	salt := append(append([]byte{}, ephPub...), k.Public...)
+	kek, err := hkdf.Key(sha256.New, shared, salt, "mump2p-e2e-v1", 32)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(kek)
+	if err != nil {
+		return nil, err
+	}
+	return gcm.Open(nil, make([]byte, nonceSize), wrapped, nil)
+}
+
+// reader walks an encrypted payload; err is set once it runs out of bytes.
+type reader struct {
+	b   []byte
+	off int
+	err bool
+}
+
+func (r *reader) next(n int) []byte {
+	if r.err || r.off+n > len(r.b) {
+		r.err = true
+		return nil
+	}
+	out := r.b[r.off : r.off+n]
+	r.off += n
+	return out
+}
+
+func (r *reader) byte() byte {
+	b := r.next(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
@@ -0,0 +1,76 @@
+package keyring
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestSealSymmetric tests encrypting with a topic key and that the
+// ciphertext is bound to the topic and the key.
+func TestSealSymmetric(t *testing.T) {
+	k, err := Generate("team", Symmetric, "alerts")
+	require.NoError(t, err)
+	kr := &Keyring{Keys: []Key{k}}
+
+	sealed, err := SealSymmetric(k, "alerts", []byte("hello"))
+	require.NoError(t, err)
+	require.True(t, IsSealed(sealed))
+	require.NotContains(t, string(sealed), "hello")
+
+	got, err := kr.Open(sealed, "alerts")
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(got))
+
+	_, err = kr.Open(sealed, "other-topic")
+	require.ErrorContains(t, err, "decryption failed")
+
+	tampered := append([]byte{}, sealed...)
+	tampered[len(tampered)-1] ^= 1
+	_, err = kr.Open(tampered, "alerts")
+	require.Error(t, err)
+
+	_, err = (&Keyring{}).Open(sealed, "alerts")
+	require.ErrorIs(t, err, ErrNoKey)
+	_, err = kr.Open(sealed[:len(sealMagic)+4], "alerts")
+	require.ErrorIs(t, err, ErrCorrupt)
+	require.False(t, IsSealed([]byte("plain text")))
+}
+
+// TestSealFor tests that every recipient, and only they, can decrypt.
+func TestSealFor(t *testing.T) {
+	alice, err := Generate("alice", X25519, "")
+	require.NoError(t, err)
+	bob, err := Generate("bob", X25519, "")
+	require.NoError(t, err)
+	eve, err := Generate("eve", X25519, "")
+	require.NoError(t, err)
+
+	alicePub, err := alice.Export(false)
+	require.NoError(t, err)
+	alicePubKey, err := Parse("alice", alicePub)
+	require.NoError(t, err)
+
+	sealed, err := SealFor([]Key{alicePubKey, bob}, "dm", []byte("secret"))
+	require.NoError(t, err)
+
+	for _, k := range []Key{alice, bob} {
+		got, err := (&Keyring{Keys: []Key{eve, k}}).Open(sealed, "dm")
+		require.NoError(t, err, k.Name)
+		require.Equal(t, "secret", string(got))
+	}
+
+	_, err = (&Keyring{Keys: []Key{eve, alicePubKey}}).Open(sealed, "dm")
+	require.ErrorIs(t, err, ErrNoKey)
+	_, err = (&Keyring{Keys: []Key{bob}}).Open(sealed[:40], "dm")
+	require.ErrorIs(t, err, ErrCorrupt)
+
+	_, err = SealFor(nil, "dm", []byte("x"))
+	require.Error(t, err)
+	sym, err := Generate("s", Symmetric, "")
+	require.NoError(t, err)
+	_, err = SealFor([]Key{sym}, "dm", []byte("x"))
+	require.Error(t, err)
+	_, err = SealSymmetric(bob, "dm", []byte("x"))
+	require.Error(t, err)
+}
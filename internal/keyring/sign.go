@@ -0,0 +1,122 @@
+package keyring
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+)
+
+// Signed payloads are wrapped in an envelope:
+//
+//	signMagic version(1) keyID(8) signature(64) payload
+//
+// The signature covers everything before it, the topic and the payload, so
+// a signed message cannot be moved to another topic.
+var signMagic = []byte{0xc3, 'M', 'S'}
+
+const (
+	signVersion    = 1
+	signHeaderSize = 3 + 1 + idSize + ed25519.SignatureSize
+)
+
+// Status is the outcome of verifying a message.
+type Status string
+
+const (
+	// Verified means the message was signed by a trusted key.
+	Verified Status = "verified"
+	// Unsigned means the message carries no signature.
+	Unsigned Status = "unsigned"
+	// Unverified means the message is signed but was not checked, because
+	// there is no trust store.
+	Unverified Status = "unverified"
+	// UnknownSigner means the signing key is not in the trust store.
+	UnknownSigner Status = "unknown-signer"
+	// BadSignature means the signature does not match the message.
+	BadSignature Status = "bad-signature"
+)
+
+// Verification describes who signed a message.
+type Verification struct {
+	Status Status `json:"status" yaml:"status"`
+	Signer string `json:"signer,omitempty" yaml:"signer,omitempty"`
+	KeyID  string `json:"key_id,omitempty" yaml:"key_id,omitempty"`
+}
+
+// OK reports whether the message was signed by a trusted key.
+func (v Verification) OK() bool {
+	return v.Status == Verified
+}
+
+// String describes the result for people, e.g. "signed by alice".
+func (v Verification) String() string {
+	switch v.Status {
+	case Verified:
+		return "signed by " + v.Signer
+	case UnknownSigner:
+		return "unknown signer " + v.KeyID
+	case BadSignature:
+		return "bad signature"
+	case Unverified:
+		return "unverified signature " + v.KeyID
+	default:
+		return string(v.Status)
+	}
+}
+
+// IsSigned reports whether b looks like a signed payload.
+func IsSigned(b []byte) bool {
+	return len(b) >= signHeaderSize && bytes.HasPrefix(b, signMagic)
+}
+
+// Sign wraps payload for topic in an envelope signed with an ed25519 key.
+func Sign(k Key, topic string, payload []byte) ([]byte, error) {
+	if k.Type != Ed25519 || len(k.Private) != ed25519.SeedSize {
+		return nil, fmt.Errorf("key %s is not an ed25519 key with a private part", k.Name)
+	}
+	out := make([]byte, 0, signHeaderSize+len(payload))
+	out = append(out, signMagic...)
+	out = append(out, signVersion)
+	out = append(out, idBytes(k)...)
+	sig := ed25519.Sign(ed25519.NewKeyFromSeed(k.Private), signedData(out, topic, payload))
+	out = append(out, sig...)
+	return append(out, payload...), nil
+}
+
+// Verify checks a payload from topic against the ed25519 keys in the
+// keyring and returns the payload without the envelope. Unsigned payloads
+// are returned unchanged. A nil keyring only removes the envelope, and
+// reports signed payloads as Unverified.
+func (kr *Keyring) Verify(b []byte, topic string) ([]byte, Verification) {
+	if !IsSigned(b) {
+		return b, Verification{Status: Unsigned}
+	}
+	header := b[:len(signMagic)+1+idSize]
+	sig := b[len(header):signHeaderSize]
+	payload := b[signHeaderSize:]
+	id := hex.EncodeToString(header[len(signMagic)+1:])
+
+	if kr == nil {
+		return payload, Verification{Status: Unverified, KeyID: id}
+	}
+	if header[len(signMagic)] != signVersion {
+		return payload, Verification{Status: BadSignature, KeyID: id}
+	}
+	k, ok := kr.ByID(Ed25519, id)
+	if !ok {
+		return payload, Verification{Status: UnknownSigner, KeyID: id}
+	}
+	if !ed25519.Verify(ed25519.PublicKey(k.Public), signedData(header, topic, payload), sig) {
+		return payload, Verification{Status: BadSignature, Signer: k.Name, KeyID: id}
+	}
+	return payload, Verification{Status: Verified, Signer: k.Name, KeyID: id}
+}
+
+func signedData(header []byte, topic string, payload []byte) []byte {
+	out := make([]byte, 0, len(header)+len(topic)+1+len(payload))
+	out = append(out, header...)
+	out = append(out, topic...)
+	out = append(out, 0)
+	return append(out, payload...)
+}
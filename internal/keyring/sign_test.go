@@ -0,0 +1,101 @@
+package keyring
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestSignVerify tests each verification outcome.
+func TestSignVerify(t *testing.T) {
+	alice, err := Generate("alice", Ed25519, "")
+	require.NoError(t, err)
+	mallory, err := Generate("mallory", Ed25519, "")
+	require.NoError(t, err)
+
+	pub, err := alice.Export(false)
+	require.NoError(t, err)
+	trusted, err := Parse("alice", pub)
+	require.NoError(t, err)
+	require.False(t, trusted.HasPrivate())
+	trust := &Keyring{Keys: []Key{trusted}}
+
+	signed, err := Sign(alice, "news", []byte("hello"))
+	require.NoError(t, err)
+	require.True(t, IsSigned(signed))
+
+	payload, v := trust.Verify(signed, "news")
+	require.Equal(t, "hello", string(payload))
+	require.True(t, v.OK())
+	require.Equal(t, "alice", v.Signer)
+	require.Equal(t, "signed by alice", v.String())
+
+	_, v = trust.Verify(signed, "other")
+	require.Equal(t, BadSignature, v.Status)
+
+	tampered := append([]byte{}, signed...)
+	tampered[len(tampered)-1] = '!'
+	payload, v = trust.Verify(tampered, "news")
+	require.Equal(t, BadSignature, v.Status)
+	require.Equal(t, "hell!", string(payload))
+
+	forged, err := Sign(mallory, "news", []byte("hello"))
+	require.NoError(t, err)
+	_, v = trust.Verify(forged, "news")
+	require.Equal(t, UnknownSigner, v.Status)
+	require.Equal(t, mallory.ID(), v.KeyID)
+
+	payload, v = trust.Verify([]byte("plain"), "news")
+	require.Equal(t, Unsigned, v.Status)
+	require.Equal(t, "plain", string(payload))
+
+	_, err = Sign(trusted, "news", []byte("x"))
+	require.Error(t, err)
+}
+
+// TestEd25519Export tests that an exported private key imports with the
+// same public key.
+func TestEd25519Export(t *testing.T) {
+	k, err := Generate("signer", Ed25519, "")
+	require.NoError(t, err)
+	priv, err := k.Export(true)
+	require.NoError(t, err)
+	back, err := Parse("signer", priv)
+	require.NoError(t, err)
+	require.Equal(t, k.Public, back.Public)
+	require.Equal(t, k.ID(), back.ID())
+
+	_, err = Parse("x", "ed25519-public:AAAA")
+	require.Error(t, err)
+}
+
+// TestSignedSealedWithoutTrust reads a signed, encrypted payload the way
+// subscribe does without --verify.
+func TestSignedSealedWithoutTrust(t *testing.T) {
+	signer, err := Generate("alice", Ed25519, "")
+	require.NoError(t, err)
+	topicKey, err := Generate("news-key", Symmetric, "news")
+	require.NoError(t, err)
+
+	sealed, err := SealSymmetric(topicKey, "news", []byte("hello"))
+	require.NoError(t, err)
+	signed, err := Sign(signer, "news", sealed)
+	require.NoError(t, err)
+
+	var trust *Keyring
+	inner, v := trust.Verify(signed, "news")
+	require.Equal(t, Unverified, v.Status)
+	require.False(t, v.OK())
+	require.Equal(t, signer.ID(), v.KeyID)
+	require.True(t, IsSealed(inner))
+
+	keys := &Keyring{Keys: []Key{topicKey}}
+	plain, err := keys.Open(inner, "news")
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(plain))
+
+	// unsigned payloads pass through untouched
+	inner, v = trust.Verify(sealed, "news")
+	require.Equal(t, Unsigned, v.Status)
+	require.Equal(t, sealed, inner)
+}
@@ -4,11 +4,10 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"math"
 
 	pb "github.com/getoptimum/mump2p-cli/proto"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/connectivity"
 )
 
 const (
@@ -22,14 +21,13 @@ type Client struct {
 	client pb.CommandStreamClient
 }
 
-func NewClient(nodeAddr string) (*Client, error) {
-	conn, err := grpc.NewClient(nodeAddr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithDefaultCallOptions(
-			grpc.MaxCallRecvMsgSize(math.MaxInt),
-			grpc.MaxCallSendMsgSize(math.MaxInt),
-		),
-	)
+func NewClient(nodeAddr string, opts ...DialOption) (*Client, error) {
+	dc := dialConfig{tuning: DefaultTuning()}
+	for _, o := range opts {
+		o(&dc)
+	}
+
+	conn, err := grpc.NewClient(nodeAddr, dc.grpcOptions()...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to node %s: %w", nodeAddr, err)
 	}
@@ -40,8 +38,10 @@ func NewClient(nodeAddr string) (*Client, error) {
 }
 
 // Subscribe opens a bidi stream, sends a subscribe command, and returns a
-// channel that delivers raw message payloads received from the mesh.
-func (c *Client) Subscribe(ctx context.Context, ticket, topic string, bufSize int) (<-chan *pb.Response, error) {
+// channel that delivers raw message payloads received from the mesh. The
+// channel closes when the stream ends; if it ends with an error before ctx
+// is done, onErr (when not nil) is called with it first.
+func (c *Client) Subscribe(ctx context.Context, ticket, topic string, bufSize int, onErr func(error)) (<-chan *pb.Response, error) {
 	stream, err := c.client.ListenCommands(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open command stream: %w", err)
@@ -64,10 +64,8 @@ func (c *Client) Subscribe(ctx context.Context, ticket, topic string, bufSize in
 				return
 			}
 			if err != nil {
-				select {
-				case <-ctx.Done():
-				default:
-					fmt.Printf("stream error: %v\n", err)
+				if ctx.Err() == nil && onErr != nil {
+					onErr(err)
 				}
 				return
 			}
@@ -113,6 +111,138 @@ func (c *Client) Publish(ctx context.Context, ticket, topic string, data []byte)
 	return resp, nil
 }
 
+// PublishStream publishes a series of messages over one command stream,
+// for callers that send many messages to the same node. It is not safe for
+// concurrent use. Once Publish fails the stream is broken and has to be
+// reopened.
+type PublishStream struct {
+	stream pb.CommandStream_ListenCommandsClient
+	cancel context.CancelFunc
+	ticket string
+	topic  string
+	acks   chan *pb.Response
+	done   chan struct{}
+	err    error // why the stream ended, set before done is closed
+}
+
+// OpenPublishStream opens a command stream for publishing to topic with
+// ticket. It stays open until Close is called or the node ends it.
+func (c *Client) OpenPublishStream(ticket, topic string) (*PublishStream, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := c.client.ListenCommands(ctx)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to open command stream: %w", err)
+	}
+	p := &PublishStream{
+		stream: stream,
+		cancel: cancel,
+		ticket: ticket,
+		topic:  topic,
+		acks:   make(chan *pb.Response, 16),
+		done:   make(chan struct{}),
+	}
+	go p.recv()
+	return p, nil
+}
+
+func (p *PublishStream) recv() {
+	defer close(p.done)
+	for {
+		resp, err := p.stream.Recv()
+		if err == io.EOF {
+			p.err = fmt.Errorf("node closed stream (EOF)")
+			return
+		}
+		if err != nil {
+			p.err = err
+			return
+		}
+		select {
+		case p.acks <- resp:
+		default:
+			// nobody is waiting for this many responses; drop it
+		}
+	}
+}
+
+// Publish sends data and waits for the node's response (typically a
+// MessageTrace confirmation).
+func (p *PublishStream) Publish(ctx context.Context, data []byte) (*pb.Response, error) {
+	// drop responses to earlier messages that arrived late
+	for len(p.acks) > 0 {
+		<-p.acks
+	}
+
+	if err := p.stream.Send(&pb.Request{
+		Command:  CommandPublishData,
+		Topic:    p.topic,
+		Data:     data,
+		JwtToken: p.ticket,
+	}); err != nil {
+		if err == io.EOF {
+			// the stream has ended; the reason comes from Recv
+			<-p.done
+			err = p.err
+		}
+		return nil, fmt.Errorf("failed to send publish command: %w", err)
+	}
+
+	select {
+	case resp := <-p.acks:
+		return resp, nil
+	case <-p.done:
+		select {
+		case resp := <-p.acks:
+			return resp, nil
+		default:
+		}
+		return nil, fmt.Errorf("failed to receive publish response: %w", p.err)
+	case <-ctx.Done():
+		return nil, fmt.Errorf("failed to receive publish response: %w", ctx.Err())
+	}
+}
+
+// Close ends the stream.
+func (p *PublishStream) Close() error {
+	err := p.stream.CloseSend()
+	p.cancel()
+	return err
+}
+
+// WaitReady connects to the node and blocks until the connection is ready,
+// the connection attempt fails, or ctx is done.
+func (c *Client) WaitReady(ctx context.Context) error {
+	c.conn.Connect()
+	for state := c.conn.GetState(); state != connectivity.Ready; state = c.conn.GetState() {
+		if state == connectivity.TransientFailure {
+			return fmt.Errorf("failed to connect to node %s", c.conn.Target())
+		}
+		if !c.conn.WaitForStateChange(ctx, state) {
+			return fmt.Errorf("failed to connect to node %s: %w", c.conn.Target(), ctx.Err())
+		}
+	}
+	return nil
+}
+
+// Health returns the node's mode, resource usage and location.
+func (c *Client) Health(ctx context.Context) (*pb.HealthResponse, error) {
+	resp, err := c.client.Health(ctx, &pb.Void{})
+	if err != nil {
+		return nil, fmt.Errorf("health request failed: %w", err)
+	}
+	return resp, nil
+}
+
+// ListTopics returns the topics the node currently has subscribers for.
+func (c *Client) ListTopics(ctx context.Context) ([]string, error) {
+	resp, err := c.client.ListTopics(ctx, &pb.Void{})
+	if err != nil {
+		return nil, fmt.Errorf("list topics request failed: %w", err)
+	}
+	return resp.GetTopics(), nil
+}
+
 func (c *Client) Close() error {
 	return c.conn.Close()
 }
@@ -0,0 +1,117 @@
+package node_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/getoptimum/mump2p-cli/internal/fakemesh"
+	"github.com/getoptimum/mump2p-cli/internal/node"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestClientHealthAndTopics tests the Health and ListTopics wrappers.
+func TestClientHealthAndTopics(t *testing.T) {
+	m := fakemesh.New(t, 1)
+	sess := m.Session(t, "sub", []string{"subscribe"}, "demo")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	fakemesh.Subscribe(ctx, t, sess.Nodes[0], "demo")
+	m.WaitSubscribers(t, "demo", 1)
+
+	c, err := node.NewClient(sess.Nodes[0].Address)
+	require.NoError(t, err)
+	defer c.Close()
+	require.NoError(t, c.WaitReady(ctx))
+
+	h, err := c.Health(ctx)
+	require.NoError(t, err)
+	require.True(t, h.GetStatus())
+	require.NotEmpty(t, h.GetP2PAddress())
+
+	topics, err := c.ListTopics(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"demo"}, topics)
+}
+
+// TestClientWaitReadyUnreachable tests that an unreachable node fails fast.
+func TestClientWaitReadyUnreachable(t *testing.T) {
+	m := fakemesh.New(t, 1)
+	addr := m.Node(1).Addr()
+	m.Node(1).Kill()
+
+	c, err := node.NewClient(addr)
+	require.NoError(t, err)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	require.Error(t, c.WaitReady(ctx))
+	require.Less(t, time.Since(start), 2*time.Second)
+}
+
+// TestPublishStream tests publishing several messages over one stream and
+// that a stream ended by the node fails the next publish.
+func TestPublishStream(t *testing.T) {
+	m := fakemesh.New(t, 1)
+	sess := m.Session(t, "pub", []string{"publish", "subscribe"}, "demo")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	msgs := fakemesh.Subscribe(ctx, t, sess.Nodes[0], "demo")
+	m.WaitSubscribers(t, "demo", 1)
+
+	c, err := node.NewClient(sess.Nodes[0].Address)
+	require.NoError(t, err)
+	defer c.Close()
+	ps, err := c.OpenPublishStream(sess.Nodes[0].Ticket, "demo")
+	require.NoError(t, err)
+	defer ps.Close()
+
+	for i := 0; i < 5; i++ {
+		resp, err := ps.Publish(ctx, []byte(fmt.Sprintf("msg-%d", i)))
+		require.NoError(t, err)
+		require.NotNil(t, resp)
+	}
+	require.Len(t, fakemesh.Collect(msgs, 500*time.Millisecond), 5)
+
+	bad, err := c.OpenPublishStream("not-a-ticket", "demo")
+	require.NoError(t, err)
+	defer bad.Close()
+	_, err = bad.Publish(ctx, []byte("x"))
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	m.Node(1).Kill()
+	_, err = ps.Publish(ctx, []byte("after kill"))
+	require.Error(t, err)
+}
+
+// TestSubscribeStreamError tests that an error ending a subscription is
+// passed to onErr rather than printed.
+func TestSubscribeStreamError(t *testing.T) {
+	m := fakemesh.New(t, 1)
+	sess := m.Session(t, "sub", []string{"subscribe"}, "demo")
+
+	c, err := node.NewClient(sess.Nodes[0].Address)
+	require.NoError(t, err)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	errs := make(chan error, 1)
+	ch, err := c.Subscribe(ctx, "not-a-ticket", "demo", 10, func(err error) { errs <- err })
+	require.NoError(t, err)
+	for range ch {
+	}
+	select {
+	case err := <-errs:
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+	default:
+		t.Fatal("onErr was not called before the channel closed")
+	}
+}
@@ -0,0 +1,143 @@
+package node
+
+import (
+	"crypto/tls"
+	"fmt"
+	"math"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+)
+
+const (
+	// DefaultMaxRecvSize caps a single message from a node when the account's
+	// message size limit is unknown.
+	DefaultMaxRecvSize = 16 << 20
+	// recvOverhead leaves room for the response envelope and trace data
+	// around a payload of the maximum message size.
+	recvOverhead = 64 << 10
+	// minRecvSize is gRPC's own default, kept as a floor.
+	minRecvSize = 4 << 20
+)
+
+// Tuning controls the gRPC connection to a node.
+type Tuning struct {
+	// Keepalive is the interval between pings while a stream is open but
+	// quiet, which keeps NAT mappings alive for quiet subscriptions.
+	// Negative disables pings. Nodes close connections that ping more often
+	// than every 5m, gRPC's default server policy.
+	Keepalive time.Duration
+	// KeepaliveTimeout is how long to wait for a ping ack before closing
+	// the connection.
+	KeepaliveTimeout time.Duration
+	// MaxRecvSize caps the size of a single message received from a node.
+	MaxRecvSize int
+	// ConnectTimeout bounds each connection attempt.
+	ConnectTimeout time.Duration
+	// BackoffBase and BackoffMax bound the delay between reconnect attempts.
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+}
+
+// DefaultTuning returns the settings used when none are given.
+func DefaultTuning() Tuning {
+	return Tuning{
+		Keepalive:        5 * time.Minute,
+		KeepaliveTimeout: 10 * time.Second,
+		MaxRecvSize:      DefaultMaxRecvSize,
+		ConnectTimeout:   10 * time.Second,
+		BackoffBase:      time.Second,
+		BackoffMax:       30 * time.Second,
+	}
+}
+
+// RecvSizeFor returns the receive cap for an account whose messages are at
+// most maxMessage bytes; zero or less means unknown.
+func RecvSizeFor(maxMessage int64) int {
+	if maxMessage <= 0 {
+		return DefaultMaxRecvSize
+	}
+	return max(int(maxMessage)+recvOverhead, minRecvSize)
+}
+
+func (t Tuning) String() string {
+	keepalive := "off"
+	if t.Keepalive > 0 {
+		keepalive = fmt.Sprintf("%s (timeout %s)", t.Keepalive, t.KeepaliveTimeout)
+	}
+	return fmt.Sprintf("keepalive %s, max recv %d bytes, connect timeout %s, backoff %s..%s",
+		keepalive, t.MaxRecvSize, t.ConnectTimeout, t.BackoffBase, t.BackoffMax)
+}
+
+type dialConfig struct {
+	tls    *tls.Config
+	tuning Tuning
+}
+
+// DialOption configures how NewClient connects to a node.
+type DialOption func(*dialConfig)
+
+// WithTLS secures the connection with cfg instead of plaintext.
+func WithTLS(cfg *tls.Config) DialOption {
+	return func(d *dialConfig) { d.tls = cfg }
+}
+
+// WithDefaults fills zero fields from DefaultTuning.
+func (t Tuning) WithDefaults() Tuning {
+	def := DefaultTuning()
+	if t.Keepalive == 0 {
+		t.Keepalive = def.Keepalive
+	}
+	t.KeepaliveTimeout = orDefault(t.KeepaliveTimeout, def.KeepaliveTimeout)
+	t.ConnectTimeout = orDefault(t.ConnectTimeout, def.ConnectTimeout)
+	t.BackoffBase = orDefault(t.BackoffBase, def.BackoffBase)
+	t.BackoffMax = max(orDefault(t.BackoffMax, def.BackoffMax), t.BackoffBase)
+	if t.MaxRecvSize <= 0 {
+		t.MaxRecvSize = def.MaxRecvSize
+	}
+	return t
+}
+
+// WithTuning replaces DefaultTuning; see Tuning.WithDefaults.
+func WithTuning(t Tuning) DialOption {
+	return func(d *dialConfig) { d.tuning = t.WithDefaults() }
+}
+
+func orDefault(d, def time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return def
+}
+
+func (d dialConfig) grpcOptions() []grpc.DialOption {
+	creds := insecure.NewCredentials()
+	if d.tls != nil {
+		creds = credentials.NewTLS(d.tls)
+	}
+
+	t := d.tuning
+	bc := backoff.DefaultConfig
+	bc.BaseDelay = t.BackoffBase
+	bc.MaxDelay = t.BackoffMax
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultCallOptions(
+			grpc.MaxCallRecvMsgSize(t.MaxRecvSize),
+			grpc.MaxCallSendMsgSize(math.MaxInt),
+		),
+		grpc.WithConnectParams(grpc.ConnectParams{Backoff: bc, MinConnectTimeout: t.ConnectTimeout}),
+	}
+	if t.Keepalive > 0 {
+		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                t.Keepalive,
+			Timeout:             t.KeepaliveTimeout,
+			PermitWithoutStream: false,
+		}))
+	}
+	return opts
+}
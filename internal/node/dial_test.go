@@ -0,0 +1,76 @@
+package node_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/getoptimum/mump2p-cli/internal/fakemesh"
+	"github.com/getoptimum/mump2p-cli/internal/node"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMaxRecvSize tests that a node cannot push a message larger than the
+// receive cap.
+func TestMaxRecvSize(t *testing.T) {
+	m := fakemesh.New(t, 1)
+	sess := m.Session(t, "sub", []string{"subscribe", "publish"}, "demo")
+	n := sess.Nodes[0]
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	subscribe := func(tuning node.Tuning) <-chan []byte {
+		c, err := node.NewClient(n.Address, node.WithTuning(tuning))
+		require.NoError(t, err)
+		t.Cleanup(func() { c.Close() })
+		ch, err := c.Subscribe(ctx, n.Ticket, "demo", 10, nil)
+		require.NoError(t, err)
+		out := make(chan []byte, 10)
+		go func() {
+			defer close(out)
+			for resp := range ch {
+				out <- resp.GetData()
+			}
+		}()
+		return out
+	}
+	capped := subscribe(node.Tuning{MaxRecvSize: 1024})
+	open := subscribe(node.Tuning{Keepalive: 10 * time.Second})
+	m.WaitSubscribers(t, "demo", 2)
+
+	payload := bytes.Repeat([]byte("x"), 4096)
+	_, err := fakemesh.Publish(ctx, n, "demo", payload)
+	require.NoError(t, err)
+
+	select {
+	case data := <-open:
+		require.Greater(t, len(data), len(payload))
+	case <-ctx.Done():
+		t.Fatal("uncapped subscriber got nothing")
+	}
+	select {
+	case data, ok := <-capped:
+		require.False(t, ok, "capped stream delivered %d bytes", len(data))
+	case <-ctx.Done():
+		t.Fatal("capped stream stayed open")
+	}
+}
+
+// TestTuningDefaults tests how unset fields and the receive cap are derived.
+func TestTuningDefaults(t *testing.T) {
+	def := node.DefaultTuning()
+	require.Equal(t, def, node.Tuning{}.WithDefaults())
+	require.GreaterOrEqual(t, def.Keepalive, 5*time.Minute, "keepalive pings respect gRPC's default server policy")
+	require.Contains(t, node.Tuning{Keepalive: -1}.WithDefaults().String(), "keepalive off")
+
+	tuned := node.Tuning{Keepalive: time.Minute, BackoffBase: time.Minute}.WithDefaults()
+	require.Equal(t, time.Minute, tuned.Keepalive)
+	require.Equal(t, time.Minute, tuned.BackoffMax, "max backoff is never below the base")
+	require.Equal(t, def.ConnectTimeout, tuned.ConnectTimeout)
+
+	require.Equal(t, node.DefaultMaxRecvSize, node.RecvSizeFor(0))
+	require.Equal(t, 4<<20, node.RecvSizeFor(1<<20), "never below gRPC's default")
+	require.Equal(t, 10<<20+64<<10, node.RecvSizeFor(10<<20))
+}
@@ -0,0 +1,172 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	pb "github.com/getoptimum/mump2p-cli/proto"
+)
+
+// PublishTarget is one node a hedged publish may be sent to.
+type PublishTarget struct {
+	Address string
+	Ticket  string
+	Data    []byte
+	// Dial configures the connection, e.g. WithTLS.
+	Dial []DialOption
+}
+
+// HedgeOptions controls how a publish is spread across targets.
+//
+// With the zero value targets are tried one after another, moving on only
+// when an attempt fails. A positive Delay also starts the next target when
+// the running attempts have not been acknowledged within Delay. Fanout
+// starts that many targets at once.
+type HedgeOptions struct {
+	Delay   time.Duration
+	Fanout  int
+	Timeout time.Duration
+
+	// OnStart, if set, is called before each attempt is started.
+	OnStart func(i int, t PublishTarget)
+}
+
+// Attempt outcomes.
+const (
+	AttemptWon       = "won"
+	AttemptFailed    = "failed"
+	AttemptCancelled = "cancelled"
+	AttemptLate      = "late"
+	AttemptSkipped   = "skipped"
+)
+
+// Attempt is the outcome of publishing to one target. Latency is reported
+// in output as LatencyMs, in line with the other *_ms fields.
+type Attempt struct {
+	Address   string        `json:"address" yaml:"address"`
+	Status    string        `json:"status" yaml:"status"`
+	Latency   time.Duration `json:"-" yaml:"-"`
+	LatencyMs int64         `json:"latency_ms" yaml:"latency_ms"`
+	Error     string        `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// HedgeResult reports which target acknowledged the publish first.
+// Attempts has one entry per target, in target order.
+type HedgeResult struct {
+	Winner   int
+	Response *pb.Response
+	Attempts []Attempt
+}
+
+type attemptResult struct {
+	i       int
+	resp    *pb.Response
+	err     error
+	latency time.Duration
+}
+
+// HedgedPublish publishes data to targets according to opts. The first
+// acknowledgement wins and the remaining attempts are cancelled. Every
+// started attempt is a real publish, so a message may reach the mesh more
+// than once when several nodes accept it before cancellation.
+func HedgedPublish(ctx context.Context, topic string, targets []PublishTarget, opts HedgeOptions) (*HedgeResult, error) {
+	if len(targets) == 0 {
+		return nil, errors.New("no nodes to publish to")
+	}
+	fanout := max(opts.Fanout, 1)
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	res := &HedgeResult{Winner: -1, Attempts: make([]Attempt, len(targets))}
+	for i, t := range targets {
+		res.Attempts[i] = Attempt{Address: t.Address, Status: AttemptSkipped}
+	}
+
+	hctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan attemptResult, len(targets))
+	next, running := 0, 0
+	start := func() {
+		i, t := next, targets[next]
+		next++
+		running++
+		if opts.OnStart != nil {
+			opts.OnStart(i, t)
+		}
+		go func() {
+			started := time.Now()
+			resp, err := publishOnce(hctx, t, topic, timeout)
+			results <- attemptResult{i: i, resp: resp, err: err, latency: time.Since(started)}
+		}()
+	}
+
+	for next < len(targets) && next < fanout {
+		start()
+	}
+
+	var hedge <-chan time.Time
+	resetHedge := func() {
+		if opts.Delay > 0 && next < len(targets) {
+			hedge = time.After(opts.Delay)
+		} else {
+			hedge = nil
+		}
+	}
+	resetHedge()
+
+	for running > 0 {
+		select {
+		case <-hedge:
+			start()
+			resetHedge()
+		case r := <-results:
+			running--
+			a := &res.Attempts[r.i]
+			a.Latency, a.LatencyMs = r.latency, r.latency.Milliseconds()
+			switch {
+			case r.err == nil && res.Winner < 0:
+				a.Status = AttemptWon
+				res.Winner = r.i
+				res.Response = r.resp
+				cancel()
+			case r.err == nil:
+				// also accepted, after the winner
+				a.Status = AttemptLate
+			case res.Winner >= 0:
+				a.Status = AttemptCancelled
+			default:
+				a.Status = AttemptFailed
+				a.Error = r.err.Error()
+				// a failed attempt is replaced right away
+				if next < len(targets) && ctx.Err() == nil {
+					start()
+					resetHedge()
+				}
+			}
+		}
+	}
+
+	if res.Winner < 0 {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		return res, fmt.Errorf("all %d node(s) failed to publish", next)
+	}
+	return res, nil
+}
+
+func publishOnce(ctx context.Context, t PublishTarget, topic string, timeout time.Duration) (*pb.Response, error) {
+	c, err := NewClient(t.Address, t.Dial...)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return c.Publish(ctx, t.Ticket, topic, t.Data)
+}
@@ -0,0 +1,106 @@
+package node_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/getoptimum/mump2p-cli/internal/fakemesh"
+	"github.com/getoptimum/mump2p-cli/internal/node"
+	"github.com/stretchr/testify/require"
+)
+
+func hedgeTargets(t *testing.T, m *fakemesh.Mesh) []node.PublishTarget {
+	sess := m.Session(t, "pub", []string{"publish"}, "demo")
+	targets := make([]node.PublishTarget, len(sess.Nodes))
+	for i, n := range sess.Nodes {
+		targets[i] = node.PublishTarget{Address: n.Address, Ticket: n.Ticket, Data: []byte("hello")}
+	}
+	return targets
+}
+
+// TestHedgedPublish tests sequential, hedged and fan-out publishing.
+func TestHedgedPublish(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(t *testing.T, m *fakemesh.Mesh)
+		opts     node.HedgeOptions
+		winner   int
+		statuses []string
+		maxWait  time.Duration
+	}{
+		{
+			name:     "sequential uses the first node",
+			opts:     node.HedgeOptions{},
+			winner:   0,
+			statuses: []string{node.AttemptWon, node.AttemptSkipped, node.AttemptSkipped},
+		},
+		{
+			name:     "sequential moves on after a failure",
+			setup:    func(t *testing.T, m *fakemesh.Mesh) { m.Node(1).Kill() },
+			opts:     node.HedgeOptions{Timeout: time.Second},
+			winner:   1,
+			statuses: []string{node.AttemptFailed, node.AttemptWon, node.AttemptSkipped},
+		},
+		{
+			name:     "hedge starts the next node when the first is slow",
+			setup:    func(t *testing.T, m *fakemesh.Mesh) { m.SetLink(t, 1, fakemesh.Link{Latency: 2 * time.Second}) },
+			opts:     node.HedgeOptions{Delay: 50 * time.Millisecond},
+			winner:   1,
+			statuses: []string{node.AttemptCancelled, node.AttemptWon, node.AttemptSkipped},
+			maxWait:  time.Second,
+		},
+		{
+			name: "fanout sends to several nodes at once",
+			setup: func(t *testing.T, m *fakemesh.Mesh) {
+				m.SetLink(t, 1, fakemesh.Link{Latency: 2 * time.Second})
+				m.SetLink(t, 2, fakemesh.Link{Latency: 2 * time.Second})
+			},
+			opts:     node.HedgeOptions{Fanout: 3},
+			winner:   2,
+			statuses: []string{node.AttemptCancelled, node.AttemptCancelled, node.AttemptWon},
+			maxWait:  time.Second,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m := fakemesh.New(t, 3)
+			targets := hedgeTargets(t, m)
+			if tc.setup != nil {
+				tc.setup(t, m)
+			}
+
+			started := time.Now()
+			res, err := node.HedgedPublish(context.Background(), "demo", targets, tc.opts)
+			require.NoError(t, err)
+			if tc.maxWait > 0 {
+				require.Less(t, time.Since(started), tc.maxWait, "slow node should not hold up the publish")
+			}
+			require.Equal(t, tc.winner, res.Winner)
+			require.NotNil(t, res.Response)
+
+			got := make([]string, len(res.Attempts))
+			for i, a := range res.Attempts {
+				got[i] = a.Status
+			}
+			require.Equal(t, tc.statuses, got)
+		})
+	}
+}
+
+// TestHedgedPublishAllFail tests the error when no node acknowledges.
+func TestHedgedPublishAllFail(t *testing.T) {
+	m := fakemesh.New(t, 2)
+	targets := hedgeTargets(t, m)
+	m.Node(1).Kill()
+	m.Node(2).Kill()
+
+	res, err := node.HedgedPublish(context.Background(), "demo", targets, node.HedgeOptions{Timeout: time.Second})
+	require.ErrorContains(t, err, "all 2 node(s) failed")
+	require.Equal(t, -1, res.Winner)
+	for _, a := range res.Attempts {
+		require.Equal(t, node.AttemptFailed, a.Status)
+		require.NotEmpty(t, a.Error)
+	}
+}
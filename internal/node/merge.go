@@ -0,0 +1,139 @@
+package node
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/getoptimum/mump2p-cli/internal/entities"
+	pb "github.com/getoptimum/mump2p-cli/proto"
+)
+
+// DefaultDedupSize is the number of recent message keys a Merger remembers.
+const DefaultDedupSize = 10000
+
+// Arrival is a response tagged with the index of the stream that delivered
+// it and when it was received.
+type Arrival struct {
+	Stream   int
+	Response *pb.Response
+	Received time.Time
+}
+
+// Merger fans in several subscription streams and drops messages already
+// delivered by another stream. Messages are keyed by P2PMessage.MessageID,
+// or by a hash of the payload when the ID is absent. Trace responses are
+// passed through untouched.
+type Merger struct {
+	mu         sync.Mutex
+	seen       *keyLRU
+	wins       []int
+	duplicates int
+}
+
+// NewMerger creates a merger for the given number of streams remembering up
+// to dedupSize keys. A dedupSize of 0 disables deduplication.
+func NewMerger(streams, dedupSize int) *Merger {
+	m := &Merger{wins: make([]int, streams)}
+	if dedupSize > 0 {
+		m.seen = newKeyLRU(dedupSize)
+	}
+	return m
+}
+
+// Run merges streams into one channel, which closes once every stream has
+// closed or ctx is done.
+func (m *Merger) Run(ctx context.Context, streams []<-chan *pb.Response, bufSize int) <-chan Arrival {
+	out := make(chan Arrival, bufSize)
+	var wg sync.WaitGroup
+	for i, ch := range streams {
+		wg.Add(1)
+		go func(i int, ch <-chan *pb.Response) {
+			defer wg.Done()
+			for resp := range ch {
+				if !m.admit(i, resp) {
+					continue
+				}
+				select {
+				case out <- Arrival{Stream: i, Response: resp, Received: time.Now()}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(i, ch)
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// admit records a response from stream i and reports whether it is the
+// first copy.
+func (m *Merger) admit(i int, resp *pb.Response) bool {
+	if resp.GetCommand() != pb.ResponseType_Message {
+		return true
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.seen != nil && !m.seen.add(messageKey(resp.GetData())) {
+		m.duplicates++
+		return false
+	}
+	m.wins[i]++
+	return true
+}
+
+// Wins returns, per stream, how many messages it delivered first.
+func (m *Merger) Wins() []int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]int(nil), m.wins...)
+}
+
+// Duplicates returns the number of suppressed copies.
+func (m *Merger) Duplicates() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.duplicates
+}
+
+func messageKey(raw []byte) string {
+	if msg, err := entities.UnmarshalP2PMessage(raw); err == nil {
+		if msg.MessageID != "" {
+			return "id:" + msg.MessageID
+		}
+		raw = msg.Message
+	}
+	sum := sha256.Sum256(raw)
+	return "sha:" + string(sum[:])
+}
+
+// keyLRU is a bounded set that evicts the least recently added key.
+type keyLRU struct {
+	size  int
+	order *list.List
+	keys  map[string]*list.Element
+}
+
+func newKeyLRU(size int) *keyLRU {
+	return &keyLRU{size: size, order: list.New(), keys: make(map[string]*list.Element, size)}
+}
+
+// add inserts key and reports whether it was new.
+func (l *keyLRU) add(key string) bool {
+	if e, ok := l.keys[key]; ok {
+		l.order.MoveToFront(e)
+		return false
+	}
+	l.keys[key] = l.order.PushFront(key)
+	if l.order.Len() > l.size {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.keys, oldest.Value.(string))
+	}
+	return true
+}
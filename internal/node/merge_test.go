@@ -0,0 +1,126 @@
+package node_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/getoptimum/mump2p-cli/internal/entities"
+	"github.com/getoptimum/mump2p-cli/internal/fakemesh"
+	"github.com/getoptimum/mump2p-cli/internal/node"
+	pb "github.com/getoptimum/mump2p-cli/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func p2pResponse(t *testing.T, id, payload string) *pb.Response {
+	data, err := (&entities.P2PMessage{Topic: "demo", MessageID: id, Message: []byte(payload)}).Marshal()
+	require.NoError(t, err)
+	return &pb.Response{Command: pb.ResponseType_Message, Data: data}
+}
+
+func feed(resps ...*pb.Response) <-chan *pb.Response {
+	ch := make(chan *pb.Response, len(resps))
+	for _, r := range resps {
+		ch <- r
+	}
+	close(ch)
+	return ch
+}
+
+func drain(ch <-chan node.Arrival) []node.Arrival {
+	var got []node.Arrival
+	for a := range ch {
+		got = append(got, a)
+	}
+	return got
+}
+
+// TestMergerDedup tests deduplication by message ID and by payload hash.
+func TestMergerDedup(t *testing.T) {
+	trace := &pb.Response{Command: pb.ResponseType_MessageTraceMumP2P, Data: []byte(`{}`)}
+
+	m := node.NewMerger(2, node.DefaultDedupSize)
+	got := drain(m.Run(context.Background(), []<-chan *pb.Response{
+		feed(p2pResponse(t, "a", "one"), trace, p2pResponse(t, "", "no id")),
+		feed(p2pResponse(t, "a", "one"), p2pResponse(t, "b", "two"), trace, p2pResponse(t, "", "no id")),
+	}, 10))
+
+	var messages, traces int
+	for _, a := range got {
+		require.False(t, a.Received.IsZero())
+		if a.Response.GetCommand() == pb.ResponseType_Message {
+			messages++
+		} else {
+			traces++
+		}
+	}
+	require.Equal(t, 3, messages)
+	require.Equal(t, 2, traces, "traces are not deduplicated")
+	require.Equal(t, 2, m.Duplicates())
+
+	wins := m.Wins()
+	require.Equal(t, 3, wins[0]+wins[1])
+	require.GreaterOrEqual(t, wins[1], 1, "only stream 1 carried b")
+}
+
+// TestMergerBounded tests that the dedup window forgets the oldest keys.
+func TestMergerBounded(t *testing.T) {
+	m := node.NewMerger(1, 2)
+	got := drain(m.Run(context.Background(), []<-chan *pb.Response{
+		feed(p2pResponse(t, "a", ""), p2pResponse(t, "b", ""), p2pResponse(t, "c", ""), p2pResponse(t, "a", ""), p2pResponse(t, "c", "")),
+	}, 10))
+	require.Len(t, got, 4, "a was evicted and is delivered again; c is still remembered")
+	require.Equal(t, 1, m.Duplicates())
+
+	m = node.NewMerger(1, 0)
+	got = drain(m.Run(context.Background(), []<-chan *pb.Response{
+		feed(p2pResponse(t, "a", ""), p2pResponse(t, "a", "")),
+	}, 10))
+	require.Len(t, got, 2, "size 0 disables deduplication")
+}
+
+// TestMergerRedundantSubscribe tests first-arrival accounting across slow and fast nodes.
+func TestMergerRedundantSubscribe(t *testing.T) {
+	mesh := fakemesh.New(t, 3)
+	sess := mesh.Session(t, "sub", []string{"subscribe", "publish"}, "demo")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var streams []<-chan *pb.Response
+	for _, n := range sess.Nodes {
+		c, err := node.NewClient(n.Address)
+		require.NoError(t, err)
+		t.Cleanup(func() { c.Close() })
+		ch, err := c.Subscribe(ctx, n.Ticket, "demo", 100, nil)
+		require.NoError(t, err)
+		streams = append(streams, ch)
+	}
+	mesh.WaitSubscribers(t, "demo", 3)
+	mesh.SetLink(t, 1, fakemesh.Link{Latency: 100 * time.Millisecond})
+	mesh.SetLink(t, 3, fakemesh.Link{Latency: 100 * time.Millisecond})
+
+	m := node.NewMerger(len(streams), node.DefaultDedupSize)
+	merged := m.Run(ctx, streams, 100)
+
+	for i := 0; i < 5; i++ {
+		_, err := fakemesh.Publish(ctx, sess.Nodes[0], "demo", []byte{byte(i)})
+		require.NoError(t, err)
+	}
+
+	var got int
+	timeout := time.After(2 * time.Second)
+	for got < 5 {
+		select {
+		case a := <-merged:
+			if a.Response.GetCommand() == pb.ResponseType_Message {
+				got++
+			}
+		case <-timeout:
+			t.Fatalf("received %d of 5 messages", got)
+		}
+	}
+	require.Equal(t, []int{0, 5, 0}, m.Wins(), "node 2 has no added latency")
+
+	require.Eventually(t, func() bool { return m.Duplicates() == 10 }, 2*time.Second, 10*time.Millisecond)
+}
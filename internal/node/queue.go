@@ -0,0 +1,295 @@
+package node
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/getoptimum/mump2p-cli/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+// Overflow selects what a Queue does when its consumer falls behind.
+type Overflow string
+
+const (
+	// OverflowBlock stops reading from the streams until there is room,
+	// which can stall them until the node gives up on us.
+	OverflowBlock Overflow = "block"
+	// OverflowDropNewest discards arriving messages while the queue is full.
+	OverflowDropNewest Overflow = "drop-newest"
+	// OverflowDropOldest discards the oldest queued message to make room.
+	OverflowDropOldest Overflow = "drop-oldest"
+	// OverflowSpill moves messages that do not fit in memory to a file,
+	// dropping the newest once the file reaches its limit.
+	OverflowSpill Overflow = "spill"
+)
+
+// DefaultQueueSize is the number of messages a Queue holds in memory.
+const DefaultQueueSize = 1000
+
+// DefaultSpillMax is the default limit on spilled bytes.
+const DefaultSpillMax = 256 << 20
+
+// ParseOverflow validates an --overflow value; empty means block.
+func ParseOverflow(s string) (Overflow, error) {
+	switch o := Overflow(strings.ToLower(s)); o {
+	case "":
+		return OverflowBlock, nil
+	case OverflowBlock, OverflowDropNewest, OverflowDropOldest, OverflowSpill:
+		return o, nil
+	default:
+		return "", fmt.Errorf("unknown overflow policy %q (use block, drop-newest, drop-oldest or spill)", s)
+	}
+}
+
+// QueueOptions configures a Queue.
+type QueueOptions struct {
+	Overflow Overflow
+	// Size is the number of messages held in memory; zero uses
+	// DefaultQueueSize.
+	Size int
+	// SpillDir holds the spill file; empty uses the system temp dir.
+	SpillDir string
+	// SpillMax limits the bytes on disk; zero uses DefaultSpillMax.
+	SpillMax int64
+}
+
+// QueueStats counts what happened to messages that did not fit.
+type QueueStats struct {
+	Queued     int    `json:"queued" yaml:"queued"`
+	Dropped    uint64 `json:"dropped" yaml:"dropped"`
+	Spilled    uint64 `json:"spilled" yaml:"spilled"`
+	SpillBytes int64  `json:"spill_bytes" yaml:"spill_bytes"`
+}
+
+// Queue decouples the stream reader from a slow consumer. It keeps reading
+// arrivals and applies the overflow policy when the consumer falls behind.
+type Queue struct {
+	opts QueueOptions
+
+	mu    sync.Mutex
+	stats QueueStats
+}
+
+// NewQueue creates a queue with opts.
+func NewQueue(opts QueueOptions) *Queue {
+	if opts.Size <= 0 {
+		opts.Size = DefaultQueueSize
+	}
+	if opts.SpillMax <= 0 {
+		opts.SpillMax = DefaultSpillMax
+	}
+	if opts.Overflow == "" {
+		opts.Overflow = OverflowBlock
+	}
+	return &Queue{opts: opts}
+}
+
+// Stats returns the current counters.
+func (q *Queue) Stats() QueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.stats
+}
+
+// Run forwards in to the returned channel, which closes once in is closed
+// and drained, or ctx is done. The spill file, if any, is removed then.
+func (q *Queue) Run(ctx context.Context, in <-chan Arrival) (<-chan Arrival, error) {
+	var sp *spillFile
+	if q.opts.Overflow == OverflowSpill {
+		var err error
+		if sp, err = newSpillFile(q.opts.SpillDir); err != nil {
+			return nil, err
+		}
+	}
+	out := make(chan Arrival)
+	go q.loop(ctx, in, out, sp)
+	return out, nil
+}
+
+func (q *Queue) loop(ctx context.Context, in <-chan Arrival, out chan<- Arrival, sp *spillFile) {
+	defer close(out)
+	if sp != nil {
+		defer sp.remove()
+	}
+
+	var mem []Arrival
+	for {
+		// refill memory from disk, oldest first
+		for sp != nil && sp.count() > 0 && len(mem) < q.opts.Size {
+			a, err := sp.pop()
+			if err != nil {
+				q.count(func(s *QueueStats) { s.Dropped++ })
+				continue
+			}
+			mem = append(mem, a)
+		}
+		q.count(func(s *QueueStats) {
+			s.Queued = len(mem)
+			if sp != nil {
+				s.Queued += sp.count()
+				s.SpillBytes = sp.size()
+			}
+		})
+
+		var (
+			send chan<- Arrival
+			next Arrival
+		)
+		if len(mem) > 0 {
+			send, next = out, mem[0]
+		}
+		recv := in
+		if q.opts.Overflow == OverflowBlock && len(mem) >= q.opts.Size {
+			recv = nil
+		}
+		if recv == nil && send == nil {
+			return
+		}
+
+		select {
+		case a, ok := <-recv:
+			if !ok {
+				in = nil
+				continue
+			}
+			mem = q.push(mem, a, sp)
+		case send <- next:
+			mem = mem[1:]
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// push applies the overflow policy to an arriving message.
+func (q *Queue) push(mem []Arrival, a Arrival, sp *spillFile) []Arrival {
+	switch {
+	case sp != nil && (sp.count() > 0 || len(mem) >= q.opts.Size):
+		// once spilling, later messages queue behind the spilled ones
+		if err := sp.push(a, q.opts.SpillMax); err != nil {
+			q.count(func(s *QueueStats) { s.Dropped++ })
+		} else {
+			q.count(func(s *QueueStats) { s.Spilled++ })
+		}
+	case len(mem) < q.opts.Size:
+		mem = append(mem, a)
+	case q.opts.Overflow == OverflowDropOldest:
+		mem = append(mem[1:], a)
+		q.count(func(s *QueueStats) { s.Dropped++ })
+	default:
+		q.count(func(s *QueueStats) { s.Dropped++ })
+	}
+	return mem
+}
+
+func (q *Queue) count(f func(*QueueStats)) {
+	q.mu.Lock()
+	f(&q.stats)
+	q.mu.Unlock()
+}
+
+// spillFile is a FIFO of arrivals on disk. Records are appended at the end
+// and read from the front; the file is truncated whenever it empties and
+// compacted once the consumed front outgrows the unread records, so its size
+// on disk stays within the limit even if it never empties.
+type spillFile struct {
+	f     *os.File
+	read  int64
+	write int64
+	sizes []int
+}
+
+func newSpillFile(dir string) (*spillFile, error) {
+	f, err := os.CreateTemp(dir, "mump2p-spill-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spill file: %w", err)
+	}
+	return &spillFile{f: f}, nil
+}
+
+func (s *spillFile) count() int { return len(s.sizes) }
+
+func (s *spillFile) size() int64 { return s.write - s.read }
+
+func (s *spillFile) push(a Arrival, limit int64) error {
+	data, err := proto.Marshal(a.Response)
+	if err != nil {
+		return err
+	}
+	rec := binary.AppendUvarint(nil, uint64(a.Stream))
+	rec = binary.AppendVarint(rec, a.Received.UnixNano())
+	rec = append(rec, data...)
+	if s.read > 0 && (s.read >= s.size() || s.write+int64(len(rec)) > limit) {
+		if err := s.compact(); err != nil {
+			return err
+		}
+	}
+	if s.write+int64(len(rec)) > limit {
+		return fmt.Errorf("spill file full")
+	}
+	if _, err := s.f.WriteAt(rec, s.write); err != nil {
+		return err
+	}
+	s.write += int64(len(rec))
+	s.sizes = append(s.sizes, len(rec))
+	return nil
+}
+
+func (s *spillFile) pop() (Arrival, error) {
+	n := s.sizes[0]
+	s.sizes = s.sizes[1:]
+	rec := make([]byte, n)
+	_, err := s.f.ReadAt(rec, s.read)
+	s.read += int64(n)
+	if len(s.sizes) == 0 {
+		s.read, s.write = 0, 0
+		_ = s.f.Truncate(0)
+	}
+	if err != nil {
+		return Arrival{}, err
+	}
+
+	stream, k := binary.Uvarint(rec)
+	if k <= 0 {
+		return Arrival{}, fmt.Errorf("corrupt spill record")
+	}
+	received, j := binary.Varint(rec[k:])
+	if j <= 0 {
+		return Arrival{}, fmt.Errorf("corrupt spill record")
+	}
+	resp := &pb.Response{}
+	if err := proto.Unmarshal(rec[k+j:], resp); err != nil {
+		return Arrival{}, err
+	}
+	return Arrival{Stream: int(stream), Response: resp, Received: time.Unix(0, received)}, nil
+}
+
+// compact moves the unread records to the start of the file and truncates
+// the rest.
+func (s *spillFile) compact() error {
+	buf := make([]byte, 64<<10)
+	var off int64
+	for off < s.size() {
+		n, err := s.f.ReadAt(buf[:min(int64(len(buf)), s.size()-off)], s.read+off)
+		if err != nil && n == 0 {
+			return err
+		}
+		if _, err := s.f.WriteAt(buf[:n], off); err != nil {
+			return err
+		}
+		off += int64(n)
+	}
+	s.read, s.write = 0, s.size()
+	return s.f.Truncate(s.write)
+}
+
+func (s *spillFile) remove() {
+	s.f.Close()
+	os.Remove(s.f.Name())
+}
@@ -0,0 +1,163 @@
+package node_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/getoptimum/mump2p-cli/internal/node"
+	pb "github.com/getoptimum/mump2p-cli/proto"
+	"github.com/stretchr/testify/require"
+)
+
+// arrivals returns a closed channel holding n arrivals whose data and
+// receive time are their index.
+func arrivals(n int) <-chan node.Arrival {
+	ch := make(chan node.Arrival, n)
+	for i := range n {
+		ch <- node.Arrival{
+			Stream:   i % 2,
+			Response: &pb.Response{Command: pb.ResponseType_Message, Data: []byte(strconv.Itoa(i))},
+			Received: time.Unix(int64(i), 0),
+		}
+	}
+	close(ch)
+	return ch
+}
+
+func indexes(got []node.Arrival) []string {
+	var out []string
+	for _, a := range got {
+		out = append(out, string(a.Response.GetData()))
+	}
+	return out
+}
+
+// TestQueueOverflow tests each policy against a consumer that only starts
+// reading once all input has arrived.
+func TestQueueOverflow(t *testing.T) {
+	cases := []struct {
+		overflow node.Overflow
+		want     []string
+		dropped  uint64
+		spilled  uint64
+	}{
+		{node.OverflowDropNewest, []string{"0", "1"}, 3, 0},
+		{node.OverflowDropOldest, []string{"3", "4"}, 3, 0},
+		{node.OverflowSpill, []string{"0", "1", "2", "3", "4"}, 0, 3},
+	}
+	for _, c := range cases {
+		t.Run(string(c.overflow), func(t *testing.T) {
+			dir := t.TempDir()
+			q := node.NewQueue(node.QueueOptions{Overflow: c.overflow, Size: 2, SpillDir: dir})
+			out, err := q.Run(context.Background(), arrivals(5))
+			require.NoError(t, err)
+			require.Eventually(t, func() bool {
+				s := q.Stats()
+				return s.Dropped+s.Spilled == 3
+			}, time.Second, time.Millisecond)
+
+			got := drain(out)
+			require.Equal(t, c.want, indexes(got))
+			for _, a := range got {
+				i, _ := strconv.Atoi(string(a.Response.GetData()))
+				require.Equal(t, i%2, a.Stream, "stream survives the spill file")
+				require.Equal(t, int64(i), a.Received.Unix())
+			}
+			s := q.Stats()
+			require.Equal(t, c.dropped, s.Dropped)
+			require.Equal(t, c.spilled, s.Spilled)
+			require.Zero(t, s.Queued)
+
+			files, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			require.Empty(t, files, "spill file is removed")
+		})
+	}
+}
+
+// TestQueueBlock tests that the block policy delivers everything by holding
+// back the input.
+func TestQueueBlock(t *testing.T) {
+	q := node.NewQueue(node.QueueOptions{Size: 2})
+	in := arrivals(5)
+	out, err := q.Run(context.Background(), in)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return len(in) == 3 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	require.Len(t, in, 3, "input is not read while the queue is full")
+
+	require.Equal(t, []string{"0", "1", "2", "3", "4"}, indexes(drain(out)))
+	require.Zero(t, q.Stats().Dropped)
+}
+
+// TestQueueSpillLimit tests that messages beyond the spill limit are dropped
+// and later ones still spill once there is room.
+func TestQueueSpillLimit(t *testing.T) {
+	in := make(chan node.Arrival)
+	q := node.NewQueue(node.QueueOptions{Overflow: node.OverflowSpill, Size: 1, SpillDir: t.TempDir(), SpillMax: 40})
+	out, err := q.Run(context.Background(), in)
+	require.NoError(t, err)
+
+	send := func(data string) {
+		in <- node.Arrival{Response: &pb.Response{Data: []byte(data)}}
+	}
+	send("mem")
+	send("spilled-1")
+	send("too-big-for-the-limit")
+	require.Eventually(t, func() bool { return q.Stats().Dropped == 1 }, time.Second, time.Millisecond)
+	require.Equal(t, uint64(1), q.Stats().Spilled)
+	require.Positive(t, q.Stats().SpillBytes)
+
+	require.Equal(t, "mem", string((<-out).Response.GetData()))
+	require.Equal(t, "spilled-1", string((<-out).Response.GetData()))
+	send("spilled-2")
+	close(in)
+	require.Equal(t, []string{"spilled-2"}, indexes(drain(out)))
+}
+
+// TestParseOverflow tests flag parsing.
+// TestQueueSpillReclaim tests that a spill file which is pushed to and popped
+// from in turn, without ever emptying, stays within the limit on disk.
+func TestQueueSpillReclaim(t *testing.T) {
+	dir := t.TempDir()
+	in := make(chan node.Arrival)
+	q := node.NewQueue(node.QueueOptions{Overflow: node.OverflowSpill, Size: 1, SpillDir: dir, SpillMax: 512})
+	out, err := q.Run(context.Background(), in)
+	require.NoError(t, err)
+
+	send := func(i int) {
+		in <- node.Arrival{Response: &pb.Response{Data: []byte(fmt.Sprintf("message-%04d", i))}}
+	}
+	for i := range 3 {
+		send(i)
+	}
+	for i := 3; i < 1000; i++ {
+		send(i)
+		require.Equal(t, fmt.Sprintf("message-%04d", i-3), string((<-out).Response.GetData()))
+
+		files, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+		info, err := files[0].Info()
+		require.NoError(t, err)
+		require.LessOrEqual(t, info.Size(), int64(512))
+	}
+	require.Zero(t, q.Stats().Dropped)
+	close(in)
+	require.Len(t, drain(out), 3)
+}
+
+func TestParseOverflow(t *testing.T) {
+	o, err := node.ParseOverflow("")
+	require.NoError(t, err)
+	require.Equal(t, node.OverflowBlock, o)
+	o, err = node.ParseOverflow("Drop-Oldest")
+	require.NoError(t, err)
+	require.Equal(t, node.OverflowDropOldest, o)
+	_, err = node.ParseOverflow("discard")
+	require.Error(t, err)
+}
@@ -0,0 +1,176 @@
+package node_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/getoptimum/mump2p-cli/internal/node"
+	pb "github.com/getoptimum/mump2p-cli/proto"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+type healthServer struct {
+	pb.UnimplementedCommandStreamServer
+}
+
+func (healthServer) Health(context.Context, *pb.Void) (*pb.HealthResponse, error) {
+	return &pb.HealthResponse{Status: true, NodeMode: "tls"}, nil
+}
+
+type testCert struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certFile string
+	keyFile  string
+}
+
+// issue creates a certificate signed by parent, or a self-signed CA when
+// parent is nil, and writes it to dir as PEM.
+func issue(t *testing.T, dir, name string, parent *testCert, tmpl *x509.Certificate) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl.SerialNumber = big.NewInt(time.Now().UnixNano())
+	tmpl.Subject = pkix.Name{CommonName: name}
+	tmpl.NotBefore = time.Now().Add(-time.Minute)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+	signer, signerKey := tmpl, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	c := &testCert{cert: cert, key: key,
+		certFile: filepath.Join(dir, name+".pem"), keyFile: filepath.Join(dir, name+"-key.pem")}
+	require.NoError(t, os.WriteFile(c.certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(c.keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return c
+}
+
+type testPKI struct {
+	ca, server, client *testCert
+}
+
+func newPKI(t *testing.T) *testPKI {
+	dir := t.TempDir()
+	ca := issue(t, dir, "ca", nil, &x509.Certificate{
+		IsCA: true, BasicConstraintsValid: true, KeyUsage: x509.KeyUsageCertSign,
+	})
+	return &testPKI{
+		ca: ca,
+		// only valid for node.test, so dialing 127.0.0.1 needs a server name override
+		server: issue(t, dir, "server", ca, &x509.Certificate{
+			DNSNames: []string{"node.test"}, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		}),
+		client: issue(t, dir, "client", ca, &x509.Certificate{
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}),
+	}
+}
+
+// serveTLS starts a node serving Health over TLS, requiring a client
+// certificate when mutual is set.
+func serveTLS(t *testing.T, p *testPKI, mutual bool) string {
+	t.Helper()
+	cert, err := tls.LoadX509KeyPair(p.server.certFile, p.server.keyFile)
+	require.NoError(t, err)
+	cfg := &tls.Config{Certificates: []tls.Certificate{cert}}
+	if mutual {
+		pool := x509.NewCertPool()
+		pool.AddCert(p.ca.cert)
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpc.NewServer(grpc.Creds(credentials.NewTLS(cfg)))
+	pb.RegisterCommandStreamServer(srv, healthServer{})
+	go srv.Serve(l) //nolint:errcheck
+	t.Cleanup(srv.Stop)
+	return l.Addr().String()
+}
+
+func health(t *testing.T, addr string, opts ...node.DialOption) error {
+	t.Helper()
+	c, err := node.NewClient(addr, opts...)
+	require.NoError(t, err)
+	defer c.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	h, err := c.Health(ctx)
+	if err == nil {
+		require.Equal(t, "tls", h.GetNodeMode())
+	}
+	return err
+}
+
+func withTLS(t *testing.T, cfg node.TLSConfig) node.DialOption {
+	t.Helper()
+	tc, err := cfg.Load()
+	require.NoError(t, err)
+	return node.WithTLS(tc)
+}
+
+// TestClientTLS tests server verification against a custom CA and the
+// server name override.
+func TestClientTLS(t *testing.T) {
+	p := newPKI(t)
+	addr := serveTLS(t, p, false)
+
+	require.NoError(t, health(t, addr, withTLS(t, node.TLSConfig{CAFile: p.ca.certFile, ServerName: "node.test"})))
+	require.Error(t, health(t, addr, withTLS(t, node.TLSConfig{CAFile: p.ca.certFile})), "certificate is not valid for 127.0.0.1")
+	require.Error(t, health(t, addr, withTLS(t, node.TLSConfig{ServerName: "node.test"})), "CA is not in the system roots")
+	require.Error(t, health(t, addr), "plaintext against a TLS node")
+}
+
+// TestClientMutualTLS tests that a client certificate is presented when configured.
+func TestClientMutualTLS(t *testing.T) {
+	p := newPKI(t)
+	addr := serveTLS(t, p, true)
+
+	require.NoError(t, health(t, addr, withTLS(t, node.TLSConfig{
+		CAFile: p.ca.certFile, ServerName: "node.test",
+		CertFile: p.client.certFile, KeyFile: p.client.keyFile,
+	})))
+	require.Error(t, health(t, addr, withTLS(t, node.TLSConfig{CAFile: p.ca.certFile, ServerName: "node.test"})))
+}
+
+// TestTLSConfigLoad tests config validation.
+func TestTLSConfigLoad(t *testing.T) {
+	p := newPKI(t)
+
+	_, err := node.TLSConfig{CertFile: p.client.certFile}.Load()
+	require.Error(t, err, "certificate without key")
+
+	_, err = node.TLSConfig{CAFile: p.client.keyFile}.Load()
+	require.Error(t, err, "key is not a CA bundle")
+
+	_, err = node.TLSConfig{CAFile: filepath.Join(t.TempDir(), "missing.pem")}.Load()
+	require.Error(t, err)
+
+	require.True(t, node.UsesTLS("grpcs"))
+	require.True(t, node.UsesTLS("TLS"))
+	require.False(t, node.UsesTLS("grpc"))
+	require.False(t, node.UsesTLS(""))
+}
@@ -0,0 +1,182 @@
+package payload
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codec transforms payloads before publishing, e.g. compression. Encoded
+// payloads carry a header naming the codec, so subscribers can undo it
+// without being told which codec was used.
+type Codec interface {
+	// Name is how the codec is selected on the command line.
+	Name() string
+	// ID identifies the codec in the payload header and must never change
+	// once payloads have been published with it.
+	ID() byte
+	Encode([]byte) ([]byte, error)
+	Decode([]byte) ([]byte, error)
+}
+
+// MaxDecodedSize caps the size of a decoded payload so a small compressed
+// message cannot exhaust memory.
+const MaxDecodedSize = 64 << 20
+
+// codecMagic starts every encoded payload and is followed by the codec ID.
+var codecMagic = []byte{0xc3, 'M', 'P'}
+
+var (
+	codecMu     sync.RWMutex
+	codecByName = map[string]Codec{}
+	codecByID   = map[byte]Codec{}
+)
+
+// RegisterCodec makes c available to Wrap and Unwrap. It panics if the
+// name or ID is taken.
+func RegisterCodec(c Codec) {
+	codecMu.Lock()
+	defer codecMu.Unlock()
+	if _, ok := codecByName[c.Name()]; ok {
+		panic("payload: codec " + c.Name() + " registered twice")
+	}
+	if other, ok := codecByID[c.ID()]; ok {
+		panic(fmt.Sprintf("payload: codec %s reuses ID %d of %s", c.Name(), c.ID(), other.Name()))
+	}
+	codecByName[c.Name()] = c
+	codecByID[c.ID()] = c
+}
+
+// LookupCodec returns the codec registered as name.
+func LookupCodec(name string) (Codec, error) {
+	codecMu.RLock()
+	defer codecMu.RUnlock()
+	if c, ok := codecByName[name]; ok {
+		return c, nil
+	}
+	return nil, fmt.Errorf("unknown codec %q (available: %v)", name, codecNames())
+}
+
+// Codecs returns the names of the registered codecs.
+func Codecs() []string {
+	codecMu.RLock()
+	defer codecMu.RUnlock()
+	return codecNames()
+}
+
+func codecNames() []string {
+	names := make([]string, 0, len(codecByName))
+	for n := range codecByName {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Wrap encodes b with c and prepends the codec header.
+func Wrap(c Codec, b []byte) ([]byte, error) {
+	enc, err := c.Encode(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", c.Name(), err)
+	}
+	out := make([]byte, 0, len(codecMagic)+1+len(enc))
+	out = append(out, codecMagic...)
+	out = append(out, c.ID())
+	return append(out, enc...), nil
+}
+
+// Unwrap decodes a payload produced by Wrap and returns the codec that was
+// used. Payloads without a header are returned unchanged with a nil codec.
+func Unwrap(b []byte) ([]byte, Codec, error) {
+	if len(b) <= len(codecMagic) || !bytes.HasPrefix(b, codecMagic) {
+		return b, nil, nil
+	}
+	id := b[len(codecMagic)]
+	codecMu.RLock()
+	c, ok := codecByID[id]
+	codecMu.RUnlock()
+	if !ok {
+		return b, nil, fmt.Errorf("payload uses unknown codec %d", id)
+	}
+	dec, err := c.Decode(b[len(codecMagic)+1:])
+	if err != nil {
+		return b, c, fmt.Errorf("%s: %w", c.Name(), err)
+	}
+	return dec, c, nil
+}
+
+var errTooLarge = errors.New("decoded payload exceeds size limit")
+
+type gzipCodec struct{}
+
+func (gzipCodec) Name() string { return "gzip" }
+
+func (gzipCodec) ID() byte { return 1 }
+
+func (gzipCodec) Encode(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCodec) Decode(b []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	out, err := io.ReadAll(io.LimitReader(r, MaxDecodedSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > MaxDecodedSize {
+		return nil, errTooLarge
+	}
+	return out, nil
+}
+
+type zstdCodec struct{}
+
+var (
+	zstdEncoder = sync.OnceValues(func() (*zstd.Encoder, error) { return zstd.NewWriter(nil) })
+	zstdDecoder = sync.OnceValues(func() (*zstd.Decoder, error) {
+		return zstd.NewReader(nil, zstd.WithDecoderMaxMemory(MaxDecodedSize))
+	})
+)
+
+func (zstdCodec) Name() string { return "zstd" }
+
+func (zstdCodec) ID() byte { return 2 }
+
+func (zstdCodec) Encode(b []byte) ([]byte, error) {
+	enc, err := zstdEncoder()
+	if err != nil {
+		return nil, err
+	}
+	return enc.EncodeAll(b, nil), nil
+}
+
+func (zstdCodec) Decode(b []byte) ([]byte, error) {
+	dec, err := zstdDecoder()
+	if err != nil {
+		return nil, err
+	}
+	return dec.DecodeAll(b, nil)
+}
+
+func init() {
+	RegisterCodec(gzipCodec{})
+	RegisterCodec(zstdCodec{})
+}
@@ -0,0 +1,59 @@
+package payload
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestCodecRoundTrip tests that each built-in codec shrinks a repetitive
+// payload and that Unwrap detects it from the header.
+func TestCodecRoundTrip(t *testing.T) {
+	data := bytes.Repeat([]byte("sensor reading 21.5C; "), 200)
+	require.Equal(t, []string{"gzip", "zstd"}, Codecs())
+
+	for _, name := range Codecs() {
+		c, err := LookupCodec(name)
+		require.NoError(t, err)
+		wire, err := Wrap(c, data)
+		require.NoError(t, err)
+		require.Less(t, len(wire), len(data)/4, name)
+
+		got, used, err := Unwrap(wire)
+		require.NoError(t, err)
+		require.Equal(t, name, used.Name())
+		require.Equal(t, data, got)
+	}
+}
+
+// TestUnwrapPlain tests that payloads without a header pass through.
+func TestUnwrapPlain(t *testing.T) {
+	for _, b := range [][]byte{nil, []byte("hello"), codecMagic} {
+		got, used, err := Unwrap(b)
+		require.NoError(t, err)
+		require.Nil(t, used)
+		require.Equal(t, b, got)
+	}
+}
+
+// TestUnwrapErrors tests unknown codecs and corrupt or oversized data.
+func TestUnwrapErrors(t *testing.T) {
+	_, err := LookupCodec("brotli")
+	require.Error(t, err)
+
+	_, _, err = Unwrap(append(append([]byte{}, codecMagic...), 99, 1, 2))
+	require.ErrorContains(t, err, "unknown codec")
+
+	_, _, err = Unwrap(append(append([]byte{}, codecMagic...), 1, 1, 2))
+	require.ErrorContains(t, err, "gzip")
+
+	gz, err := LookupCodec("gzip")
+	require.NoError(t, err)
+	bomb, err := Wrap(gz, make([]byte, MaxDecodedSize+1))
+	require.NoError(t, err)
+	_, _, err = Unwrap(bomb)
+	require.ErrorIs(t, err, errTooLarge)
+
+	require.Panics(t, func() { RegisterCodec(gzipCodec{}) })
+}
@@ -0,0 +1,165 @@
+// Package payload converts message payloads between bytes on the wire and
+// the text forms used for display, persistence and command-line input.
+package payload
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Encoding selects how a payload is rendered as text.
+type Encoding string
+
+const (
+	// Auto shows text as is and anything else in a binary form.
+	Auto Encoding = "auto"
+	// Text always shows the payload as a string, even if that mangles it.
+	Text   Encoding = "text"
+	Hex    Encoding = "hex"
+	Base64 Encoding = "base64"
+	// Raw writes the payload bytes unchanged.
+	Raw Encoding = "raw"
+)
+
+// base64Prefix marks binary payloads in persisted Auto output so they can
+// be told apart from text.
+const base64Prefix = "base64:"
+
+// textPrefix marks quoted text in persisted Auto output: text that would
+// otherwise read as marked, or that spans lines.
+const textPrefix = "text:"
+
+// displayLimit is the number of bytes of a binary payload shown by Display.
+const displayLimit = 128
+
+// ParseEncoding validates a --payload-encoding value; empty means Auto.
+func ParseEncoding(s string) (Encoding, error) {
+	switch e := Encoding(strings.ToLower(s)); e {
+	case "":
+		return Auto, nil
+	case Auto, Text, Hex, Base64, Raw:
+		return e, nil
+	default:
+		return "", fmt.Errorf("unknown payload encoding %q (use auto, text, hex, base64 or raw)", s)
+	}
+}
+
+// IsText reports whether b is non-empty UTF-8 without control characters
+// other than whitespace.
+func IsText(b []byte) bool {
+	for _, c := range b {
+		if c < 0x20 && c != '\n' && c != '\r' && c != '\t' {
+			return false
+		}
+	}
+	return len(b) > 0 && utf8.Valid(b)
+}
+
+// Display renders b for a terminal. With Auto, binary payloads are
+// summarized as their size and leading bytes in hex.
+func Display(b []byte, e Encoding) string {
+	if e == Auto {
+		if IsText(b) {
+			return string(b)
+		}
+		if len(b) > displayLimit {
+			return fmt.Sprintf("[binary %d bytes] %x...", len(b), b[:displayLimit])
+		}
+		return fmt.Sprintf("[binary %d bytes] %x", len(b), b)
+	}
+	return Encode(b, e)
+}
+
+// Encode renders b without losing data. With Auto, text is kept as is and
+// binary payloads are written as "base64:" followed by base64; text that
+// starts with either marker is quoted after "text:".
+func Encode(b []byte, e Encoding) string {
+	switch e {
+	case Hex:
+		return hex.EncodeToString(b)
+	case Base64:
+		return base64.StdEncoding.EncodeToString(b)
+	case Text, Raw:
+		return string(b)
+	default:
+		if IsText(b) {
+			s := string(b)
+			if strings.HasPrefix(s, base64Prefix) || strings.HasPrefix(s, textPrefix) {
+				return textPrefix + strconv.Quote(s)
+			}
+			return s
+		}
+		return base64Prefix + base64.StdEncoding.EncodeToString(b)
+	}
+}
+
+// EncodeLine is Encode for line-based files: with Auto, text that spans
+// lines is quoted as well, so each payload stays on one line.
+func EncodeLine(b []byte, e Encoding) string {
+	if e == Auto && IsText(b) && strings.ContainsAny(string(b), "\r\n") {
+		return textPrefix + strconv.Quote(string(b))
+	}
+	return Encode(b, e)
+}
+
+// Decode parses s written in encoding e back into bytes. It accepts
+// what Encode produces, except for Raw and Text which are taken as is.
+func Decode(s string, e Encoding) ([]byte, error) {
+	switch e {
+	case Hex:
+		b, err := hex.DecodeString(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex payload: %v", err)
+		}
+		return b, nil
+	case Base64:
+		b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 payload: %v", err)
+		}
+		return b, nil
+	case Auto:
+		if rest, ok := strings.CutPrefix(s, base64Prefix); ok {
+			return Decode(rest, Base64)
+		}
+		if rest, ok := strings.CutPrefix(s, textPrefix); ok {
+			text, err := strconv.Unquote(rest)
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted text payload: %v", err)
+			}
+			return []byte(text), nil
+		}
+	}
+	return []byte(s), nil
+}
+
+// WriteFrame writes b as one record of a Raw persist file: its length as a
+// 4-byte big-endian integer, then the bytes.
+func WriteFrame(w io.Writer, b []byte) error {
+	if uint64(len(b)) > 1<<32-1 {
+		return fmt.Errorf("payload of %d bytes is too large to frame", len(b))
+	}
+	frame := binary.BigEndian.AppendUint32(make([]byte, 0, 4+len(b)), uint32(len(b)))
+	_, err := w.Write(append(frame, b...))
+	return err
+}
+
+// ReadFrame reads one record written by WriteFrame. It returns io.EOF when
+// r ends between records.
+func ReadFrame(r io.Reader) ([]byte, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, err
+	}
+	b := make([]byte, binary.BigEndian.Uint32(size[:]))
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return b, nil
+}
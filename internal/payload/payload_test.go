@@ -0,0 +1,103 @@
+package payload
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestRoundTrip tests that every lossless encoding decodes back to the
+// original bytes.
+func TestRoundTrip(t *testing.T) {
+	payloads := [][]byte{
+		[]byte("hello world"),
+		{0x00, 0x01, 0xff, 0x0a},
+		[]byte("base64:looks encoded"),
+	}
+	for _, p := range payloads {
+		for _, e := range []Encoding{Hex, Base64} {
+			got, err := Decode(Encode(p, e), e)
+			require.NoError(t, err)
+			require.Equal(t, p, got, "%s %q", e, p)
+		}
+	}
+
+	got, err := Decode(Encode(payloads[1], Auto), Auto)
+	require.NoError(t, err)
+	require.Equal(t, payloads[1], got)
+	require.Equal(t, "hello world", Encode(payloads[0], Auto))
+}
+
+// TestPersistRoundTrip tests that Auto persist lines and Raw frames read
+// back as the original payloads, including text that looks marked or spans
+// lines.
+func TestPersistRoundTrip(t *testing.T) {
+	payloads := [][]byte{
+		[]byte("hello world"),
+		{0x00, 0x01, 0xff, 0x0a},
+		[]byte("base64:aGk="),
+		[]byte(`text:"quoted"`),
+		[]byte("first line\nsecond line\r\n"),
+	}
+
+	var lines, frames bytes.Buffer
+	for _, p := range payloads {
+		lines.WriteString(EncodeLine(p, Auto) + "\n")
+		require.NoError(t, WriteFrame(&frames, p))
+	}
+
+	got := strings.Split(strings.TrimSuffix(lines.String(), "\n"), "\n")
+	require.Len(t, got, len(payloads), "one line per payload")
+	for i, line := range got {
+		b, err := Decode(line, Auto)
+		require.NoError(t, err)
+		require.Equal(t, payloads[i], b)
+	}
+	require.Equal(t, "hello world", got[0])
+
+	for _, p := range payloads {
+		b, err := ReadFrame(&frames)
+		require.NoError(t, err)
+		require.Equal(t, p, b)
+	}
+	_, err := ReadFrame(&frames)
+	require.ErrorIs(t, err, io.EOF)
+	_, err = ReadFrame(bytes.NewReader([]byte{0, 0, 0, 9, 'x'}))
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+// TestDisplay tests how binary payloads are summarized.
+func TestDisplay(t *testing.T) {
+	require.Equal(t, "hi", Display([]byte("hi"), Auto))
+	require.Equal(t, "[binary 2 bytes] 00ff", Display([]byte{0, 0xff}, Auto))
+
+	long := Display(bytes.Repeat([]byte{0}, 300), Auto)
+	require.True(t, strings.HasPrefix(long, "[binary 300 bytes] "))
+	require.True(t, strings.HasSuffix(long, "..."))
+
+	require.Equal(t, "00ff", Display([]byte{0, 0xff}, Hex))
+	require.Equal(t, "AP8=", Display([]byte{0, 0xff}, Base64))
+}
+
+// TestParse tests flag and input validation.
+func TestParse(t *testing.T) {
+	e, err := ParseEncoding("")
+	require.NoError(t, err)
+	require.Equal(t, Auto, e)
+	e, err = ParseEncoding("HEX")
+	require.NoError(t, err)
+	require.Equal(t, Hex, e)
+	_, err = ParseEncoding("binary")
+	require.Error(t, err)
+
+	_, err = Decode("zz", Hex)
+	require.Error(t, err)
+	_, err = Decode("not base64!", Base64)
+	require.Error(t, err)
+	require.False(t, IsText(nil))
+	require.False(t, IsText([]byte{0xff}))
+	require.True(t, IsText([]byte("tab\tand\nnewline")))
+}
@@ -0,0 +1,70 @@
+package payload
+
+import (
+	"fmt"
+	"os"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// ProtoCodec converts payloads of one protobuf message type to and from
+// JSON, using a descriptor set instead of generated code.
+type ProtoCodec struct {
+	desc  protoreflect.MessageDescriptor
+	types *dynamicpb.Types
+}
+
+// LoadProtoCodec reads a FileDescriptorSet, as written by
+// `protoc --include_imports --descriptor_set_out`, and looks up typeName,
+// e.g. "pkg.Msg".
+func LoadProtoCodec(descriptorFile, typeName string) (*ProtoCodec, error) {
+	raw, err := os.ReadFile(descriptorFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read proto descriptor: %w", err)
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(raw, &set); err != nil {
+		return nil, fmt.Errorf("invalid proto descriptor %s: %w", descriptorFile, err)
+	}
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proto descriptor %s (built with --include_imports?): %w", descriptorFile, err)
+	}
+	d, err := files.FindDescriptorByName(protoreflect.FullName(typeName))
+	if err != nil {
+		return nil, fmt.Errorf("proto type %s not found in %s", typeName, descriptorFile)
+	}
+	md, ok := d.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a message type", typeName)
+	}
+	return &ProtoCodec{desc: md, types: dynamicpb.NewTypes(files)}, nil
+}
+
+// Name returns the full name of the message type.
+func (c *ProtoCodec) Name() string {
+	return string(c.desc.FullName())
+}
+
+// ToJSON decodes a protobuf payload to JSON.
+func (c *ProtoCodec) ToJSON(b []byte) ([]byte, error) {
+	msg := dynamicpb.NewMessage(c.desc)
+	if err := (proto.UnmarshalOptions{Resolver: c.types}).Unmarshal(b, msg); err != nil {
+		return nil, fmt.Errorf("not a %s: %w", c.Name(), err)
+	}
+	return protojson.MarshalOptions{Resolver: c.types}.Marshal(msg)
+}
+
+// FromJSON encodes a JSON document as a protobuf payload.
+func (c *ProtoCodec) FromJSON(b []byte) ([]byte, error) {
+	msg := dynamicpb.NewMessage(c.desc)
+	if err := (protojson.UnmarshalOptions{Resolver: c.types}).Unmarshal(b, msg); err != nil {
+		return nil, fmt.Errorf("invalid %s JSON: %w", c.Name(), err)
+	}
+	return proto.Marshal(msg)
+}
@@ -0,0 +1,71 @@
+package payload
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pb "github.com/getoptimum/mump2p-cli/proto"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// writeDescriptorSet writes the descriptor of the node protocol, which
+// has no imports, as a FileDescriptorSet.
+func writeDescriptorSet(t *testing.T) string {
+	set := &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{
+		protodesc.ToFileDescriptorProto(pb.File_p2p_stream_proto),
+	}}
+	raw, err := proto.Marshal(set)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "p2p.pb")
+	require.NoError(t, os.WriteFile(path, raw, 0600))
+	return path
+}
+
+// TestProtoCodec tests decoding to JSON and encoding back.
+func TestProtoCodec(t *testing.T) {
+	c, err := LoadProtoCodec(writeDescriptorSet(t), "proto.HealthResponse")
+	require.NoError(t, err)
+	require.Equal(t, "proto.HealthResponse", c.Name())
+
+	wire, err := proto.Marshal(&pb.HealthResponse{Status: true, NodeMode: "optimum", MemoryUsed: 12.5})
+	require.NoError(t, err)
+	js, err := c.ToJSON(wire)
+	require.NoError(t, err)
+	var fields map[string]any
+	require.NoError(t, json.Unmarshal(js, &fields))
+	require.Equal(t, true, fields["status"])
+	require.Equal(t, "optimum", fields["nodeMode"])
+
+	back, err := c.FromJSON(js)
+	require.NoError(t, err)
+	var got pb.HealthResponse
+	require.NoError(t, proto.Unmarshal(back, &got))
+	require.Equal(t, "optimum", got.GetNodeMode())
+	require.InDelta(t, 12.5, got.GetMemoryUsed(), 0.001)
+
+	_, err = c.ToJSON([]byte{0xff, 0xff, 0xff})
+	require.Error(t, err)
+	_, err = c.FromJSON([]byte(`{"noSuchField": 1}`))
+	require.Error(t, err)
+}
+
+// TestLoadProtoCodecErrors tests bad descriptor files and type names.
+func TestLoadProtoCodecErrors(t *testing.T) {
+	path := writeDescriptorSet(t)
+	_, err := LoadProtoCodec(path, "proto.Missing")
+	require.ErrorContains(t, err, "not found")
+	_, err = LoadProtoCodec(path, "proto.CommandStream")
+	require.ErrorContains(t, err, "not a message")
+	_, err = LoadProtoCodec(filepath.Join(t.TempDir(), "none.pb"), "proto.Void")
+	require.Error(t, err)
+
+	garbage := filepath.Join(t.TempDir(), "garbage.pb")
+	require.NoError(t, os.WriteFile(garbage, []byte("not a descriptor"), 0600))
+	_, err = LoadProtoCodec(garbage, "proto.Void")
+	require.Error(t, err)
+}
@@ -1,6 +1,9 @@
 package ratelimit
 
-import "time"
+import (
+	"errors"
+	"time"
+)
 
 // UsageData represents persistent usage metrics
 type UsageData struct {
@@ -27,13 +30,23 @@ type UsageStats struct {
 	LastSubscribeTime   time.Time
 }
 
+// Limit types reported in LimitError.
+const (
+	LimitPerSecond   = "publish_per_second"
+	LimitPerHour     = "publish_per_hour"
+	LimitMessageSize = "message_size"
+	LimitDailyQuota  = "daily_quota"
+)
+
 // LimitError represents a rate limit exceeded error
 type LimitError struct {
 	Message      string
-	LimitType    string // "publish", "message_size", "daily_quota"
+	LimitType    string // one of the Limit* constants
 	CurrentUsage interface{}
 	Limit        interface{}
 	ResetTime    time.Time
+	// Err is a sentinel the error wraps, such as ErrPerSecondLimit.
+	Err error
 }
 
 // Error returns the error message
@@ -41,8 +54,13 @@ func (e *LimitError) Error() string {
 	return e.Message
 }
 
+// Unwrap returns Err.
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
+
 // IsRateLimitError checks if an error is a rate limit error
 func IsRateLimitError(err error) bool {
-	_, ok := err.(*LimitError)
-	return ok
+	var le *LimitError
+	return errors.As(err, &le)
 }
@@ -1,7 +1,9 @@
 package ratelimit
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -11,6 +13,13 @@ import (
 	"github.com/getoptimum/mump2p-cli/internal/auth"
 )
 
+// ErrPerSecondLimit is wrapped by CheckPublishAllowed when only the
+// per-second limit stands in the way; retrying a second later can succeed.
+var ErrPerSecondLimit = errors.New("per-second limit reached")
+
+// ErrInactive is returned by CheckPublishAllowed for an inactive token.
+var ErrInactive = errors.New("your token is inactive; please contact support or check your subscription status")
+
 // RateLimiter tracks and enforces rate limits
 // The CLI records locally the limit, and the proxy records it as well.
 type RateLimiter struct {
@@ -70,8 +79,15 @@ func NewRateLimiterWithDir(claims *auth.TokenClaims, customDir string) (*RateLim
 	return limiter, nil
 }
 
-// CheckPublishAllowed verifies if a publish operation is allowed
+// CheckPublishAllowed verifies if a publish operation is allowed. Exceeded
+// limits are returned as *LimitError.
 func (r *RateLimiter) CheckPublishAllowed(messageSize int64) error {
+	return r.CheckPublishesAllowed(1, messageSize)
+}
+
+// CheckPublishesAllowed is CheckPublishAllowed for count publishes of the
+// same message, such as one sent to several nodes.
+func (r *RateLimiter) CheckPublishesAllowed(count int, messageSize int64) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	now := time.Now()
@@ -80,12 +96,17 @@ func (r *RateLimiter) CheckPublishAllowed(messageSize int64) error {
 
 	// check if the user is active
 	if !r.tokenClaims.IsActive {
-		return fmt.Errorf("your token is inactive; please contact support or check your subscription status")
+		return ErrInactive
 	}
 
 	// message size limit
 	if messageSize > r.tokenClaims.MaxMessageSize {
-		return fmt.Errorf("message size exceeds limit of %d bytes", r.tokenClaims.MaxMessageSize)
+		return &LimitError{
+			Message:      fmt.Sprintf("message size exceeds limit of %d bytes", r.tokenClaims.MaxMessageSize),
+			LimitType:    LimitMessageSize,
+			CurrentUsage: messageSize,
+			Limit:        r.tokenClaims.MaxMessageSize,
+		}
 	}
 
 	// per-second check
@@ -93,10 +114,17 @@ func (r *RateLimiter) CheckPublishAllowed(messageSize int64) error {
 		r.usage.LastSecondTime = now
 		r.usage.SecondPublishCount = 0
 	}
-	if r.usage.SecondPublishCount >= r.tokenClaims.MaxPublishPerSec {
-		return fmt.Errorf("per-second limit reached (%d/sec)", r.tokenClaims.MaxPublishPerSec)
+	if r.usage.SecondPublishCount+count > r.tokenClaims.MaxPublishPerSec {
+		return &LimitError{
+			Message:      fmt.Sprintf("%v (%d/sec)", ErrPerSecondLimit, r.tokenClaims.MaxPublishPerSec),
+			LimitType:    LimitPerSecond,
+			CurrentUsage: r.usage.SecondPublishCount,
+			Limit:        r.tokenClaims.MaxPublishPerSec,
+			ResetTime:    r.usage.LastSecondTime.Add(time.Second),
+			Err:          ErrPerSecondLimit,
+		}
 	}
-	r.usage.SecondPublishCount++
+	r.usage.SecondPublishCount += count
 
 	// Save the updated per-second counter
 	if err := r.saveUsage(); err != nil {
@@ -105,29 +133,67 @@ func (r *RateLimiter) CheckPublishAllowed(messageSize int64) error {
 	}
 
 	// Per-hour check
-	if r.usage.PublishCount >= r.tokenClaims.MaxPublishPerHour {
+	if r.usage.PublishCount+count > r.tokenClaims.MaxPublishPerHour {
 		next := r.usage.LastReset.Add(24 * time.Hour)
-		return fmt.Errorf("per-hour limit reached (%d/hour), resets in %s",
-			r.tokenClaims.MaxPublishPerHour, time.Until(next).Round(time.Minute))
+		return &LimitError{
+			Message: fmt.Sprintf("per-hour limit reached (%d/hour), resets in %s",
+				r.tokenClaims.MaxPublishPerHour, time.Until(next).Round(time.Minute)),
+			LimitType:    LimitPerHour,
+			CurrentUsage: r.usage.PublishCount,
+			Limit:        r.tokenClaims.MaxPublishPerHour,
+			ResetTime:    next,
+		}
 	}
 
 	// daily quota
-	if r.usage.BytesPublished+messageSize > r.tokenClaims.DailyQuota {
+	total := r.usage.BytesPublished + int64(count)*messageSize
+	if total > r.tokenClaims.DailyQuota {
 		next := r.usage.LastReset.Add(24 * time.Hour)
-		return fmt.Errorf("daily quota exceeded (%d/%d bytes), resets in %s",
-			r.usage.BytesPublished+messageSize, r.tokenClaims.DailyQuota, time.Until(next).Round(time.Minute))
+		return &LimitError{
+			Message: fmt.Sprintf("daily quota exceeded (%d/%d bytes), resets in %s",
+				total, r.tokenClaims.DailyQuota, time.Until(next).Round(time.Minute)),
+			LimitType:    LimitDailyQuota,
+			CurrentUsage: total,
+			Limit:        r.tokenClaims.DailyQuota,
+			ResetTime:    next,
+		}
 	}
 
 	return nil
 }
 
+// WaitPublishAllowed is CheckPublishAllowed for callers that publish in a
+// loop: it waits for the next second instead of failing on the per-second
+// limit. Other limits still fail.
+func (r *RateLimiter) WaitPublishAllowed(ctx context.Context, messageSize int64) error {
+	for {
+		err := r.CheckPublishAllowed(messageSize)
+		if !errors.Is(err, ErrPerSecondLimit) {
+			return err
+		}
+		r.mu.Lock()
+		next := r.usage.LastSecondTime.Add(time.Second)
+		r.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Until(next)):
+		}
+	}
+}
+
 // RecordPublish records a successful publish operation
 func (r *RateLimiter) RecordPublish(size int64) error {
+	return r.RecordPublishes(1, size)
+}
+
+// RecordPublishes records count publishes of a message of size bytes.
+func (r *RateLimiter) RecordPublishes(count int, size int64) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	r.usage.PublishCount++
-	r.usage.BytesPublished += size
+	r.usage.PublishCount += count
+	r.usage.BytesPublished += int64(count) * size
 	r.usage.LastPublishTime = time.Now()
 	return r.saveUsage()
 }
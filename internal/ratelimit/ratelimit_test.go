@@ -1,6 +1,7 @@
 package ratelimit
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
@@ -95,6 +96,7 @@ func TestRateLimiter(t *testing.T) {
 			if tc.expectErr {
 				require.Error(t, err)
 				require.Contains(t, err.Error(), tc.expectLimitErr)
+				require.True(t, IsRateLimitError(err))
 			} else {
 				require.NoError(t, err)
 				err = rl.RecordPublish(tc.messageSize)
@@ -103,3 +105,60 @@ func TestRateLimiter(t *testing.T) {
 		})
 	}
 }
+
+// TestWaitPublishAllowed tests that the per-second limit is waited out
+// while other limits still fail.
+func TestWaitPublishAllowed(t *testing.T) {
+	claims := createTestClaims()
+	claims.MaxPublishPerHour = 100
+	cleanupUsageFile(claims)
+	defer cleanupUsageFile(claims)
+
+	rl, err := NewRateLimiter(claims)
+	require.NoError(t, err)
+
+	start := time.Now()
+	for range 3 {
+		require.NoError(t, rl.WaitPublishAllowed(context.Background(), 1024))
+	}
+	require.GreaterOrEqual(t, time.Since(start), 900*time.Millisecond)
+
+	require.NoError(t, rl.CheckPublishAllowed(1024))
+	require.ErrorIs(t, rl.CheckPublishAllowed(1024), ErrPerSecondLimit)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, rl.WaitPublishAllowed(ctx, 1024), context.Canceled)
+
+	err = rl.WaitPublishAllowed(context.Background(), 2*1024*1024)
+	require.ErrorContains(t, err, "message size exceeds limit")
+}
+
+// TestCheckPublishesAllowed tests that publishing one message to several
+// nodes counts every copy against the limits.
+func TestCheckPublishesAllowed(t *testing.T) {
+	claims := createTestClaims()
+	claims.MaxPublishPerSec = 100
+	cleanupUsageFile(claims)
+	defer cleanupUsageFile(claims)
+
+	rl, err := NewRateLimiter(claims)
+	require.NoError(t, err)
+
+	require.NoError(t, rl.CheckPublishesAllowed(3, 1024))
+	require.NoError(t, rl.RecordPublishes(3, 1024))
+	require.Equal(t, 3, rl.GetUsageStats().PublishCount)
+	require.Equal(t, int64(3*1024), rl.GetUsageStats().BytesPublished)
+
+	err = rl.CheckPublishesAllowed(3, 1024)
+	require.ErrorContains(t, err, "per-hour limit reached", "3 + 3 copies exceed 5/hour")
+
+	rl.usage.PublishCount = 0
+	rl.usage.BytesPublished = claims.DailyQuota - 2*1024
+	err = rl.CheckPublishesAllowed(3, 1024)
+	require.ErrorContains(t, err, "daily quota exceeded")
+	require.NoError(t, rl.CheckPublishesAllowed(2, 1024))
+
+	rl.usage.SecondPublishCount = claims.MaxPublishPerSec - 1
+	require.ErrorIs(t, rl.CheckPublishesAllowed(2, 1024), ErrPerSecondLimit)
+}
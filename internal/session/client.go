@@ -2,11 +2,23 @@ package session
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"strings"
 	"time"
+
+	"github.com/getoptimum/mump2p-cli/internal/httpclient"
+	pb "github.com/getoptimum/mump2p-cli/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 )
 
 type Node struct {
@@ -24,6 +36,63 @@ type Session struct {
 	ExpiresAt    string `json:"expires_at"`
 	RefreshAfter string `json:"refresh_after"`
 	Error        string `json:"error,omitempty"`
+	// Proxy is the endpoint that issued the session.
+	Proxy string `json:"-" yaml:"-"`
+}
+
+// Transport selects how sessions are requested from the proxy.
+type Transport string
+
+const (
+	// TransportHTTP posts JSON to the proxy's /api/v1/session endpoint.
+	TransportHTTP Transport = "http"
+	// TransportGRPC calls SessionService.CreateSession.
+	TransportGRPC Transport = "grpc"
+)
+
+// ParseTransport validates a session transport name; empty means HTTP.
+func ParseTransport(s string) (Transport, error) {
+	switch t := Transport(strings.ToLower(s)); t {
+	case "":
+		return TransportHTTP, nil
+	case TransportHTTP, TransportGRPC:
+		return t, nil
+	default:
+		return "", fmt.Errorf("unknown session transport %q (use http or grpc)", s)
+	}
+}
+
+// ParseProtocol validates a --protocol hint; empty lets the proxy decide.
+func ParseProtocol(s string) (string, error) {
+	switch p := strings.ToLower(s); p {
+	case "", "mump2p", "gossipsub":
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown protocol %q (use mump2p or gossipsub)", s)
+	}
+}
+
+// Request describes the session to create.
+type Request struct {
+	// Endpoint is the proxy base URL for HTTP, or the host:port of the
+	// SessionService for gRPC.
+	Endpoint string
+	// Fallbacks are tried after Endpoint on connection errors and 5xx
+	// replies. HTTP proxies are tried healthy first.
+	Fallbacks    []string
+	Transport    Transport
+	ClientID     string
+	AccessToken  string
+	Topics       []string
+	Capabilities []string
+	ExposeAmount uint32
+	// Protocol and Region are hints; the proxy may ignore them.
+	Protocol string
+	Region   string
+	// TLS secures the gRPC transport. When nil, endpoints other than
+	// loopback use TLS with the system roots unless Plaintext is set.
+	TLS       *tls.Config
+	Plaintext bool
 }
 
 type sessionRequest struct {
@@ -31,14 +100,85 @@ type sessionRequest struct {
 	Topics       []string `json:"topics"`
 	Capabilities []string `json:"capabilities"`
 	ExposeAmount uint32   `json:"expose_amount"`
+	Protocol     string   `json:"protocol,omitempty"`
+	RegionHint   string   `json:"region_hint,omitempty"`
 }
 
+// CreateSession requests a new session from the proxy over HTTP.
 func CreateSession(proxyURL, clientID, accessToken string, topics, capabilities []string, exposeAmount uint32) (*Session, error) {
-	reqData := sessionRequest{
+	return Create(Request{
+		Endpoint:     proxyURL,
 		ClientID:     clientID,
+		AccessToken:  accessToken,
 		Topics:       topics,
 		Capabilities: capabilities,
 		ExposeAmount: exposeAmount,
+	})
+}
+
+// Create requests a new session over the request's transport, failing over
+// to the next endpoint when one is unreachable or returns a 5xx.
+func Create(req Request) (*Session, error) {
+	endpoints := req.endpoints()
+	if len(endpoints) > 1 && req.Transport != TransportGRPC {
+		endpoints = OrderByHealth(context.Background(), endpoints)
+	}
+
+	var errs []error
+	for _, endpoint := range endpoints {
+		sess, err := createAt(req, endpoint)
+		if err == nil {
+			sess.Proxy = endpoint
+			return sess, nil
+		}
+		if len(endpoints) == 1 {
+			return nil, err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", endpoint, err))
+		if !retryable(err) {
+			break
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+func createAt(req Request, endpoint string) (*Session, error) {
+	req.Endpoint = endpoint
+	var (
+		sess *Session
+		err  error
+	)
+	switch req.Transport {
+	case TransportGRPC:
+		sess, err = createGRPC(req)
+	case TransportHTTP, "":
+		sess, err = createHTTP(req)
+	default:
+		return nil, fmt.Errorf("unknown session transport %q", req.Transport)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if sess.Error != "" {
+		return nil, fmt.Errorf("session error: %s", sess.Error)
+	}
+
+	if len(sess.Nodes) == 0 {
+		return nil, fmt.Errorf("no nodes available")
+	}
+
+	return sess, nil
+}
+
+func createHTTP(req Request) (*Session, error) {
+	reqData := sessionRequest{
+		ClientID:     req.ClientID,
+		Topics:       req.Topics,
+		Capabilities: req.Capabilities,
+		ExposeAmount: req.ExposeAmount,
+		Protocol:     req.Protocol,
+		RegionHint:   req.Region,
 	}
 
 	body, err := json.Marshal(reqData)
@@ -46,20 +186,20 @@ func CreateSession(proxyURL, clientID, accessToken string, topics, capabilities
 		return nil, fmt.Errorf("failed to marshal session request: %w", err)
 	}
 
-	url := proxyURL + "/api/v1/session"
+	url := req.Endpoint + "/api/v1/session"
 	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
-	if accessToken != "" {
-		httpReq.Header.Set("Authorization", "Bearer "+accessToken)
+	if req.AccessToken != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+req.AccessToken)
 	}
 
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := httpclient.Client(0)
 	resp, err := client.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("session request failed: %w", err)
+		return nil, fmt.Errorf("session request failed: %w", &transportError{err})
 	}
 	defer resp.Body.Close()
 
@@ -69,21 +209,88 @@ func CreateSession(proxyURL, clientID, accessToken string, topics, capabilities
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("proxy returned status %d: %s", resp.StatusCode, string(respBody))
+		return nil, &StatusError{Code: resp.StatusCode, Body: string(respBody)}
 	}
 
 	var sess Session
 	if err := json.Unmarshal(respBody, &sess); err != nil {
 		return nil, fmt.Errorf("failed to parse session response: %w", err)
 	}
+	return &sess, nil
+}
 
-	if sess.Error != "" {
-		return nil, fmt.Errorf("session error: %s", sess.Error)
+func createGRPC(req Request) (*Session, error) {
+	creds, tokenSafe := grpcCredentials(req)
+	if req.AccessToken != "" && !tokenSafe {
+		return nil, fmt.Errorf("refusing to send the access token to %s without TLS", req.Endpoint)
+	}
+	conn, err := grpc.NewClient(req.Endpoint, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to session service %s: %w", req.Endpoint, err)
 	}
+	defer conn.Close()
 
-	if len(sess.Nodes) == 0 {
-		return nil, fmt.Errorf("no nodes available")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if req.AccessToken != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+req.AccessToken)
 	}
 
-	return &sess, nil
+	resp, err := pb.NewSessionServiceClient(conn).CreateSession(ctx, &pb.SessionRequest{
+		ClientId:     req.ClientID,
+		Topics:       req.Topics,
+		Protocol:     req.Protocol,
+		RegionHint:   req.Region,
+		ExposeAmount: req.ExposeAmount,
+		Capabilities: req.Capabilities,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("session request failed: %w", err)
+	}
+
+	sess := &Session{
+		SessionID:    resp.GetSessionId(),
+		ExpiresAt:    resp.GetExpiresAt(),
+		RefreshAfter: resp.GetRefreshAfter(),
+		Error:        resp.GetError(),
+	}
+	for _, n := range resp.GetNodes() {
+		sess.Nodes = append(sess.Nodes, Node{
+			ID:        n.GetId(),
+			Address:   n.GetAddress(),
+			Transport: n.GetTransport(),
+			Region:    n.GetRegion(),
+			Ticket:    n.GetTicket(),
+			Score:     n.GetScore(),
+		})
+	}
+	return sess, nil
+}
+
+// grpcCredentials picks TLS for the session service unless the request
+// asks for plaintext or the endpoint is on this host. tokenSafe reports
+// whether the access token may be sent: over TLS, or to loopback.
+func grpcCredentials(req Request) (creds credentials.TransportCredentials, tokenSafe bool) {
+	loopback := isLoopback(req.Endpoint)
+	switch {
+	case req.TLS != nil:
+		return credentials.NewTLS(req.TLS), true
+	case req.Plaintext || loopback:
+		return insecure.NewCredentials(), loopback
+	default:
+		return credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12}), true
+	}
+}
+
+// isLoopback reports whether a host:port endpoint is on this host.
+func isLoopback(endpoint string) bool {
+	host, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		host = endpoint
+	}
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
 }
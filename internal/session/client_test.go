@@ -0,0 +1,97 @@
+package session
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/getoptimum/mump2p-cli/internal/devserver"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCreateTransports tests that HTTP and gRPC session creation return the same result shape.
+func TestCreateTransports(t *testing.T) {
+	srv, err := devserver.Start(devserver.Config{Nodes: 3, SessionGRPCAddr: "127.0.0.1:0"})
+	require.NoError(t, err)
+	t.Cleanup(srv.Close)
+
+	tests := []struct {
+		name string
+		req  Request
+	}{
+		{"http", Request{Endpoint: srv.URL(), Transport: TransportHTTP}},
+		{"grpc", Request{Endpoint: srv.SessionAddr(), Transport: TransportGRPC}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := tc.req
+			req.ClientID = "client"
+			req.Topics = []string{"demo"}
+			req.Capabilities = []string{"subscribe"}
+			req.ExposeAmount = 2
+			req.Region = "local-3"
+			req.Protocol = "gossipsub"
+
+			sess, err := Create(req)
+			require.NoError(t, err)
+			require.NotEmpty(t, sess.SessionID)
+			require.NotEmpty(t, sess.ExpiresAt)
+			require.Len(t, sess.Nodes, 2)
+			require.Equal(t, "local-3", sess.Nodes[0].Region, "region hint is sent")
+			require.NotEmpty(t, sess.Nodes[0].Ticket)
+
+			req.Protocol = "quic"
+			_, err = Create(req)
+			require.Error(t, err, "protocol hint is sent")
+		})
+	}
+}
+
+// TestGRPCSessionTLS tests when the gRPC transport uses TLS and that the
+// access token is never sent in plaintext off this host.
+func TestGRPCSessionTLS(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       Request
+		tls       bool
+		tokenSafe bool
+	}{
+		{"remote", Request{Endpoint: "proxy.example.com:50051"}, true, true},
+		{"remote plaintext", Request{Endpoint: "proxy.example.com:50051", Plaintext: true}, false, false},
+		{"loopback", Request{Endpoint: "127.0.0.1:50051"}, false, true},
+		{"localhost", Request{Endpoint: "localhost:50051"}, false, true},
+		{"ipv6 loopback", Request{Endpoint: "[::1]:50051"}, false, true},
+		{"loopback with tls config", Request{Endpoint: "localhost:50051", TLS: &tls.Config{}}, true, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			creds, tokenSafe := grpcCredentials(tc.req)
+			require.Equal(t, tc.tls, creds.Info().SecurityProtocol == "tls")
+			require.Equal(t, tc.tokenSafe, tokenSafe)
+		})
+	}
+
+	_, err := createGRPC(Request{Endpoint: "proxy.example.com:50051", Plaintext: true, AccessToken: "secret"})
+	require.ErrorContains(t, err, "without TLS")
+}
+
+// TestCachedSessionMatches tests that hints and transport are part of the cache key.
+func TestCachedSessionMatches(t *testing.T) {
+	req := Request{Endpoint: "http://proxy", ClientID: "c", Topics: []string{"a"}, Capabilities: []string{"publish"}}
+	c := &CachedSession{ProxyURL: "http://proxy", ClientID: "c", Topics: []string{"a", "b"}, Capabilities: []string{"publish"}}
+	require.True(t, c.matches(req))
+
+	req.Transport = TransportHTTP
+	require.True(t, c.matches(req), "http is the default transport")
+
+	withRegion := req
+	withRegion.Region = "eu"
+	require.False(t, c.matches(withRegion))
+
+	withProtocol := req
+	withProtocol.Protocol = "gossipsub"
+	require.False(t, c.matches(withProtocol))
+
+	grpcReq := req
+	grpcReq.Transport = TransportGRPC
+	require.False(t, c.matches(grpcReq))
+}
@@ -0,0 +1,171 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getoptimum/mump2p-cli/internal/httpclient"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// HealthTimeout bounds the /api/v1/health probe of each proxy before
+// failover.
+var HealthTimeout = 2 * time.Second
+
+// StatusError is a non-200 reply from the proxy.
+type StatusError struct {
+	Code int
+	Body string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("proxy returned status %d: %s", e.Code, e.Body)
+}
+
+// IsAuthError reports whether the proxy rejected the request's credentials:
+// an HTTP 401 or 403, or a gRPC Unauthenticated or PermissionDenied.
+func IsAuthError(err error) bool {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.Code == http.StatusUnauthorized || se.Code == http.StatusForbidden
+	}
+	if s, ok := status.FromError(err); ok {
+		return s.Code() == codes.Unauthenticated || s.Code() == codes.PermissionDenied
+	}
+	return false
+}
+
+// ParseEndpoints splits a comma-separated list of proxy URLs, dropping
+// blanks, duplicates and trailing slashes.
+func ParseEndpoints(s string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, e := range strings.Split(s, ",") {
+		e = strings.TrimRight(strings.TrimSpace(e), "/")
+		if e == "" || seen[e] {
+			continue
+		}
+		seen[e] = true
+		out = append(out, e)
+	}
+	return out
+}
+
+// endpoints returns Endpoint followed by the fallbacks.
+func (r Request) endpoints() []string {
+	out := []string{r.Endpoint}
+	for _, e := range r.Fallbacks {
+		if e != r.Endpoint {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// proxyKey identifies the proxies a request may be served by, independent of
+// which one answered.
+func (r Request) proxyKey() string {
+	return sortedKey(r.endpoints())
+}
+
+// retryable reports whether another proxy may succeed where this one failed:
+// connection errors and 5xx replies are, rejected requests are not.
+func retryable(err error) bool {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.Code >= 500
+	}
+	var te *transportError
+	if errors.As(err, &te) {
+		return true
+	}
+	if s, ok := status.FromError(err); ok {
+		switch s.Code() {
+		case codes.Unavailable, codes.DeadlineExceeded, codes.Internal:
+			return true
+		}
+	}
+	return false
+}
+
+// transportError marks a request that never got a reply.
+type transportError struct{ err error }
+
+func (e *transportError) Error() string { return e.err.Error() }
+func (e *transportError) Unwrap() error { return e.err }
+
+// Do sends the request that build makes for each proxy in turn, moving on
+// when one is unreachable or returns a 5xx, and returns the first other
+// response. The last proxy's 5xx response is returned as is.
+func Do(endpoints []string, build func(endpoint string) (*http.Request, error)) (*http.Response, error) {
+	var errs []error
+	for i, e := range endpoints {
+		req, err := build(e)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := httpclient.Client(0).Do(req)
+		if err != nil {
+			if len(endpoints) == 1 {
+				return nil, err
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", e, err))
+			continue
+		}
+		if resp.StatusCode >= 500 && i < len(endpoints)-1 {
+			resp.Body.Close()
+			errs = append(errs, fmt.Errorf("%s: HTTP %d", e, resp.StatusCode))
+			continue
+		}
+		return resp, nil
+	}
+	return nil, errors.Join(errs...)
+}
+
+// OrderByHealth probes /api/v1/health on every proxy concurrently and
+// returns them healthy first, keeping the given order otherwise.
+func OrderByHealth(ctx context.Context, endpoints []string) []string {
+	healthy := make([]bool, len(endpoints))
+	var wg sync.WaitGroup
+	for i, e := range endpoints {
+		wg.Add(1)
+		go func(i int, e string) {
+			defer wg.Done()
+			healthy[i] = proxyHealthy(ctx, e)
+		}(i, e)
+	}
+	wg.Wait()
+
+	idx := make([]int, len(endpoints))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool { return healthy[idx[a]] && !healthy[idx[b]] })
+	out := make([]string, len(endpoints))
+	for i, j := range idx {
+		out[i] = endpoints[j]
+	}
+	return out
+}
+
+func proxyHealthy(ctx context.Context, endpoint string) bool {
+	ctx, cancel := context.WithTimeout(ctx, HealthTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint+"/api/v1/health", nil)
+	if err != nil {
+		return false
+	}
+	resp, err := httpclient.Client(0).Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
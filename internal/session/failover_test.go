@@ -0,0 +1,129 @@
+package session
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// deadEndpoint returns a URL nothing listens on.
+func deadEndpoint(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	l.Close()
+	return "http://" + addr
+}
+
+// statusProxy answers every request with code and counts session requests.
+func statusProxy(t *testing.T, code int, calls *atomic.Int32) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/session" {
+			calls.Add(1)
+		}
+		http.Error(w, http.StatusText(code), code)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// TestCreateFailover tests that session creation moves on to the next proxy
+// on connection errors and 5xx, but not on rejected requests.
+func TestCreateFailover(t *testing.T) {
+	good := startProxy(t).URL()
+	var calls503, calls403 atomic.Int32
+	down := statusProxy(t, http.StatusServiceUnavailable, &calls503)
+	forbidden := statusProxy(t, http.StatusForbidden, &calls403)
+	dead := deadEndpoint(t)
+
+	req := Request{Endpoint: dead, Fallbacks: []string{down, good}, ClientID: "c", Topics: []string{"demo"}, Capabilities: []string{"publish"}}
+	sess, err := Create(req)
+	require.NoError(t, err)
+	require.Equal(t, good, sess.Proxy)
+	require.Zero(t, calls503.Load(), "unhealthy proxies are tried last")
+
+	// with every proxy unhealthy they are tried in order
+	retried := req
+	retried.Endpoint, retried.Fallbacks = down, []string{dead}
+	_, err = Create(retried)
+	require.Error(t, err)
+	require.Equal(t, int32(1), calls503.Load())
+	require.Contains(t, err.Error(), down)
+	require.Contains(t, err.Error(), dead)
+
+	rejected := req
+	rejected.Endpoint, rejected.Fallbacks = forbidden, []string{dead}
+	_, err = Create(rejected)
+	require.Error(t, err)
+	require.NotContains(t, err.Error(), dead, "4xx is not retried")
+}
+
+// TestStoreRecordsIssuingProxy tests that the cache keys on the proxy list
+// and remembers which proxy answered.
+func TestStoreRecordsIssuingProxy(t *testing.T) {
+	good := startProxy(t).URL()
+	dead := deadEndpoint(t)
+	store := NewStore(t.TempDir())
+
+	req := Request{Endpoint: dead, Fallbacks: []string{good}, ClientID: "c", Topics: []string{"demo"}, Capabilities: []string{"publish"}}
+	sess, reused, err := store.GetOrCreate(req)
+	require.NoError(t, err)
+	require.False(t, reused)
+	require.Equal(t, good, sess.Proxy)
+
+	// the same proxies in another order share the session
+	swapped := req
+	swapped.Endpoint, swapped.Fallbacks = good, []string{dead}
+	again, reused, err := store.GetOrCreate(swapped)
+	require.NoError(t, err)
+	require.True(t, reused)
+	require.Equal(t, sess.SessionID, again.SessionID)
+	require.Equal(t, good, again.Proxy)
+
+	all, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	require.Equal(t, good, all[0].IssuedBy)
+}
+
+// TestParseEndpoints tests the --service-url list syntax.
+func TestParseEndpoints(t *testing.T) {
+	require.Equal(t, []string{"http://a", "http://b"}, ParseEndpoints(" http://a/, http://b,,http://a "))
+	require.Empty(t, ParseEndpoints(""))
+}
+
+// TestDo tests that Do moves past dead and failing proxies but not past a
+// proxy that rejected the request.
+func TestDo(t *testing.T) {
+	var calls atomic.Int32
+	get := func(base string) (*http.Request, error) {
+		return http.NewRequest("GET", base+"/api/v1/health", nil)
+	}
+	ok := statusProxy(t, http.StatusOK, &calls)
+	failing := statusProxy(t, http.StatusBadGateway, &calls)
+	rejecting := statusProxy(t, http.StatusForbidden, &calls)
+
+	resp, err := Do([]string{deadEndpoint(t), failing, ok}, get)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = Do([]string{rejecting, ok}, get)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp, err = Do([]string{deadEndpoint(t), failing}, get)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadGateway, resp.StatusCode, "the last proxy's reply is returned")
+
+	_, err = Do([]string{deadEndpoint(t), deadEndpoint(t)}, get)
+	require.Error(t, err)
+}
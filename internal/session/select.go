@@ -0,0 +1,243 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/getoptimum/mump2p-cli/internal/node"
+)
+
+// Strategy decides the order in which session nodes are tried.
+type Strategy string
+
+const (
+	// StrategyProxy keeps the order returned by the proxy.
+	StrategyProxy Strategy = "proxy"
+	// StrategyRTT orders nodes by measured round-trip time weighted by the
+	// proxy score.
+	StrategyRTT Strategy = "rtt"
+	// StrategyRegion groups nodes by region, nearest region first, keeping
+	// proxy order within a region.
+	StrategyRegion Strategy = "region"
+)
+
+// ParseStrategy validates a --node-strategy value.
+func ParseStrategy(s string) (Strategy, error) {
+	switch st := Strategy(s); st {
+	case StrategyProxy, StrategyRTT, StrategyRegion:
+		return st, nil
+	case "":
+		return StrategyProxy, nil
+	default:
+		return "", fmt.Errorf("unknown node strategy %q (use proxy, rtt or region)", s)
+	}
+}
+
+// Probe is the measured reachability of one node.
+type Probe struct {
+	Address  string        `json:"address"`
+	Connect  time.Duration `json:"connect"`
+	RTT      time.Duration `json:"rtt"`
+	Error    string        `json:"error,omitempty"`
+	ProbedAt time.Time     `json:"probed_at"`
+}
+
+// OK reports whether the node answered the probe.
+func (p Probe) OK() bool {
+	return p.Error == ""
+}
+
+// Prober measures the connect time and Health round-trip of a node.
+type Prober func(ctx context.Context, addr string) (connect, rtt time.Duration, err error)
+
+// Selector orders session nodes according to a strategy, probing nodes
+// concurrently and caching the results.
+type Selector struct {
+	Strategy Strategy
+	Prober   Prober
+	Timeout  time.Duration
+	CacheTTL time.Duration
+
+	// CachePath is the probe cache file; empty keeps results in memory only.
+	CachePath string
+
+	mu     sync.Mutex
+	probes map[string]Probe
+}
+
+// NewSelector creates a selector with the default prober. Set CachePath to
+// keep probes across runs.
+func NewSelector(strategy Strategy) *Selector {
+	s := &Selector{
+		Strategy: strategy,
+		Prober:   HealthProbe,
+		Timeout:  2 * time.Second,
+		CacheTTL: 5 * time.Minute,
+	}
+	return s
+}
+
+// Order returns nodes in the order they should be tried, along with the
+// probes used to decide it (nil for the proxy strategy).
+func (s *Selector) Order(ctx context.Context, nodes []Node) ([]Node, map[string]Probe) {
+	ordered := append([]Node(nil), nodes...)
+	if s.Strategy == StrategyProxy || len(nodes) == 0 {
+		return ordered, nil
+	}
+
+	probes := s.probeAll(ctx, nodes)
+	switch s.Strategy {
+	case StrategyRTT:
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return rttCost(ordered[i], probes) < rttCost(ordered[j], probes)
+		})
+	case StrategyRegion:
+		best := make(map[string]float64)
+		for _, n := range ordered {
+			c := rttCost(n, probes)
+			if b, ok := best[n.Region]; !ok || c < b {
+				best[n.Region] = c
+			}
+		}
+		sort.SliceStable(ordered, func(i, j int) bool {
+			ri, rj := ordered[i].Region, ordered[j].Region
+			if ri == rj {
+				return false
+			}
+			return best[ri] < best[rj]
+		})
+	}
+	return ordered, probes
+}
+
+// rttCost combines measured RTT with the proxy score: a node with score 1
+// costs its RTT, a node with score 0 twice its RTT. Unreachable nodes sort
+// last.
+func rttCost(n Node, probes map[string]Probe) float64 {
+	p, ok := probes[n.Address]
+	if !ok || !p.OK() {
+		return float64(time.Hour)
+	}
+	score := float64(n.Score)
+	if score < 0 {
+		score = 0
+	} else if score > 1 {
+		score = 1
+	}
+	return float64(p.RTT) * (2 - score)
+}
+
+// probeAll returns fresh cached probes and probes the remaining nodes
+// concurrently.
+func (s *Selector) probeAll(ctx context.Context, nodes []Node) map[string]Probe {
+	s.mu.Lock()
+	if s.probes == nil {
+		s.probes = s.loadProbes()
+	}
+	out := make(map[string]Probe, len(nodes))
+	var stale []string
+	queued := make(map[string]bool)
+	for _, n := range nodes {
+		if p, ok := s.probes[n.Address]; ok && time.Since(p.ProbedAt) < s.CacheTTL {
+			out[n.Address] = p
+		} else if !queued[n.Address] {
+			queued[n.Address] = true
+			stale = append(stale, n.Address)
+		}
+	}
+	s.mu.Unlock()
+	if len(stale) == 0 {
+		return out
+	}
+
+	results := make(chan Probe, len(stale))
+	for _, addr := range stale {
+		go func(addr string) {
+			pctx, cancel := context.WithTimeout(ctx, s.Timeout)
+			defer cancel()
+			p := Probe{Address: addr, ProbedAt: time.Now().UTC()}
+			connect, rtt, err := s.Prober(pctx, addr)
+			p.Connect, p.RTT = connect, rtt
+			if err != nil {
+				p.Error = err.Error()
+			}
+			results <- p
+		}(addr)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for range stale {
+		p := <-results
+		out[p.Address] = p
+		s.probes[p.Address] = p
+	}
+	s.saveProbes()
+	return out
+}
+
+func (s *Selector) loadProbes() map[string]Probe {
+	probes := make(map[string]Probe)
+	if s.CachePath == "" {
+		return probes
+	}
+	data, err := os.ReadFile(s.CachePath)
+	if err != nil {
+		return probes
+	}
+	_ = json.Unmarshal(data, &probes)
+	return probes
+}
+
+func (s *Selector) saveProbes() {
+	if s.CachePath == "" {
+		return
+	}
+	for addr, p := range s.probes {
+		if time.Since(p.ProbedAt) > s.CacheTTL {
+			delete(s.probes, addr)
+		}
+	}
+	data, err := json.MarshalIndent(s.probes, "", "  ")
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(s.CachePath, data, 0600)
+}
+
+// HealthProbe connects to a node and times a Health RPC.
+func HealthProbe(ctx context.Context, addr string) (connect, rtt time.Duration, err error) {
+	return probeHealth(ctx, addr)
+}
+
+// HealthProbeWith is HealthProbe with per-address dial options, e.g. TLS.
+func HealthProbeWith(dial func(addr string) []node.DialOption) Prober {
+	return func(ctx context.Context, addr string) (time.Duration, time.Duration, error) {
+		return probeHealth(ctx, addr, dial(addr)...)
+	}
+}
+
+func probeHealth(ctx context.Context, addr string, opts ...node.DialOption) (connect, rtt time.Duration, err error) {
+	c, err := node.NewClient(addr, opts...)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer c.Close()
+
+	start := time.Now()
+	if err := c.WaitReady(ctx); err != nil {
+		return 0, 0, err
+	}
+	connect = time.Since(start)
+
+	start = time.Now()
+	if _, err := c.Health(ctx); err != nil {
+		return connect, 0, err
+	}
+	return connect, time.Since(start), nil
+}
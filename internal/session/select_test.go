@@ -0,0 +1,111 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func fakeProber(rtts map[string]time.Duration, calls *atomic.Int32) Prober {
+	return func(ctx context.Context, addr string) (time.Duration, time.Duration, error) {
+		calls.Add(1)
+		rtt, ok := rtts[addr]
+		if !ok {
+			return 0, 0, errors.New("unreachable")
+		}
+		return time.Millisecond, rtt, nil
+	}
+}
+
+func addresses(nodes []Node) []string {
+	out := make([]string, len(nodes))
+	for i, n := range nodes {
+		out[i] = n.Address
+	}
+	return out
+}
+
+// TestSelectorOrder tests the proxy, rtt and region strategies.
+func TestSelectorOrder(t *testing.T) {
+	nodes := []Node{
+		{Address: "a", Region: "eu", Score: 1},
+		{Address: "b", Region: "us", Score: 1},
+		{Address: "c", Region: "eu", Score: 0},
+		{Address: "d", Region: "us", Score: 1},
+		{Address: "e", Region: "asia", Score: 1},
+	}
+	rtts := map[string]time.Duration{
+		"a": 50 * time.Millisecond,
+		"b": 20 * time.Millisecond,
+		"c": 30 * time.Millisecond, // score 0 doubles its cost to 60ms
+		"d": 80 * time.Millisecond,
+		// e is unreachable
+	}
+
+	tests := []struct {
+		strategy Strategy
+		want     []string
+	}{
+		{StrategyProxy, []string{"a", "b", "c", "d", "e"}},
+		{StrategyRTT, []string{"b", "a", "c", "d", "e"}},
+		{StrategyRegion, []string{"b", "d", "a", "c", "e"}},
+	}
+	for _, tc := range tests {
+		t.Run(string(tc.strategy), func(t *testing.T) {
+			var calls atomic.Int32
+			s := &Selector{Strategy: tc.strategy, Prober: fakeProber(rtts, &calls), Timeout: time.Second, CacheTTL: time.Minute}
+			got, probes := s.Order(context.Background(), nodes)
+			require.Equal(t, tc.want, addresses(got))
+			if tc.strategy == StrategyProxy {
+				require.Nil(t, probes)
+				require.Zero(t, calls.Load())
+				return
+			}
+			require.Len(t, probes, 5)
+			require.False(t, probes["e"].OK())
+		})
+	}
+}
+
+// TestSelectorCache tests that probes are reused within the TTL, also across processes.
+func TestSelectorCache(t *testing.T) {
+	nodes := []Node{{Address: "a"}, {Address: "b"}, {Address: "a"}}
+	rtts := map[string]time.Duration{"a": time.Millisecond, "b": 2 * time.Millisecond}
+	path := filepath.Join(t.TempDir(), "probes.json")
+
+	var calls atomic.Int32
+	s := &Selector{Strategy: StrategyRTT, Prober: fakeProber(rtts, &calls), Timeout: time.Second, CacheTTL: time.Minute, CachePath: path}
+	s.Order(context.Background(), nodes)
+	require.Equal(t, int32(2), calls.Load(), "each address is probed once")
+
+	s.Order(context.Background(), nodes)
+	require.Equal(t, int32(2), calls.Load(), "fresh probes are cached")
+
+	other := &Selector{Strategy: StrategyRTT, Prober: fakeProber(rtts, &calls), Timeout: time.Second, CacheTTL: time.Minute, CachePath: path}
+	_, probes := other.Order(context.Background(), nodes)
+	require.Equal(t, int32(2), calls.Load(), "probes are loaded from disk")
+	require.Equal(t, 2*time.Millisecond, probes["b"].RTT)
+
+	expired := &Selector{Strategy: StrategyRTT, Prober: fakeProber(rtts, &calls), Timeout: time.Second, CacheTTL: time.Nanosecond, CachePath: path}
+	expired.Order(context.Background(), nodes)
+	require.Equal(t, int32(4), calls.Load(), "stale probes are refreshed")
+}
+
+// TestParseStrategy tests flag validation.
+func TestParseStrategy(t *testing.T) {
+	s, err := ParseStrategy("")
+	require.NoError(t, err)
+	require.Equal(t, StrategyProxy, s)
+
+	s, err = ParseStrategy("rtt")
+	require.NoError(t, err)
+	require.Equal(t, StrategyRTT, s)
+
+	_, err = ParseStrategy("fastest")
+	require.Error(t, err)
+}
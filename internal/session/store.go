@@ -1,7 +1,10 @@
 package session
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -11,45 +14,93 @@ import (
 	"time"
 )
 
+// DefaultMaxEntries is the number of sessions a Store keeps before evicting
+// the least recently used one.
+const DefaultMaxEntries = 32
+
+const (
+	cacheFileName  = "sessions.json"
+	lockFileName   = "sessions.lock"
+	legacyFileName = "session.json"
+)
+
 type CachedSession struct {
-	ProxyURL     string   `json:"proxy_url"`
-	ClientID     string   `json:"client_id"`
-	Topics       []string `json:"topics"`
-	Capabilities []string `json:"capabilities"`
-	ExposeAmount uint32   `json:"expose_amount"`
-	Session      Session  `json:"session"`
+	Key string `json:"key" yaml:"key"`
+	// ProxyURL lists the proxies the session may come from, comma-separated
+	// when failover is configured; IssuedBy is the one that answered.
+	ProxyURL     string    `json:"proxy_url" yaml:"proxy_url"`
+	IssuedBy     string    `json:"issued_by,omitempty" yaml:"issued_by,omitempty"`
+	ClientID     string    `json:"client_id" yaml:"client_id"`
+	Topics       []string  `json:"topics" yaml:"topics"`
+	Capabilities []string  `json:"capabilities" yaml:"capabilities"`
+	ExposeAmount uint32    `json:"expose_amount" yaml:"expose_amount"`
+	Transport    string    `json:"transport,omitempty" yaml:"transport,omitempty"`
+	Protocol     string    `json:"protocol,omitempty" yaml:"protocol,omitempty"`
+	Region       string    `json:"region,omitempty" yaml:"region,omitempty"`
+	CreatedAt    time.Time `json:"created_at" yaml:"created_at"`
+	LastUsed     time.Time `json:"last_used" yaml:"last_used"`
+	Session      Session   `json:"session" yaml:"session"`
 }
 
-func sessionDir() (string, error) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return "", err
+type cacheFile struct {
+	Sessions []*CachedSession `json:"sessions"`
+}
+
+// Store is a file-backed cache of sessions keyed by proxy, client,
+// capabilities, topics and hints. Concurrent processes coordinate through a
+// file lock.
+type Store struct {
+	dir        string
+	MaxEntries int
+	// OnSaveError, when set, is called if a new session could not be
+	// cached. The session is still returned.
+	OnSaveError func(error)
+}
+
+// NewStore returns the store kept in dir, normally the directory of the
+// active auth file. An empty dir uses ~/.mump2p.
+func NewStore(dir string) *Store {
+	return &Store{dir: dir, MaxEntries: DefaultMaxEntries}
+}
+
+// Dir returns the directory holding the cache, creating it if needed.
+func (s *Store) Dir() (string, error) {
+	dir := s.dir
+	if dir == "" {
+		d, err := sessionDir()
+		if err != nil {
+			return "", err
+		}
+		dir = d
 	}
-	dir := filepath.Join(home, ".mump2p")
 	if err := os.MkdirAll(dir, 0700); err != nil {
 		return "", err
 	}
 	return dir, nil
 }
 
-func cachePath() (string, error) {
-	dir, err := sessionDir()
+func sessionDir() (string, error) {
+	home, err := os.UserHomeDir()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(dir, "session.json"), nil
+	dir := filepath.Join(home, ".mump2p")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	return dir, nil
 }
 
-func lockPath() (string, error) {
-	dir, err := sessionDir()
+func (s *Store) path(name string) (string, error) {
+	dir, err := s.Dir()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(dir, "session.lock"), nil
+	return filepath.Join(dir, name), nil
 }
 
-func acquireLock() (*os.File, error) {
-	p, err := lockPath()
+func (s *Store) acquireLock() (*os.File, error) {
+	p, err := s.path(lockFileName)
 	if err != nil {
 		return nil, err
 	}
@@ -69,32 +120,59 @@ func releaseLock(f *os.File) {
 	f.Close()
 }
 
-func loadCached() (*CachedSession, error) {
-	p, err := cachePath()
+// load reads the cache. A single-session session.json from older versions
+// is picked up when no cache exists yet.
+func (s *Store) load() (*cacheFile, error) {
+	p, err := s.path(cacheFileName)
 	if err != nil {
 		return nil, err
 	}
 	data, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return s.loadLegacy(), nil
+	}
 	if err != nil {
 		return nil, err
 	}
-	var c CachedSession
-	if err := json.Unmarshal(data, &c); err != nil {
+	var f cacheFile
+	if err := json.Unmarshal(data, &f); err != nil {
 		return nil, err
 	}
-	return &c, nil
+	return &f, nil
 }
 
-func saveCached(c *CachedSession) error {
-	p, err := cachePath()
+func (s *Store) loadLegacy() *cacheFile {
+	f := &cacheFile{}
+	p, err := s.path(legacyFileName)
+	if err != nil {
+		return f
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return f
+	}
+	var c CachedSession
+	if json.Unmarshal(data, &c) == nil && len(c.Session.Nodes) > 0 {
+		c.Key = cacheKey(&c)
+		f.Sessions = append(f.Sessions, &c)
+	}
+	return f
+}
+
+func (s *Store) save(f *cacheFile) error {
+	p, err := s.path(cacheFileName)
 	if err != nil {
 		return err
 	}
-	data, err := json.MarshalIndent(c, "", "  ")
+	data, err := json.MarshalIndent(f, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(p, data, 0600)
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
 }
 
 func sortedKey(s []string) string {
@@ -104,18 +182,31 @@ func sortedKey(s []string) string {
 	return strings.Join(cp, ",")
 }
 
-func (c *CachedSession) matches(proxyURL, clientID string, topics, capabilities []string) bool {
-	if c.ProxyURL != proxyURL || c.ClientID != clientID {
+// cacheKey identifies the kind of session an entry holds. Entries with the
+// same key replace each other.
+func cacheKey(c *CachedSession) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		c.ProxyURL, c.ClientID, c.Transport, c.Protocol, c.Region,
+		sortedKey(c.Capabilities), sortedKey(c.Topics),
+	}, "\x00")))
+	return hex.EncodeToString(sum[:6])
+}
+
+func (c *CachedSession) matches(req Request) bool {
+	if c.ProxyURL != req.proxyKey() || c.ClientID != req.ClientID {
 		return false
 	}
-	if sortedKey(c.Capabilities) != sortedKey(capabilities) {
+	if c.Transport != transportKey(req.Transport) || c.Protocol != req.Protocol || c.Region != req.Region {
+		return false
+	}
+	if sortedKey(c.Capabilities) != sortedKey(req.Capabilities) {
 		return false
 	}
 	cached := make(map[string]bool, len(c.Topics))
 	for _, t := range c.Topics {
 		cached[t] = true
 	}
-	for _, t := range topics {
+	for _, t := range req.Topics {
 		if !cached[t] {
 			return false
 		}
@@ -139,68 +230,200 @@ func (c *CachedSession) isExpired() bool {
 	return time.Now().UTC().After(ea)
 }
 
-func isUsable(cached *CachedSession, proxyURL, clientID string, topics, capabilities []string, exposeAmount uint32) bool {
+// Status is "valid", "refresh" (past the refresh window, no longer reused)
+// or "expired".
+func (c *CachedSession) Status() string {
+	switch {
+	case c.isExpired():
+		return "expired"
+	case c.needsRefresh():
+		return "refresh"
+	default:
+		return "valid"
+	}
+}
+
+// transportKey stores HTTP, the default, as empty so caches written before
+// transports existed still match.
+func transportKey(t Transport) string {
+	if t == TransportHTTP {
+		return ""
+	}
+	return string(t)
+}
+
+func isUsable(cached *CachedSession, req Request) bool {
 	if cached == nil ||
-		!cached.matches(proxyURL, clientID, topics, capabilities) ||
+		!cached.matches(req) ||
 		cached.isExpired() ||
 		cached.needsRefresh() {
 		return false
 	}
 	// Cached session must cover the requested node count (e.g. cannot reuse a
 	// 1-node session when the user now asks for --expose-amount 3).
-	if exposeAmount > 0 && len(cached.Session.Nodes) < int(exposeAmount) {
+	if req.ExposeAmount > 0 && len(cached.Session.Nodes) < int(req.ExposeAmount) {
 		return false
 	}
 	return true
 }
 
-// GetOrCreateSession returns a cached session if valid, refreshes if past
-// the refresh window, or creates a new session. Uses a file lock to prevent
-// concurrent processes from each creating separate sessions.
-func GetOrCreateSession(proxyURL, clientID, accessToken string, topics, capabilities []string, exposeAmount uint32) (*Session, bool, error) {
-	// Fast path: read without lock — if valid, return immediately.
-	if cached, err := loadCached(); err == nil && isUsable(cached, proxyURL, clientID, topics, capabilities, exposeAmount) {
-		return &cached.Session, true, nil
+// lookup returns the most recently used entry usable for req.
+func (f *cacheFile) lookup(req Request) *CachedSession {
+	var best *CachedSession
+	for _, c := range f.Sessions {
+		if isUsable(c, req) && (best == nil || c.LastUsed.After(best.LastUsed)) {
+			best = c
+		}
 	}
+	return best
+}
 
-	// Slow path: acquire lock, re-check (another process may have refreshed).
-	lf, lockErr := acquireLock()
+// put adds c, replacing entries with the same key, dropping expired ones
+// and evicting the least recently used beyond max.
+func (f *cacheFile) put(c *CachedSession, max int) {
+	kept := f.Sessions[:0]
+	for _, e := range f.Sessions {
+		if e.Key != c.Key && !e.isExpired() {
+			kept = append(kept, e)
+		}
+	}
+	f.Sessions = append(kept, c)
+	if max > 0 && len(f.Sessions) > max {
+		sort.SliceStable(f.Sessions, func(i, j int) bool {
+			return f.Sessions[i].LastUsed.After(f.Sessions[j].LastUsed)
+		})
+		f.Sessions = f.Sessions[:max]
+	}
+}
+
+// GetOrCreate returns a cached session if valid, or creates a new one when
+// none is cached or the cached one is past its refresh window. The bool
+// reports whether the session was reused.
+func (s *Store) GetOrCreate(req Request) (*Session, bool, error) {
+	lf, lockErr := s.acquireLock()
 	if lockErr != nil {
 		// If locking fails, fall through to create without cache.
-		sess, err := CreateSession(proxyURL, clientID, accessToken, topics, capabilities, exposeAmount)
+		sess, err := Create(req)
 		return sess, false, err
 	}
 	defer releaseLock(lf)
 
-	if cached, err := loadCached(); err == nil && isUsable(cached, proxyURL, clientID, topics, capabilities, exposeAmount) {
-		return &cached.Session, true, nil
+	f, err := s.load()
+	if err != nil {
+		// A corrupt cache is rebuilt from scratch.
+		f = &cacheFile{}
 	}
 
-	sess, err := CreateSession(proxyURL, clientID, accessToken, topics, capabilities, exposeAmount)
+	now := time.Now().UTC()
+	if c := f.lookup(req); c != nil {
+		c.LastUsed = now
+		_ = s.save(f)
+		c.Session.Proxy = c.IssuedBy
+		return &c.Session, true, nil
+	}
+
+	sess, err := Create(req)
 	if err != nil {
 		return nil, false, err
 	}
 
 	c := &CachedSession{
-		ProxyURL:     proxyURL,
-		ClientID:     clientID,
-		Topics:       topics,
-		Capabilities: capabilities,
-		ExposeAmount: exposeAmount,
+		ProxyURL:     req.proxyKey(),
+		IssuedBy:     sess.Proxy,
+		ClientID:     req.ClientID,
+		Topics:       req.Topics,
+		Capabilities: req.Capabilities,
+		ExposeAmount: req.ExposeAmount,
+		Transport:    transportKey(req.Transport),
+		Protocol:     req.Protocol,
+		Region:       req.Region,
+		CreatedAt:    now,
+		LastUsed:     now,
 		Session:      *sess,
 	}
-	if saveErr := saveCached(c); saveErr != nil {
-		fmt.Printf("Warning: could not cache session: %v\n", saveErr)
+	c.Key = cacheKey(c)
+	f.put(c, s.MaxEntries)
+	if saveErr := s.save(f); saveErr != nil && s.OnSaveError != nil {
+		s.OnSaveError(saveErr)
 	}
 
 	return sess, false, nil
 }
 
-// InvalidateSession removes the cached session file.
-func InvalidateSession() {
-	p, err := cachePath()
+// List returns all cached sessions, most recently used first.
+func (s *Store) List() ([]*CachedSession, error) {
+	lf, err := s.acquireLock()
+	if err != nil {
+		return nil, err
+	}
+	defer releaseLock(lf)
+	f, err := s.load()
 	if err != nil {
-		return
+		return nil, err
 	}
-	os.Remove(p)
+	sort.SliceStable(f.Sessions, func(i, j int) bool {
+		return f.Sessions[i].LastUsed.After(f.Sessions[j].LastUsed)
+	})
+	return f.Sessions, nil
+}
+
+// Find returns the cached session whose key or session ID starts with
+// prefix. Ambiguous prefixes are an error.
+func (s *Store) Find(prefix string) (*CachedSession, error) {
+	all, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	var found *CachedSession
+	for _, c := range all {
+		if strings.HasPrefix(c.Key, prefix) || strings.HasPrefix(c.Session.SessionID, prefix) {
+			if found != nil {
+				return nil, fmt.Errorf("%q matches more than one session", prefix)
+			}
+			found = c
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no cached session matches %q", prefix)
+	}
+	return found, nil
+}
+
+// Remove deletes the cached sessions for which drop returns true and
+// reports how many were removed.
+func (s *Store) Remove(drop func(*CachedSession) bool) (int, error) {
+	lf, err := s.acquireLock()
+	if err != nil {
+		return 0, err
+	}
+	defer releaseLock(lf)
+	f, err := s.load()
+	if err != nil {
+		f = &cacheFile{}
+	}
+	kept := f.Sessions[:0]
+	for _, c := range f.Sessions {
+		if !drop(c) {
+			kept = append(kept, c)
+		}
+	}
+	removed := len(f.Sessions) - len(kept)
+	f.Sessions = kept
+	if err := s.save(f); err != nil {
+		return 0, err
+	}
+	if p, err := s.path(legacyFileName); err == nil {
+		os.Remove(p)
+	}
+	return removed, nil
+}
+
+// Prune removes sessions that can no longer be reused.
+func (s *Store) Prune() (int, error) {
+	return s.Remove(func(c *CachedSession) bool { return c.Status() != "valid" })
+}
+
+// InvalidateAll removes every cached session, e.g. after the user logs out.
+func (s *Store) InvalidateAll() {
+	s.Remove(func(*CachedSession) bool { return true }) //nolint:errcheck
 }
@@ -0,0 +1,141 @@
+package session
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/getoptimum/mump2p-cli/internal/devserver"
+	"github.com/stretchr/testify/require"
+)
+
+func startProxy(t *testing.T) *devserver.Server {
+	t.Helper()
+	srv, err := devserver.Start(devserver.Config{Nodes: 2})
+	require.NoError(t, err)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// TestStoreKeepsSessionsApart tests that publish and subscribe sessions are
+// cached side by side instead of replacing each other.
+func TestStoreKeepsSessionsApart(t *testing.T) {
+	srv := startProxy(t)
+	store := NewStore(t.TempDir())
+
+	pub := Request{Endpoint: srv.URL(), ClientID: "c", Topics: []string{"demo"}, Capabilities: []string{"publish"}, ExposeAmount: 1}
+	sub := pub
+	sub.Capabilities = []string{"subscribe"}
+
+	p1, reused, err := store.GetOrCreate(pub)
+	require.NoError(t, err)
+	require.False(t, reused)
+	s1, reused, err := store.GetOrCreate(sub)
+	require.NoError(t, err)
+	require.False(t, reused)
+
+	p2, reused, err := store.GetOrCreate(pub)
+	require.NoError(t, err)
+	require.True(t, reused)
+	require.Equal(t, p1.SessionID, p2.SessionID)
+	s2, reused, err := store.GetOrCreate(sub)
+	require.NoError(t, err)
+	require.True(t, reused)
+	require.Equal(t, s1.SessionID, s2.SessionID)
+
+	all, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	require.Equal(t, s1.SessionID, all[0].Session.SessionID, "most recently used first")
+}
+
+// TestStoreEvictsLeastRecentlyUsed tests the MaxEntries bound.
+func TestStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	srv := startProxy(t)
+	store := NewStore(t.TempDir())
+	store.MaxEntries = 2
+
+	req := func(topic string) Request {
+		return Request{Endpoint: srv.URL(), ClientID: "c", Topics: []string{topic}, Capabilities: []string{"publish"}}
+	}
+	for _, topic := range []string{"a", "b"} {
+		_, _, err := store.GetOrCreate(req(topic))
+		require.NoError(t, err)
+	}
+	_, reused, err := store.GetOrCreate(req("a"))
+	require.NoError(t, err)
+	require.True(t, reused)
+
+	_, _, err = store.GetOrCreate(req("c"))
+	require.NoError(t, err)
+
+	all, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	require.Equal(t, []string{"c"}, all[0].Topics)
+	require.Equal(t, []string{"a"}, all[1].Topics, "b was least recently used")
+}
+
+// TestStoreFindAndRemove tests prefix lookup, Prune and Remove.
+func TestStoreFindAndRemove(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir)
+	now := time.Now().UTC()
+	valid := &CachedSession{ProxyURL: "http://proxy", Topics: []string{"a"}, LastUsed: now,
+		Session: Session{SessionID: "sess-valid", RefreshAfter: now.Add(time.Hour).Format(time.RFC3339), ExpiresAt: now.Add(2 * time.Hour).Format(time.RFC3339)}}
+	stale := &CachedSession{ProxyURL: "http://proxy", Topics: []string{"b"}, LastUsed: now,
+		Session: Session{SessionID: "sess-stale", RefreshAfter: now.Add(-time.Hour).Format(time.RFC3339), ExpiresAt: now.Add(time.Hour).Format(time.RFC3339)}}
+	valid.Key, stale.Key = cacheKey(valid), cacheKey(stale)
+	require.NoError(t, store.save(&cacheFile{Sessions: []*CachedSession{valid, stale}}))
+
+	c, err := store.Find("sess-v")
+	require.NoError(t, err)
+	require.Equal(t, valid.Key, c.Key)
+	require.Equal(t, "valid", c.Status())
+	c, err = store.Find(stale.Key[:6])
+	require.NoError(t, err)
+	require.Equal(t, "refresh", c.Status())
+	_, err = store.Find("sess-")
+	require.Error(t, err, "ambiguous prefix")
+	_, err = store.Find("nope")
+	require.Error(t, err)
+
+	removed, err := store.Prune()
+	require.NoError(t, err)
+	require.Equal(t, 1, removed)
+
+	removed, err = store.Remove(func(c *CachedSession) bool { return c.Key == valid.Key })
+	require.NoError(t, err)
+	require.Equal(t, 1, removed)
+	all, err := store.List()
+	require.NoError(t, err)
+	require.Empty(t, all)
+}
+
+// TestStoreImportsLegacyCache tests that a session.json from older versions
+// is reused and removed on invalidation.
+func TestStoreImportsLegacyCache(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now().UTC()
+	legacy := CachedSession{ProxyURL: "http://proxy", ClientID: "c", Topics: []string{"demo"}, Capabilities: []string{"publish"},
+		Session: Session{SessionID: "legacy", Nodes: []Node{{Address: "127.0.0.1:1"}},
+			RefreshAfter: now.Add(time.Hour).Format(time.RFC3339), ExpiresAt: now.Add(2 * time.Hour).Format(time.RFC3339)}}
+	data, err := json.Marshal(legacy)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, legacyFileName), data, 0600))
+
+	store := NewStore(dir)
+	sess, reused, err := store.GetOrCreate(Request{Endpoint: "http://proxy", ClientID: "c", Topics: []string{"demo"}, Capabilities: []string{"publish"}})
+	require.NoError(t, err)
+	require.True(t, reused)
+	require.Equal(t, "legacy", sess.SessionID)
+
+	store.InvalidateAll()
+	_, err = os.Stat(filepath.Join(dir, legacyFileName))
+	require.True(t, os.IsNotExist(err))
+	all, err := store.List()
+	require.NoError(t, err)
+	require.Empty(t, all)
+}
@@ -0,0 +1,231 @@
+// Package tail follows a growing text file line by line, across rotation
+// and truncation, from a position that can be saved and resumed.
+package tail
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// MaxLineSize is the longest line returned whole; longer lines are split.
+const MaxLineSize = 1 << 20
+
+// Position identifies a place in a file: its inode and a byte offset.
+type Position struct {
+	Inode  uint64 `json:"inode"`
+	Offset int64  `json:"offset"`
+}
+
+// Line is a line read from the file, without its line ending.
+type Line struct {
+	Text string
+	// Pos is just past the line; following from it resumes with the next.
+	Pos Position
+}
+
+// Follower reads lines appended to a file. When the file at its path is
+// replaced (rotation), it finishes the old file and starts the new one from
+// the beginning; when the file shrinks below what was read (truncation), it
+// starts over from the beginning.
+type Follower struct {
+	path    string
+	f       *os.File
+	pos     Position // read so far, including partial
+	partial []byte
+	buf     []byte
+}
+
+// Open follows path from pos. It starts from the beginning when pos is for
+// another file or beyond the end of this one, and at the end when pos is nil
+// and fromStart is false. The file need not exist yet.
+func Open(path string, pos *Position, fromStart bool) (*Follower, error) {
+	t := &Follower{path: path, buf: make([]byte, 64<<10)}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	t.f, t.pos = f, Position{Inode: inode(info)}
+	switch {
+	case pos != nil && pos.Inode == t.pos.Inode && pos.Offset <= info.Size():
+		t.pos.Offset = pos.Offset
+	case pos == nil && !fromStart:
+		t.pos.Offset = info.Size()
+	}
+	if _, err := f.Seek(t.pos.Offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// Position is where the last complete line returned ends.
+func (t *Follower) Position() Position {
+	return Position{Inode: t.pos.Inode, Offset: t.pos.Offset - int64(len(t.partial))}
+}
+
+// Read returns the complete lines written since the last call. A line
+// still being written is held back until its newline arrives, unless the
+// file is rotated first.
+func (t *Follower) Read() ([]Line, error) {
+	if t.f == nil {
+		if err := t.reopen(); err != nil || t.f == nil {
+			return nil, err
+		}
+	}
+	lines, err := t.drain(nil)
+	if err != nil {
+		return lines, err
+	}
+
+	info, err := os.Stat(t.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return lines, nil // rotated, and the new file is not there yet
+	}
+	if err != nil {
+		return lines, err
+	}
+	switch {
+	case inode(info) != t.pos.Inode:
+		// the old file is read to the end; its last line may have no
+		// newline
+		if len(t.partial) > 0 {
+			lines = append(lines, Line{Text: string(t.partial), Pos: t.pos})
+			t.partial = nil
+		}
+		t.f.Close()
+		t.f = nil
+		if err := t.reopen(); err != nil || t.f == nil {
+			return lines, err
+		}
+		return t.drain(lines)
+	case info.Size() < t.pos.Offset:
+		t.partial = nil
+		t.pos.Offset = 0
+		if _, err := t.f.Seek(0, io.SeekStart); err != nil {
+			return lines, err
+		}
+		return t.drain(lines)
+	}
+	return lines, nil
+}
+
+// reopen opens the file at the path from the beginning, if it exists.
+func (t *Follower) reopen() error {
+	f, err := os.Open(t.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	t.f, t.pos, t.partial = f, Position{Inode: inode(info)}, nil
+	return nil
+}
+
+// drain reads to the end of the open file and appends the complete lines.
+func (t *Follower) drain(lines []Line) ([]Line, error) {
+	for {
+		n, err := t.f.Read(t.buf)
+		data := t.buf[:n]
+		for len(data) > 0 {
+			i := bytes.IndexByte(data, '\n')
+			if i < 0 {
+				t.partial = append(t.partial, data...)
+				t.pos.Offset += int64(len(data))
+				if len(t.partial) >= MaxLineSize {
+					lines = append(lines, Line{Text: string(t.partial), Pos: t.pos})
+					t.partial = nil
+				}
+				break
+			}
+			text := append(t.partial, data[:i]...)
+			t.partial = nil
+			t.pos.Offset += int64(i + 1)
+			lines = append(lines, Line{Text: string(bytes.TrimSuffix(text, []byte("\r"))), Pos: t.pos})
+			data = data[i+1:]
+		}
+		if err == io.EOF || n == 0 {
+			return lines, nil
+		}
+		if err != nil {
+			return lines, err
+		}
+	}
+}
+
+// Close closes the file.
+func (t *Follower) Close() error {
+	if t.f == nil {
+		return nil
+	}
+	return t.f.Close()
+}
+
+func inode(info os.FileInfo) uint64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Ino)
+	}
+	return 0
+}
+
+// State is the saved position of a followed file.
+type State struct {
+	Path     string    `json:"path"`
+	Position Position  `json:"position"`
+	Updated  time.Time `json:"updated"`
+}
+
+// LoadPosition reads the position saved in file for path. It returns nil
+// when nothing was saved, or when it was saved for another path.
+func LoadPosition(file, path string) (*Position, error) {
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s State
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	if s.Path != path {
+		return nil, nil
+	}
+	return &s.Position, nil
+}
+
+// SavePosition writes pos for path to file.
+func SavePosition(file, path string, pos Position) error {
+	data, err := json.Marshal(State{Path: path, Position: pos, Updated: time.Now()})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
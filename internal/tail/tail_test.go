@@ -0,0 +1,131 @@
+package tail
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func appendFile(t *testing.T, path, s string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString(s)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+}
+
+func texts(lines []Line) []string {
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		out[i] = l.Text
+	}
+	return out
+}
+
+func TestFollow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "old\n")
+
+	f, err := Open(path, nil, false)
+	require.NoError(t, err)
+	defer f.Close()
+	lines, err := f.Read()
+	require.NoError(t, err)
+	require.Empty(t, lines, "starts at the end")
+
+	appendFile(t, path, "one\r\ntwo\nthr")
+	lines, err = f.Read()
+	require.NoError(t, err)
+	require.Equal(t, []string{"one", "two"}, texts(lines))
+	require.Equal(t, int64(len("old\none\r\ntwo\n")), f.Position().Offset, "partial line not consumed")
+
+	appendFile(t, path, "ee\n")
+	lines, err = f.Read()
+	require.NoError(t, err)
+	require.Equal(t, []string{"three"}, texts(lines))
+
+	// rotation: the old file's last line is kept even without a newline
+	appendFile(t, path, "last")
+	require.NoError(t, os.Rename(path, path+".1"))
+	lines, err = f.Read()
+	require.NoError(t, err)
+	require.Empty(t, lines, "the old file may still be written until the new one appears")
+	appendFile(t, path, "new\n")
+	lines, err = f.Read()
+	require.NoError(t, err)
+	require.Equal(t, []string{"last", "new"}, texts(lines))
+
+	// truncation starts over
+	require.NoError(t, os.Truncate(path, 0))
+	appendFile(t, path, "a\n")
+	lines, err = f.Read()
+	require.NoError(t, err)
+	require.Equal(t, []string{"a"}, texts(lines))
+}
+
+func TestResume(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	state := filepath.Join(dir, "state", "app.json")
+
+	pos, err := LoadPosition(state, path)
+	require.NoError(t, err)
+	require.Nil(t, pos)
+
+	f, err := Open(path, nil, true)
+	require.NoError(t, err)
+	lines, err := f.Read()
+	require.NoError(t, err)
+	require.Empty(t, lines, "file does not exist yet")
+	appendFile(t, path, "1\n2\n")
+	lines, err = f.Read()
+	require.NoError(t, err)
+	require.Equal(t, []string{"1", "2"}, texts(lines))
+	require.NoError(t, SavePosition(state, path, lines[0].Pos))
+	require.NoError(t, f.Close())
+
+	pos, err = LoadPosition(state, path)
+	require.NoError(t, err)
+	require.NotNil(t, pos)
+	f, err = Open(path, pos, false)
+	require.NoError(t, err)
+	lines, err = f.Read()
+	require.NoError(t, err)
+	require.Equal(t, []string{"2"}, texts(lines), "resumes after the saved line")
+	require.NoError(t, f.Close())
+
+	other, err := LoadPosition(state, filepath.Join(dir, "other.log"))
+	require.NoError(t, err)
+	require.Nil(t, other)
+
+	// replaced while stopped: the new file is read from the start
+	require.NoError(t, os.Remove(path))
+	appendFile(t, path, "x\n")
+	f, err = Open(path, &Position{Inode: ^uint64(0), Offset: 2}, false)
+	require.NoError(t, err)
+	defer f.Close()
+	lines, err = f.Read()
+	require.NoError(t, err)
+	require.Equal(t, []string{"x"}, texts(lines))
+}
+
+func TestLongLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	f, err := Open(path, nil, true)
+	require.NoError(t, err)
+	defer f.Close()
+
+	long := make([]byte, MaxLineSize+10)
+	for i := range long {
+		long[i] = 'x'
+	}
+	appendFile(t, path, string(long)+"\n")
+	lines, err := f.Read()
+	require.NoError(t, err)
+	require.Len(t, lines, 2)
+	require.Len(t, lines[0].Text, MaxLineSize)
+	require.Len(t, lines[1].Text, 10)
+}
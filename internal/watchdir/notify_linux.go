@@ -0,0 +1,57 @@
+package watchdir
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// notifier reports files closed after writing or moved into a directory.
+type notifier struct {
+	f *os.File
+}
+
+// startNotifier calls finished with the name of each file a writer closes
+// or that is moved into dir. It returns nil if inotify cannot be used.
+func startNotifier(dir string, finished func(name string)) *notifier {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil
+	}
+	if _, err := syscall.InotifyAddWatch(fd, dir, syscall.IN_CLOSE_WRITE|syscall.IN_MOVED_TO); err != nil {
+		syscall.Close(fd)
+		return nil
+	}
+	// a non-blocking fd goes through the runtime poller, so Close
+	// interrupts the read below
+	n := &notifier{f: os.NewFile(uintptr(fd), "inotify")}
+	go n.read(finished)
+	return n
+}
+
+func (n *notifier) read(finished func(name string)) {
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		size, err := n.f.Read(buf)
+		if err != nil {
+			return
+		}
+		for off := 0; off+syscall.SizeofInotifyEvent <= size; {
+			ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			start := off + syscall.SizeofInotifyEvent
+			end := start + int(ev.Len)
+			if end > size {
+				break
+			}
+			if name := string(bytes.TrimRight(buf[start:end], "\x00")); name != "" && ev.Mask&syscall.IN_ISDIR == 0 {
+				finished(name)
+			}
+			off = end
+		}
+	}
+}
+
+func (n *notifier) close() error {
+	return n.f.Close()
+}
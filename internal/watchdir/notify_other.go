@@ -0,0 +1,14 @@
+//go:build !linux
+
+package watchdir
+
+// notifier is only implemented on Linux; elsewhere the Watcher polls.
+type notifier struct{}
+
+func startNotifier(string, func(string)) *notifier {
+	return nil
+}
+
+func (n *notifier) close() error {
+	return nil
+}
@@ -0,0 +1,128 @@
+package watchdir
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// StateFileName is the default state file, kept in the watched directory.
+const StateFileName = ".mump2p-watch.json"
+
+// State records files that were published but not yet moved to done, so a
+// restart moves them instead of publishing them again.
+type State struct {
+	path  string
+	Files map[string]Entry `json:"files"`
+}
+
+// Entry identifies a published file by its content.
+type Entry struct {
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256"`
+	Published time.Time `json:"published"`
+}
+
+// LoadState reads the state file at path; a missing file is an empty state.
+func LoadState(path string) (*State, error) {
+	s := &State{path: path, Files: make(map[string]Entry)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("invalid state file %s: %v", path, err)
+	}
+	if s.Files == nil {
+		s.Files = make(map[string]Entry)
+	}
+	return s, nil
+}
+
+// Published reports whether name was published with this content.
+func (s *State) Published(name, sha256 string) bool {
+	e, ok := s.Files[name]
+	return ok && e.SHA256 == sha256
+}
+
+// MarkPublished records name as published and saves the state.
+func (s *State) MarkPublished(name string, size int64, sha256 string, now time.Time) error {
+	s.Files[name] = Entry{Size: size, SHA256: sha256, Published: now}
+	return s.save()
+}
+
+// Forget removes name once it has been moved and saves the state.
+func (s *State) Forget(name string) error {
+	if _, ok := s.Files[name]; !ok {
+		return nil
+	}
+	delete(s.Files, name)
+	return s.save()
+}
+
+// Prune forgets files no longer in dir, which were moved before the state
+// could be saved.
+func (s *State) Prune(dir string) error {
+	changed := false
+	for name := range s.Files {
+		if _, err := os.Stat(filepath.Join(dir, name)); errors.Is(err, os.ErrNotExist) {
+			delete(s.Files, name)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return s.save()
+}
+
+func (s *State) save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// MoveDone moves name from dir into its done subdirectory and returns the
+// new path.
+func MoveDone(dir, name string) (string, error) {
+	return move(dir, name, DoneDir)
+}
+
+// MoveFailed moves name from dir into its failed subdirectory and writes
+// the error next to it, in name.error. It returns the new path.
+func MoveFailed(dir, name string, cause error) (string, error) {
+	dest, err := move(dir, name, FailedDir)
+	if err != nil {
+		return "", err
+	}
+	note := fmt.Sprintf("%s\n%s\n", time.Now().UTC().Format(time.RFC3339), cause)
+	return dest, os.WriteFile(dest+".error", []byte(note), 0644)
+}
+
+// move renames name into sub without overwriting a file already there,
+// adding a counter before the extension instead.
+func move(dir, name, sub string) (string, error) {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	dest := filepath.Join(dir, sub, name)
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(dest); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		dest = filepath.Join(dir, sub, fmt.Sprintf("%s.%d%s", base, i, ext))
+	}
+	return dest, os.Rename(filepath.Join(dir, name), dest)
+}
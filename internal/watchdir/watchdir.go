@@ -0,0 +1,140 @@
+// Package watchdir finds files dropped into a directory once they are
+// finished, and moves them aside after they have been handled.
+package watchdir
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Subdirectories files are moved to once handled.
+const (
+	DoneDir   = "done"
+	FailedDir = "failed"
+)
+
+// Watcher reports regular files in a directory that are ready: their size
+// and modification time have not changed for the settle time, or, where
+// inotify is available, a writer has closed them or they were moved in.
+// Hidden files and subdirectories are ignored.
+type Watcher struct {
+	dir    string
+	settle time.Duration
+	files  map[string]observation
+	notify *notifier
+	wake   chan struct{}
+
+	mu     sync.Mutex
+	closed map[string]bool
+}
+
+type observation struct {
+	size  int64
+	mtime time.Time
+	since time.Time
+}
+
+// New watches dir, creating it and its done and failed subdirectories if
+// needed. It falls back to polling when inotify is not available.
+func New(dir string, settle time.Duration) (*Watcher, error) {
+	for _, sub := range []string{DoneDir, FailedDir} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return nil, err
+		}
+	}
+	w := &Watcher{
+		dir:    dir,
+		settle: settle,
+		files:  make(map[string]observation),
+		wake:   make(chan struct{}, 1),
+		closed: make(map[string]bool),
+	}
+	w.notify = startNotifier(dir, w.finished)
+	return w, nil
+}
+
+// Notifying reports whether inotify is in use.
+func (w *Watcher) Notifying() bool {
+	return w.notify != nil
+}
+
+// Wake receives when inotify saw a file finish, so the caller can scan
+// before its next poll. It never receives when polling.
+func (w *Watcher) Wake() <-chan struct{} {
+	return w.wake
+}
+
+func (w *Watcher) finished(name string) {
+	if strings.HasPrefix(name, ".") {
+		// hidden files, such as the state file being saved, are never
+		// published
+		return
+	}
+	w.mu.Lock()
+	w.closed[name] = true
+	w.mu.Unlock()
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Ready scans the directory and returns the names of the files that are
+// ready, in name order.
+func (w *Watcher) Ready(now time.Time) ([]string, error) {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return nil, err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	present := make(map[string]bool, len(entries))
+	var ready []string
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasPrefix(name, ".") || !e.Type().IsRegular() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue // removed since ReadDir
+		}
+		present[name] = true
+		obs, ok := w.files[name]
+		if !ok || obs.size != info.Size() || !obs.mtime.Equal(info.ModTime()) {
+			obs = observation{size: info.Size(), mtime: info.ModTime(), since: now}
+			w.files[name] = obs
+		}
+		if w.closed[name] || now.Sub(obs.since) >= w.settle {
+			ready = append(ready, name)
+		}
+	}
+	for name := range w.files {
+		if !present[name] {
+			delete(w.files, name)
+			delete(w.closed, name)
+		}
+	}
+	return ready, nil
+}
+
+// Forget drops what is known about a file once it has been handled, so a
+// new file with the same name starts over.
+func (w *Watcher) Forget(name string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.files, name)
+	delete(w.closed, name)
+}
+
+// Close stops inotify.
+func (w *Watcher) Close() error {
+	if w.notify == nil {
+		return nil
+	}
+	return w.notify.close()
+}
@@ -0,0 +1,141 @@
+package watchdir
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadySettle(t *testing.T) {
+	dir := t.TempDir()
+	w, err := New(dir, 2*time.Second)
+	require.NoError(t, err)
+	// polling only, so inotify events do not make files ready early
+	if w.notify != nil {
+		w.notify.close()
+		w.notify = nil
+	}
+
+	require.DirExists(t, filepath.Join(dir, DoneDir))
+	require.DirExists(t, filepath.Join(dir, FailedDir))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".hidden"), []byte("h"), 0644))
+
+	t0 := time.Now()
+	ready, err := w.Ready(t0)
+	require.NoError(t, err)
+	require.Empty(t, ready, "new files wait for the settle time")
+
+	ready, err = w.Ready(t0.Add(2 * time.Second))
+	require.NoError(t, err)
+	require.Equal(t, []string{"a.txt"}, ready)
+
+	// a file that grows starts over
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("abc"), 0644))
+	ready, err = w.Ready(t0.Add(3 * time.Second))
+	require.NoError(t, err)
+	require.Empty(t, ready)
+	ready, err = w.Ready(t0.Add(5 * time.Second))
+	require.NoError(t, err)
+	require.Equal(t, []string{"a.txt"}, ready)
+
+	// a writer closing the file makes it ready at once
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.txt"), []byte("b"), 0644))
+	w.finished("b.txt")
+	select {
+	case <-w.Wake():
+	default:
+		t.Fatal("expected a wake-up")
+	}
+	ready, err = w.Ready(t0.Add(5 * time.Second))
+	require.NoError(t, err)
+	require.Equal(t, []string{"a.txt", "b.txt"}, ready)
+
+	// hidden files never wake the watcher
+	w.finished(".state.json.tmp")
+	select {
+	case <-w.Wake():
+		t.Fatal("woken by a hidden file")
+	default:
+	}
+}
+
+func TestNotify(t *testing.T) {
+	dir := t.TempDir()
+	w, err := New(dir, time.Hour)
+	require.NoError(t, err)
+	defer w.Close()
+	if !w.Notifying() {
+		t.Skip("inotify not available")
+	}
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644))
+	select {
+	case <-w.Wake():
+	case <-time.After(5 * time.Second):
+		t.Fatal("no inotify event")
+	}
+	ready, err := w.Ready(time.Now())
+	require.NoError(t, err)
+	require.Equal(t, []string{"a.txt"}, ready)
+	require.NoError(t, w.Close())
+}
+
+func TestState(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, StateFileName)
+
+	s, err := LoadState(path)
+	require.NoError(t, err)
+	require.False(t, s.Published("a.txt", "aa"))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644))
+	require.NoError(t, s.MarkPublished("a.txt", 1, "aa", time.Now()))
+	require.NoError(t, s.MarkPublished("gone.txt", 1, "bb", time.Now()))
+
+	s, err = LoadState(path)
+	require.NoError(t, err)
+	require.True(t, s.Published("a.txt", "aa"))
+	require.False(t, s.Published("a.txt", "other"), "new content under the same name")
+
+	require.NoError(t, s.Prune(dir))
+	require.NotContains(t, s.Files, "gone.txt")
+	require.Contains(t, s.Files, "a.txt")
+
+	require.NoError(t, s.Forget("a.txt"))
+	s, err = LoadState(path)
+	require.NoError(t, err)
+	require.Empty(t, s.Files)
+
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0644))
+	_, err = LoadState(path)
+	require.Error(t, err)
+}
+
+func TestMove(t *testing.T) {
+	dir := t.TempDir()
+	_, err := New(dir, 0)
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "r.csv"), []byte("x"), 0644))
+		dest, err := MoveDone(dir, "r.csv")
+		require.NoError(t, err)
+		want := []string{"r.csv", "r.1.csv"}[i]
+		require.Equal(t, filepath.Join(dir, DoneDir, want), dest)
+		require.FileExists(t, dest)
+	}
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bad.bin"), []byte("x"), 0644))
+	dest, err := MoveFailed(dir, "bad.bin", errors.New("too large"))
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dir, FailedDir, "bad.bin"), dest)
+	note, err := os.ReadFile(dest + ".error")
+	require.NoError(t, err)
+	require.Contains(t, string(note), "too large")
+	require.NoFileExists(t, filepath.Join(dir, "bad.bin"))
+}
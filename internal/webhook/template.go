@@ -2,6 +2,7 @@ package webhook
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"text/template"
@@ -10,11 +11,23 @@ import (
 
 // WebhookData represents the data available in webhook templates
 type WebhookData struct {
+	// Message is the payload as text, or base64 when Encoding is "base64".
 	Message   string    `json:"message"`
+	Encoding  string    `json:"encoding"`
 	Timestamp time.Time `json:"timestamp"`
 	Topic     string    `json:"topic"`
 	ClientID  string    `json:"client_id"`
 	MessageID string    `json:"message_id"`
+	// Verification and Signer come from subscribe --verify and are empty
+	// without it.
+	Verification string `json:"verification"`
+	Signer       string `json:"signer"`
+}
+
+// Signature is the result of verifying a message's sender.
+type Signature struct {
+	Status string
+	Signer string
 }
 
 // TemplateFormatter handles webhook payload formatting using Go templates
@@ -39,7 +52,7 @@ func NewTemplateFormatter(schema string) (*TemplateFormatter, error) {
 }
 
 // FormatMessage formats the message using the template
-func (tf *TemplateFormatter) FormatMessage(message []byte, topic, clientID, messageID string) ([]byte, error) {
+func (tf *TemplateFormatter) FormatMessage(message []byte, topic, clientID, messageID string, sig Signature) ([]byte, error) {
 	// If no template, return raw message
 	if tf.template == nil {
 		return message, nil
@@ -47,13 +60,51 @@ func (tf *TemplateFormatter) FormatMessage(message []byte, topic, clientID, mess
 
 	// Prepare template data
 	data := WebhookData{
-		Message:   string(message),
-		Timestamp: time.Now().UTC(),
-		Topic:     topic,
-		ClientID:  clientID,
-		MessageID: messageID,
+		Message:      string(message),
+		Encoding:     "text",
+		Timestamp:    time.Now().UTC(),
+		Topic:        topic,
+		ClientID:     clientID,
+		MessageID:    messageID,
+		Verification: sig.Status,
+		Signer:       sig.Signer,
+	}
+
+	return tf.execute(data)
+}
+
+// Binary payload modes for webhooks without a schema.
+const (
+	BinaryRaw    = "raw"
+	BinaryBase64 = "base64"
+)
+
+// FormatBinary formats a payload that is not text and returns the content
+// type to send it with. A schema sees the payload base64 encoded in
+// .Message; without one the payload is sent as is (BinaryRaw) or as base64
+// text (BinaryBase64).
+func (tf *TemplateFormatter) FormatBinary(message []byte, mode, topic, clientID, messageID string, sig Signature) ([]byte, string, error) {
+	encoded := base64.StdEncoding.EncodeToString(message)
+	if tf.template == nil {
+		if mode == BinaryBase64 {
+			return []byte(encoded), "text/plain", nil
+		}
+		return message, "application/octet-stream", nil
 	}
+	body, err := tf.execute(WebhookData{
+		Message:      encoded,
+		Encoding:     "base64",
+		Timestamp:    time.Now().UTC(),
+		Topic:        topic,
+		ClientID:     clientID,
+		MessageID:    messageID,
+		Verification: sig.Status,
+		Signer:       sig.Signer,
+	})
+	return body, "application/json", err
+}
 
+func (tf *TemplateFormatter) execute(data WebhookData) ([]byte, error) {
 	// Execute template
 	var buf bytes.Buffer
 	if err := tf.template.Execute(&buf, data); err != nil {
@@ -84,5 +135,5 @@ func FormatMessageWithSchema(schema string, message []byte, topic, clientID, mes
 	if err != nil {
 		return nil, err
 	}
-	return formatter.FormatMessage(message, topic, clientID, messageID)
+	return formatter.FormatMessage(message, topic, clientID, messageID, Signature{})
 }
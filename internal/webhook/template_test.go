@@ -121,7 +121,7 @@ func TestFormatMessage(t *testing.T) {
 				t.Fatalf("Failed to create formatter: %v", err)
 			}
 
-			result, err := formatter.FormatMessage(tt.message, tt.topic, tt.clientID, tt.messageID)
+			result, err := formatter.FormatMessage(tt.message, tt.topic, tt.clientID, tt.messageID, Signature{})
 			if err != nil {
 				t.Fatalf("Failed to format message: %v", err)
 			}
@@ -159,7 +159,7 @@ func TestGetDefaultSchemas(t *testing.T) {
 		}
 
 		// Test with sample data
-		_, err = formatter.FormatMessage([]byte("test"), "test-topic", "test-client", "test-msg")
+		_, err = formatter.FormatMessage([]byte("test"), "test-topic", "test-client", "test-msg", Signature{})
 		if err != nil {
 			t.Errorf("Default schema %s failed to format: %v", name, err)
 		}
@@ -205,7 +205,7 @@ func TestTemplateWithTimestamp(t *testing.T) {
 		t.Fatalf("Failed to create formatter: %v", err)
 	}
 
-	result, err := formatter.FormatMessage([]byte("Test"), "test", "client", "msg")
+	result, err := formatter.FormatMessage([]byte("Test"), "test", "client", "msg", Signature{})
 	if err != nil {
 		t.Fatalf("Failed to format message: %v", err)
 	}
@@ -225,3 +225,53 @@ func TestTemplateWithTimestamp(t *testing.T) {
 		}
 	}
 }
+
+func TestFormatBinary(t *testing.T) {
+	binary := []byte{0x00, 0xff, 0x10}
+
+	raw, err := NewTemplateFormatter("")
+	if err != nil {
+		t.Fatalf("Failed to create formatter: %v", err)
+	}
+	body, contentType, err := raw.FormatBinary(binary, BinaryRaw, "test", "client", "msg", Signature{})
+	if err != nil {
+		t.Fatalf("Failed to format binary: %v", err)
+	}
+	if string(body) != string(binary) || contentType != "application/octet-stream" {
+		t.Errorf("Expected raw octet-stream body, got %q (%s)", body, contentType)
+	}
+
+	body, contentType, err = raw.FormatBinary(binary, BinaryBase64, "test", "client", "msg", Signature{})
+	if err != nil {
+		t.Fatalf("Failed to format binary: %v", err)
+	}
+	if string(body) != "AP8Q" || contentType != "text/plain" {
+		t.Errorf("Expected base64 text body, got %q (%s)", body, contentType)
+	}
+
+	schema, err := NewTemplateFormatter(`{"data":"{{.Message}}","encoding":"{{.Encoding}}"}`)
+	if err != nil {
+		t.Fatalf("Failed to create formatter: %v", err)
+	}
+	body, contentType, err = schema.FormatBinary(binary, BinaryRaw, "test", "client", "msg", Signature{})
+	if err != nil {
+		t.Fatalf("Failed to format binary: %v", err)
+	}
+	if string(body) != `{"data":"AP8Q","encoding":"base64"}` || contentType != "application/json" {
+		t.Errorf("Expected base64 in template, got %q (%s)", body, contentType)
+	}
+}
+
+func TestFormatSignature(t *testing.T) {
+	formatter, err := NewTemplateFormatter(`{"message":"{{.Message}}","verification":"{{.Verification}}","signer":"{{.Signer}}"}`)
+	if err != nil {
+		t.Fatalf("Failed to create formatter: %v", err)
+	}
+	result, err := formatter.FormatMessage([]byte("hi"), "test", "client", "msg", Signature{Status: "verified", Signer: "alice"})
+	if err != nil {
+		t.Fatalf("Failed to format message: %v", err)
+	}
+	if string(result) != `{"message":"hi","verification":"verified","signer":"alice"}` {
+		t.Errorf("Expected verification in template, got %q", result)
+	}
+}